@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// typeAlias is a type of the previous generation that is now generated under another name.
+type typeAlias struct {
+	old, current string
+}
+
+// previousAliases compares the types in the file about to be overwritten with the ones about to
+// be written and returns an alias for each type that was renamed. A renamed type is one that is
+// gone and has the same json keys, or enum values, as exactly one of the new types. Aliases in
+// the previous file are not carried over, so they last one generation.
+func previousAliases(c *config, target string, types []*Type) []typeAlias {
+	if !c.aliases {
+		return nil
+	}
+	if target == "" {
+		c.warn("stdout", "--aliases needs a --target to compare with, no aliases generated")
+		return nil
+	}
+	src, err := readFile(c.files(), target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		c.warn(target, "can not read the previous generation, no aliases generated: %v", err)
+		return nil
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), target, src, parser.ParseComments)
+	if err != nil {
+		c.warn(target, "can not read the previous generation, no aliases generated: %v", err)
+		return nil
+	}
+	old := previousSignatures(f)
+
+	current := make(map[string]bool, len(types))
+	bySignature := map[string][]string{}
+	for _, t := range types {
+		n := capitalize(t.Name)
+		current[n] = true
+		if _, existed := old[n]; !existed {
+			sig := typeSignature(t)
+			bySignature[sig] = append(bySignature[sig], n)
+		}
+	}
+
+	var aliases []typeAlias
+	for n, sig := range old {
+		// types without keys, like the ones embedding others, can not be told apart.
+		if current[n] || sig == keysSignature(nil) {
+			continue
+		}
+		switch candidates := bySignature[sig]; len(candidates) {
+		case 1:
+			aliases = append(aliases, typeAlias{old: n, current: candidates[0]})
+		case 0:
+			c.warn(n, "type is gone from the generated code and no new type looks like it")
+		default:
+			c.warn(n, "type is gone from the generated code and could be any of %s", strings.Join(candidates, ", "))
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].old < aliases[j].old })
+	return aliases
+}
+
+// provenance is part of the comment of every type made from the source, helpers such as the
+// k8s List types do not have it.
+const provenance = "is auto generated by github.com/perrito666/LAC"
+
+// previousSignatures returns the signature of every struct and enum generated from the source in
+// f, aliases are left out.
+func previousSignatures(f *ast.File) map[string]string {
+	sigs := map[string]string{}
+	enums := map[string][]string{}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				doc := s.Doc
+				if doc == nil {
+					doc = gd.Doc
+				}
+				if s.Assign.IsValid() || doc == nil || !strings.Contains(doc.Text(), provenance) {
+					continue
+				}
+				switch tt := s.Type.(type) {
+				case *ast.StructType:
+					sigs[s.Name.Name] = structSignature(tt)
+				case *ast.Ident:
+					if tt.Name == "string" {
+						sigs[s.Name.Name] = ""
+					}
+				}
+			case *ast.ValueSpec:
+				id, ok := s.Type.(*ast.Ident)
+				if !ok || gd.Tok != token.CONST {
+					continue
+				}
+				for _, v := range s.Values {
+					if lit, ok := v.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						if value, err := strconv.Unquote(lit.Value); err == nil {
+							enums[id.Name] = append(enums[id.Name], value)
+						}
+					}
+				}
+			}
+		}
+	}
+	for n, values := range enums {
+		if _, generated := sigs[n]; generated && len(values) > 0 {
+			sigs[n] = enumSignature(values)
+		}
+	}
+	return sigs
+}
+
+// structSignature returns the sorted json keys of a struct declaration.
+func structSignature(st *ast.StructType) string {
+	keys := []string{}
+	for _, fld := range st.Fields.List {
+		// k8s roots have their apiVersion and kind in the embedded TypeMeta.
+		if sel, ok := fld.Type.(*ast.SelectorExpr); ok && len(fld.Names) == 0 && sel.Sel.Name == "TypeMeta" {
+			keys = append(keys, "apiVersion", "kind")
+			continue
+		}
+		if fld.Tag == nil || len(fld.Names) == 0 {
+			continue
+		}
+		tag, err := strconv.Unquote(fld.Tag.Value)
+		if err != nil {
+			continue
+		}
+		key := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+		if key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keysSignature(keys)
+}
+
+// typeSignature returns the signature of a type about to be generated.
+func typeSignature(t *Type) string {
+	if len(t.Enum) > 0 {
+		return enumSignature(t.Enum)
+	}
+	keys := make([]string, 0, len(t.Fields))
+	for _, fld := range t.Fields {
+		if fld.Name != "" {
+			keys = append(keys, fld.Name)
+		}
+	}
+	return keysSignature(keys)
+}
+
+// keysSignature returns the signature of a struct with the given json keys.
+func keysSignature(keys []string) string {
+	sort.Strings(keys)
+	return "struct\x00" + strings.Join(keys, "\x00")
+}
+
+// enumSignature returns the signature of an enum with the given values, the empty one is left out
+// as it is also the Unknown zero value of --enum-unknown.
+func enumSignature(values []string) string {
+	sorted := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Strings(sorted)
+	return "enum\x00" + strings.Join(sorted, "\x00")
+}
+
+// writeAliases writes the deprecated aliases for the renamed types.
+func writeAliases(w io.Writer, aliases []typeAlias) error {
+	for _, a := range aliases {
+		_, err := fmt.Fprintf(w, "// %s was renamed to %s.\n//\n// Deprecated: use %s, this alias will be gone the next time the types are generated.\ntype %s = %s\n\n", a.old, a.current, a.current, a.old, a.current)
+		if err != nil {
+			return fmt.Errorf("writing aliases: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// annotationsSuffix replaces the extension of a sample to find its annotations, user.json is
+// annotated by user.annotations.yaml.
+const annotationsSuffix = ".annotations.yaml"
+
+// typeAnnotation holds what the annotations of a sample say about one of its types.
+type typeAnnotation struct {
+	Description string                     `yaml:"description"`
+	Fields      map[string]fieldAnnotation `yaml:"fields"`
+}
+
+// fieldAnnotation holds what the annotations of a sample say about a field, what samples can
+// not tell.
+type fieldAnnotation struct {
+	Description string        `yaml:"description"`
+	Required    bool          `yaml:"required"`
+	Enum        []interface{} `yaml:"enum"`
+	// Type is the Go type of the field, fully qualified, ie time.Time or []net/netip.Addr.
+	Type string `yaml:"type"`
+}
+
+// annotationsPath returns where the annotations of the sample at location are, samples fetched
+// from URLs have none.
+func annotationsPath(location string) string {
+	if strings.Contains(location, "://") {
+		return ""
+	}
+	return strings.TrimSuffix(location, filepath.Ext(location)) + annotationsSuffix
+}
+
+// readAnnotations reads the annotations of the sample at location, keyed by the type names as
+// they are in the sample, nil when there are none.
+func readAnnotations(c *config, location string) (map[string]typeAnnotation, error) {
+	p := annotationsPath(location)
+	if p == "" {
+		return nil, nil
+	}
+	b, err := readFile(c.files(), p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading annotations: %w", err)
+	}
+	annotations := map[string]typeAnnotation{}
+	if err := yaml.Unmarshal(b, &annotations); err != nil {
+		return nil, fmt.Errorf("parsing annotations %s: %w", p, err)
+	}
+	for tn, ta := range annotations {
+		for fn, fa := range ta.Fields {
+			if len(fa.Enum) == 0 {
+				continue
+			}
+			// the enum is compared like the values of the sample, decoded from JSON.
+			enum, err := jsonValues(fa.Enum)
+			if err != nil {
+				return nil, fmt.Errorf("annotations %s: enum of %s.%s: %w", p, tn, fn, err)
+			}
+			fa.Enum = enum
+			ta.Fields[fn] = fa
+		}
+	}
+	return annotations, nil
+}
+
+// jsonValues returns values as encoding/json would decode them, numbers become float64.
+func jsonValues(values []interface{}) ([]interface{}, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var result []interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// annotate merges the annotations for the type name into t, inferred from a sample.
+func annotate(t *Type, name string, annotations map[string]typeAnnotation) {
+	ta, ok := annotations[name]
+	if !ok {
+		return
+	}
+	if ta.Description != "" {
+		t.Description = ta.Description
+	}
+	for i := range t.Fields {
+		fa, ok := ta.Fields[t.Fields[i].Name]
+		if !ok {
+			continue
+		}
+		f := &t.Fields[i].Type
+		if fa.Description != "" {
+			f.description = fa.Description
+		}
+		if fa.Type != "" {
+			f.hint = fa.Type
+		}
+		if fa.Required || len(fa.Enum) > 0 {
+			if f.constraints == nil {
+				f.constraints = &constraints{}
+			}
+			f.constraints.required = f.constraints.required || fa.Required
+			if len(fa.Enum) > 0 {
+				f.constraints.enum = fa.Enum
+			}
+		}
+	}
+}
+
+// unusedAnnotations warns about the annotated types the sample does not have, names holds the
+// ones it has.
+func unusedAnnotations(c *config, source string, annotations map[string]typeAnnotation, names map[string]bool) {
+	unused := make([]string, 0, len(annotations))
+	for name := range annotations {
+		if !names[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		c.warn(annotationsPath(source), "there are annotations for %s but the sample has no type by that name", name)
+	}
+}
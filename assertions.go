@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// validatorInterface is declared once per file when a type has a Validate method.
+const validatorInterface = `// Validator is implemented by the types checking what the schema allows and Go types can not
+// express.
+type Validator interface {
+	Validate() error
+}
+
+`
+
+// hasValidator returns true if the Validator interface is to be declared, it is not when a type
+// takes its name.
+func hasValidator(types []*Type) bool {
+	needed := false
+	for _, t := range types {
+		if capitalize(t.Name) == "Validator" {
+			return false
+		}
+		needed = needed || len(t.checks) > 0
+	}
+	return needed
+}
+
+// typeAssertions returns the interfaces implemented by the methods generated for t.
+func typeAssertions(c *config, t *Type, validator bool) []string {
+	var ifaces []string
+	switch {
+	case t.union != nil:
+		ifaces = append(ifaces, "json.Marshaler", "json.Unmarshaler")
+	case len(t.Enum) > 0:
+		if c.enumUnknown == enumUnknownError || c.enumUnknown == enumUnknownUnknown {
+			ifaces = append(ifaces, "json.Unmarshaler")
+		}
+		if c.gqlgen {
+			ifaces = append(ifaces, "fmt.Stringer")
+		}
+	case t.extra && c.lossless, t.tuple:
+		ifaces = append(ifaces, "json.Marshaler", "json.Unmarshaler")
+	case t.extra:
+		ifaces = append(ifaces, "json.Unmarshaler")
+	}
+	if validator && len(t.checks) > 0 {
+		ifaces = append(ifaces, "Validator")
+	}
+	if c.k8s && isK8sRoot(t) {
+		ifaces = append(ifaces, "runtime.Object")
+	}
+	return ifaces
+}
+
+// writeAssertions writes the compile time assertions of the interfaces implemented by t, so
+// changes breaking them fail to build.
+func writeAssertions(w io.Writer, c *config, t *Type, structName string, validator bool) {
+	ifaces := typeAssertions(c, t, validator)
+	if len(ifaces) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "// %s implements these interfaces.\n", structName)
+	fmt.Fprint(w, "var (\n")
+	for _, iface := range ifaces {
+		fmt.Fprintf(w, "\t_ %s = (*%s)(nil)\n", iface, structName)
+	}
+	if c.k8s && isK8sRoot(t) {
+		fmt.Fprintf(w, "\t_ runtime.Object = (*%sList)(nil)\n", structName)
+	}
+	fmt.Fprint(w, ")\n\n")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AsyncAPIDocument represents the parts of an AsyncAPI 2.x document LAC uses, the schemas and the
+// messages sent on each channel.
+type AsyncAPIDocument struct {
+	AsyncAPI   string                     `json:"asyncapi"`
+	Channels   map[string]AsyncAPIChannel `json:"channels,omitempty"`
+	Components struct {
+		Schemas  SwaggerSchemas   `json:"schemas,omitempty"`
+		Messages AsyncAPIMessages `json:"messages,omitempty"`
+	} `json:"components,omitempty"`
+}
+
+// AsyncAPIChannel represents the operations of a channel, what the application publishes to it
+// and what it subscribes to.
+type AsyncAPIChannel struct {
+	Publish   *AsyncAPIOperation `json:"publish,omitempty"`
+	Subscribe *AsyncAPIOperation `json:"subscribe,omitempty"`
+}
+
+// AsyncAPIOperation represents an operation of a channel and the message it carries.
+type AsyncAPIOperation struct {
+	OperationID string          `json:"operationId,omitempty"`
+	Message     AsyncAPIMessage `json:"message,omitempty"`
+}
+
+// AsyncAPIMessage represents a message, a reference to one of the components or several of them
+// when the operation carries any of the messages in oneOf.
+type AsyncAPIMessage struct {
+	Ref         string `json:"$ref,omitempty"`
+	MessageID   string `json:"messageId,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Payload is the schema of the message, in the default schema format.
+	Payload      *SwaggerSchema    `json:"payload,omitempty"`
+	SchemaFormat string            `json:"schemaFormat,omitempty"`
+	OneOf        []AsyncAPIMessage `json:"oneOf,omitempty"`
+}
+
+// AsyncAPIMessages holds the messages of the components in the order they were declared.
+type AsyncAPIMessages struct {
+	Names  []string
+	ByName map[string]AsyncAPIMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler keeping the declaration order.
+func (am *AsyncAPIMessages) UnmarshalJSON(b []byte) error {
+	am.ByName = map[string]AsyncAPIMessage{}
+	return decodeObjectInOrder(b, func(key string, value []byte) error {
+		var m AsyncAPIMessage
+		if err := json.Unmarshal(value, &m); err != nil {
+			return fmt.Errorf("decoding message %s: %w", key, err)
+		}
+		if _, dup := am.ByName[key]; !dup {
+			am.Names = append(am.Names, key)
+		}
+		am.ByName[key] = m
+		return nil
+	})
+}
+
+// asyncAPIIntoTypes returns the types of the schemas of an AsyncAPI document along with the ones
+// of the payloads of its messages, the ones in components named after their key and the ones
+// declared in the channels after their messageId, name or operationId. Payloads referencing a
+// schema already have a type.
+func asyncAPIIntoTypes(ctx context.Context, c *config) ([]*Type, error) {
+	var doc AsyncAPIDocument
+	if err := decodeSpec(ctx, c, &doc); err != nil {
+		return nil, fmt.Errorf("decoding asyncapi document: %w", err)
+	}
+	if !strings.HasPrefix(doc.AsyncAPI, "2.") {
+		c.warn(c.swaggerFile, "asyncapi %q is not a 2.x document, it is read as one", doc.AsyncAPI)
+	}
+	schemas := doc.Components.Schemas
+	if err := checkComponents(c, len(schemas.Names)+len(doc.Components.Messages.Names)); err != nil {
+		return nil, err
+	}
+	var result []*Type
+	for _, name := range schemas.Names {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("processing %s: %w", name, err)
+		}
+		result = append(result, processComponent(c, name, objectSchema(schemas.ByName[name]))...)
+	}
+	// examples are looked up by name, the payloads are added along with their types.
+	all := SwaggerSchemas{ByName: make(map[string]SwaggerSchema, len(schemas.Names))}
+	for _, name := range schemas.Names {
+		all.ByName[name] = schemas.ByName[name]
+	}
+	taken := make(map[string]bool, len(result))
+	for _, t := range result {
+		taken[capitalize(t.Name)] = true
+	}
+	add := func(source, name string, m AsyncAPIMessage) {
+		payload, ok := messagePayload(c, source, m)
+		if !ok {
+			return
+		}
+		if taken[capitalize(name)] {
+			c.warn(source, "the name %s is taken, the payload is named %sPayload", capitalize(name), capitalize(name))
+			name += "Payload"
+			if taken[capitalize(name)] {
+				c.warn(source, "no type is generated for the payload as %s is taken", capitalize(name))
+				return
+			}
+		}
+		ts := processComponent(c, name, payload)
+		for _, t := range ts {
+			taken[capitalize(t.Name)] = true
+		}
+		all.ByName[name] = payload
+		result = append(result, ts...)
+	}
+	for _, name := range doc.Components.Messages.Names {
+		add(name, name, doc.Components.Messages.ByName[name])
+	}
+	for _, channel := range sortedKeys(doc.Channels) {
+		ch := doc.Channels[channel]
+		for _, op := range []struct {
+			kind string
+			op   *AsyncAPIOperation
+		}{{"publish", ch.Publish}, {"subscribe", ch.Subscribe}} {
+			if op.op == nil {
+				continue
+			}
+			source := channel + " " + op.kind
+			messages := op.op.Message.OneOf
+			if len(messages) == 0 {
+				messages = []AsyncAPIMessage{op.op.Message}
+			}
+			for i, m := range messages {
+				if m.Ref != "" || m.Payload == nil {
+					// messages of the components have their type already.
+					continue
+				}
+				name := m.MessageID
+				if name == "" {
+					name = m.Name
+				}
+				if name == "" && op.op.OperationID != "" {
+					name = op.op.OperationID
+					if len(messages) > 1 {
+						name += fmt.Sprintf("%d", i+1)
+					}
+				}
+				if name == "" {
+					c.warn(source, "inline messages are only given a type when they have a messageId or name, or their operation an operationId")
+					continue
+				}
+				add(source, name, m)
+			}
+		}
+	}
+	// only the fixtures use the examples.
+	if c.fixtures {
+		if err := markExamples(ctx, result, all); err != nil {
+			return nil, err
+		}
+	}
+	renameTypes(result, goNames(schemas))
+	markEnumRefs(result)
+	return canonicalOrder(splitReadOnly(c, result)), nil
+}
+
+// messagePayload returns the schema of the payload of m when a struct can be made of it, the
+// ones referencing a schema already have a type. Messages with their own description lend it to
+// payloads without one.
+func messagePayload(c *config, source string, m AsyncAPIMessage) (SwaggerSchema, bool) {
+	if m.Ref != "" || m.Payload == nil {
+		return SwaggerSchema{}, false
+	}
+	if m.SchemaFormat != "" && !strings.HasPrefix(m.SchemaFormat, "application/vnd.aai.asyncapi") && !strings.HasPrefix(m.SchemaFormat, "application/schema+json") {
+		c.warn(source, "the payload is in %s, only JSON Schema payloads are read", m.SchemaFormat)
+		return SwaggerSchema{}, false
+	}
+	payload := objectSchema(*m.Payload)
+	if payload.Ref != "" {
+		c.debugf("the payload of %s is %s, no type needed\n", source, payload.Ref)
+		return SwaggerSchema{}, false
+	}
+	if payload.Type != STObject {
+		c.debugf("the payload of %s is not an object, no type needed\n", source)
+		return SwaggerSchema{}, false
+	}
+	if payload.Description == "" {
+		for _, d := range []string{m.Description, m.Summary, m.Title} {
+			if d != "" {
+				payload.Description = d
+				break
+			}
+		}
+	}
+	return payload, true
+}
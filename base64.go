@@ -0,0 +1,84 @@
+package main
+
+// base64Imports are the packages needed by base64Helpers.
+var base64Imports = []string{"encoding/base64", "encoding/json", "strings"}
+
+// base64Helpers is written once per file when --lenient-base64 is passed and a field holds
+// base64, encoding/json only reads the standard alphabet with padding.
+const base64Helpers = `// Base64 is a []byte written as standard base64 that reads both the standard and the URL safe
+// alphabets, with or without padding.
+type Base64 []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Base64) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Base64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	s = strings.TrimRight(s, "=")
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	v, err := enc.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+var (
+	_ json.Marshaler   = Base64(nil)
+	_ json.Unmarshaler = (*Base64)(nil)
+)
+
+`
+
+// base64Type is the name of the type written by base64Helpers.
+const base64Type = "Base64"
+
+// isBytesFormat returns true for the formats of strings holding binary data.
+func isBytesFormat(format string) bool {
+	return format == "byte" || format == "binary"
+}
+
+// markBase64 keeps the standard decoding of encoding/json when a type takes the name of the
+// helper.
+func markBase64(c *config, types []*Type) {
+	if !c.lenientBase64 {
+		return
+	}
+	for _, t := range types {
+		if capitalize(t.Name) != base64Type {
+			continue
+		}
+		c.warn(t.Name, "the type is named %s, base64 is read as encoding/json does", base64Type)
+		for _, t := range types {
+			for i := range t.Fields {
+				t.Fields[i].Type.base64 = false
+			}
+		}
+		return
+	}
+}
+
+// needsBase64 returns true if any field of types holds base64 read leniently.
+func needsBase64(c *config, types []*Type) bool {
+	if !c.lenientBase64 {
+		return false
+	}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			if fld.Name != "" && fld.Type.base64 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bindName returns the name of the function reading the parameters of op.
+func bindName(op apiOperation) string {
+	return "Bind" + op.name
+}
+
+// bindChecks returns the checks of the validation keywords of the parameters of op, by their
+// name in Go, along with the packages they need. Keywords that can not be checked on the type of
+// their parameter are warned about and left out.
+func bindChecks(c *config, op apiOperation) (map[string][]fieldCheck, []string) {
+	checks := map[string][]fieldCheck{}
+	var imports []string
+	add := func(p apiParam, v, tn string) {
+		if p.constraints == nil {
+			return
+		}
+		f := &maybeType{constraints: p.constraints, optional: !p.required}
+		chks, ok := constraintChecks(bindName(op), p.goName, p.name, v, tn, f)
+		if !ok {
+			c.warn(op.method+" "+op.path, "the validation keywords of the %s parameter %s can not be checked on %s, they are ignored", p.in, p.name, tn)
+		}
+		for _, chk := range chks {
+			imports = append(imports, chk.imports...)
+		}
+		checks[p.goName] = chks
+	}
+	for _, p := range op.pathParams {
+		add(p, p.goName, p.goType)
+	}
+	for _, p := range op.params {
+		tn := p.goType
+		if !p.required && !strings.HasPrefix(tn, "[]") {
+			tn = "*" + tn
+		}
+		add(p, "params."+p.goName, tn)
+	}
+	return checks, imports
+}
+
+// writeBind writes the function reading the path, query and header parameters of op from a
+// request into their types, checking the validation keywords of their schemas. The handlers call
+// it, and so can the ones written by hand. Operations without parameters have none.
+func writeBind(w *bufio.Writer, op apiOperation, checks map[string][]fieldCheck) {
+	if len(op.pathParams)+len(op.params) == 0 {
+		return
+	}
+	var results, zero []string
+	for _, p := range op.pathParams {
+		results = append(results, p.goType)
+		zero = append(zero, p.goName)
+	}
+	if len(op.params) > 0 {
+		results = append(results, op.name+"Params")
+		zero = append(zero, "params")
+	}
+	ret := strings.Join(zero, ", ")
+	for _, p := range append(append([]apiParam(nil), op.pathParams...), op.params...) {
+		for _, chk := range checks[p.goName] {
+			for _, pattern := range chk.patterns {
+				fmt.Fprintf(w, "\nvar %s = regexp.MustCompile(%s)\n", pattern[0], strconv.Quote(pattern[1]))
+			}
+		}
+	}
+	args := "r *http.Request"
+	doc := fmt.Sprintf("%s reads the parameters of %s from r", bindName(op), op.name)
+	if len(op.pathParams) > 0 {
+		args += ", pathValue func(name string) string"
+		doc += ", the ones in the path with pathValue"
+	}
+	doc += ". It fails with a *ServerError answering 400 when they are missing, do not fit their type or break the validation keywords of the spec."
+	w.WriteString("\n")
+	for _, l := range wrapLine(doc, 97) {
+		fmt.Fprintf(w, "// %s\n", l)
+	}
+	fmt.Fprintf(w, "func %s(%s) (%s, error) {\n", bindName(op), args, strings.Join(results, ", "))
+	for _, p := range op.pathParams {
+		fmt.Fprintf(w, "\tvar %s %s\n", p.goName, p.goType)
+	}
+	if len(op.params) > 0 {
+		fmt.Fprintf(w, "\tvar params %sParams\n", op.name)
+	}
+	for _, p := range op.params {
+		if p.in == "query" {
+			w.WriteString("\tquery := r.URL.Query()\n")
+			break
+		}
+	}
+	for _, p := range op.pathParams {
+		what := "path parameter " + p.name
+		fmt.Fprintf(w, "\tif err := lacParam([]string{pathValue(%q)}, true, &%s); err != nil {\n", p.goName, p.goName)
+		fmt.Fprintf(w, "\t\treturn %s, lacBadRequest(%q, err)\n\t}\n", ret, what)
+		writeBindChecks(w, what, ret, checks[p.goName])
+	}
+	for _, p := range op.params {
+		what := p.in + " parameter " + p.name
+		values := fmt.Sprintf("query[%q]", p.name)
+		if p.in == "header" {
+			values = fmt.Sprintf("r.Header.Values(%q)", p.name)
+		}
+		if p.in == "query" && !p.repeated() {
+			fmt.Fprintf(w, "\tif err := lacQueryParam(query, %q, %q, %t, %t, &params.%s); err != nil {\n", p.name, p.style, p.explode, p.required, p.goName)
+		} else {
+			fmt.Fprintf(w, "\tif err := lacParam(%s, %t, &params.%s); err != nil {\n", values, p.required, p.goName)
+		}
+		fmt.Fprintf(w, "\t\treturn %s, lacBadRequest(%q, err)\n\t}\n", ret, what)
+		writeBindChecks(w, what, ret, checks[p.goName])
+	}
+	fmt.Fprintf(w, "\treturn %s, nil\n}\n", ret)
+}
+
+// writeBindChecks writes the checks of a parameter, failing with ret and the error of the check.
+func writeBindChecks(w *bufio.Writer, what, ret string, checks []fieldCheck) {
+	for _, chk := range checks {
+		if chk.items {
+			fmt.Fprintf(w, "\tfor i, e := range %s {\n", chk.value)
+			fmt.Fprintf(w, "\t\tif %s {\n", chk.invalid)
+			fmt.Fprintf(w, "\t\t\treturn %s, lacBadRequest(%q, fmt.Errorf(%s, i, e))\n\t\t}\n\t}\n", ret, what, strconv.Quote("item %d: "+chk.errorf))
+			continue
+		}
+		if chk.value == "" {
+			// lacParam already fails on the missing ones.
+			continue
+		}
+		fmt.Fprintf(w, "\tif %s {\n", chk.invalid)
+		fmt.Fprintf(w, "\t\treturn %s, lacBadRequest(%q, fmt.Errorf(%s, %s))\n\t}\n", ret, what, strconv.Quote(chk.errorf), chk.value)
+	}
+}
+
+// bindCall returns the call to the function reading the parameters of op in a handler, with the
+// router giving the values of the ones in the path.
+func bindCall(c *config, op apiOperation) string {
+	pathValue := ""
+	if len(op.pathParams) > 0 {
+		switch c.server {
+		case serverChi:
+			pathValue = ", func(name string) string { return chi.URLParam(r, name) }"
+		case serverEcho:
+			pathValue = ", e.Param"
+		case serverGin:
+			pathValue = ", g.Param"
+		default:
+			pathValue = ", r.PathValue"
+		}
+	}
+	return bindName(op) + "(r" + pathValue + ")"
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+)
+
+// bodyTypes returns the types of the JSON request bodies and responses declared inline, the ones
+// of operations are named after the operationId, ie createPet.request and createPet.201.response,
+// and the ones in components after their key. Bodies referencing a component already have a type.
+func bodyTypes(c *config, components SwaggerComponents, paths map[string]SwaggerPathItem, types []*Type) []*Type {
+	taken := make(map[string]bool, len(types))
+	for _, t := range types {
+		taken[capitalize(t.Name)] = true
+	}
+	var result []*Type
+	add := func(source, name string, content map[string]SwaggerMediaType) {
+		schema, ok := inlineBodySchema(c, source, content)
+		if !ok {
+			return
+		}
+		if taken[capitalize(name)] {
+			c.warn(source, "no type is generated for the body as %s is taken", capitalize(name))
+			return
+		}
+		ts := processComponent(c, name, schema)
+		for _, t := range ts {
+			taken[capitalize(t.Name)] = true
+		}
+		result = append(result, ts...)
+	}
+
+	for _, name := range sortedKeys(components.RequestBodies) {
+		add(name, name+".body", components.RequestBodies[name].Content)
+	}
+	for _, name := range sortedKeys(components.Responses) {
+		add(name, name+".response", components.Responses[name].Content)
+	}
+	pathNames := make([]string, 0, len(paths))
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	sort.Strings(pathNames)
+	for _, p := range pathNames {
+		for _, op := range paths[p].Operations() {
+			hasInline := op.RequestBody != nil && hasInlineBody(op.RequestBody.Content)
+			for _, resp := range op.Responses {
+				hasInline = hasInline || hasInlineBody(resp.Content)
+			}
+			if !hasInline {
+				continue
+			}
+			if op.OperationID == "" {
+				c.warn(p, "inline bodies are only given a type in operations with an operationId")
+				continue
+			}
+			if op.RequestBody != nil {
+				add(p, op.OperationID+".request", op.RequestBody.Content)
+			}
+			for _, status := range sortedKeys(op.Responses) {
+				add(p, op.OperationID+"."+status+".response", op.Responses[status].Content)
+			}
+		}
+	}
+	return result
+}
+
+// inlineBodySchema returns the schema of the JSON content of a body when it is declared inline
+// and a struct can be made of it.
+func inlineBodySchema(c *config, source string, content map[string]SwaggerMediaType) (SwaggerSchema, bool) {
+	for _, ct := range sortedKeys(content) {
+		schema := content[ct].Schema
+		if !isJSONMedia(ct) || schema.Ref != "" {
+			continue
+		}
+		if schema.Type == "" && len(schema.Properties.Names) > 0 {
+			schema.Type = STObject
+		}
+		if schema.Type != STObject {
+			c.debugf("the %s body of %s is not an object, no type needed\n", ct, source)
+			continue
+		}
+		return schema, true
+	}
+	return SwaggerSchema{}, false
+}
+
+// hasInlineBody returns true if any JSON content is declared inline.
+func hasInlineBody(content map[string]SwaggerMediaType) bool {
+	for ct, media := range content {
+		if isJSONMedia(ct) && media.Schema.Ref == "" && (media.Schema.Type != "" || len(media.Schema.Properties.Names) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m, a map with string keys, sorted.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, k.String())
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keywordCheck is a validation keyword checked on its own by constraintChecks, so its error can
+// tell which one the value fails.
+type keywordCheck struct {
+	cs *constraints
+	// errorf is the format of the error, it takes the value or, for sizes, the length.
+	errorf string
+	// size is set when the keyword is about the length of an array rather than its value.
+	size bool
+}
+
+// keywordChecks splits cs into its validation keywords, minProperties and maxProperties are
+// left out as sizeCheck already checks them.
+func keywordChecks(cs *constraints) []keywordCheck {
+	var result []keywordCheck
+	if len(cs.enum) > 0 {
+		result = append(result, keywordCheck{cs: &constraints{enum: cs.enum}, errorf: "%v is not one of the values the schema allows"})
+	}
+	if cs.minimum != nil {
+		errorf := "%v is less than the minimum of " + formatFloat(*cs.minimum)
+		if cs.exclusiveMinimum {
+			errorf = "%v is not greater than " + formatFloat(*cs.minimum)
+		}
+		result = append(result, keywordCheck{cs: &constraints{minimum: cs.minimum, exclusiveMinimum: cs.exclusiveMinimum}, errorf: errorf})
+	}
+	if cs.maximum != nil {
+		errorf := "%v is greater than the maximum of " + formatFloat(*cs.maximum)
+		if cs.exclusiveMaximum {
+			errorf = "%v is not less than " + formatFloat(*cs.maximum)
+		}
+		result = append(result, keywordCheck{cs: &constraints{maximum: cs.maximum, exclusiveMaximum: cs.exclusiveMaximum}, errorf: errorf})
+	}
+	if cs.minLength != nil {
+		result = append(result, keywordCheck{cs: &constraints{minLength: cs.minLength}, errorf: fmt.Sprintf("%%q is shorter than %d characters", *cs.minLength)})
+	}
+	if cs.maxLength != nil {
+		result = append(result, keywordCheck{cs: &constraints{maxLength: cs.maxLength}, errorf: fmt.Sprintf("%%q is longer than %d characters", *cs.maxLength)})
+	}
+	if cs.pattern != "" {
+		result = append(result, keywordCheck{cs: &constraints{pattern: cs.pattern}, errorf: "%q does not match " + strings.Replace(cs.pattern, "%", "%%", -1)})
+	}
+	if cs.minItems != nil {
+		result = append(result, keywordCheck{cs: &constraints{minItems: cs.minItems}, errorf: fmt.Sprintf("has %%d items, the schema requires at least %d", *cs.minItems), size: true})
+	}
+	if cs.maxItems != nil {
+		result = append(result, keywordCheck{cs: &constraints{maxItems: cs.maxItems}, errorf: fmt.Sprintf("has %%d items, the schema allows at most %d", *cs.maxItems), size: true})
+	}
+	return result
+}
+
+// constraintChecks returns the checks of --validate for the validation keywords of a field of Go
+// type tn, whose value is the expression v, and of its items for arrays, along with the one for
+// it being required when a missing value can be told apart. Fields that can be missing are only
+// checked when they hold a value, which for the ones that are not pointers means not holding the
+// zero value. It returns false when some keyword can not be checked on tn.
+func constraintChecks(structName, fn, key, v, tn string, f *maybeType) ([]fieldCheck, bool) {
+	cs := f.constraints
+	var result []fieldCheck
+	if cs.required && (strings.HasPrefix(tn, "*") || canBeNil(tn)) {
+		result = append(result, fieldCheck{key: key, invalid: v + " == nil", errorf: "is required", imports: []string{"fmt"}})
+	}
+	guard := ""
+	if strings.HasPrefix(tn, "*") {
+		guard = v + " != nil && "
+		v = "*" + v
+		tn = tn[1:]
+	}
+	kind := valueKind(tn, f)
+	if guard == "" && f.optional {
+		switch kind {
+		case kindString:
+			guard = v + ` != "" && `
+		case kindInt, kindFloat:
+			guard = v + " != 0 && "
+		case kindArray:
+			guard = v + " != nil && "
+		}
+	}
+	if f.enum && kind == kindString {
+		v = "string(" + v + ")"
+	}
+	keywords := keywordChecks(cs)
+	if len(keywords) > 0 && kind == "" {
+		return result, false
+	}
+	for _, kw := range keywords {
+		chk := fieldCheck{key: key, value: v, errorf: kw.errorf, imports: []string{"fmt"}}
+		if kw.size {
+			chk.value = "len(" + v + ")"
+		}
+		conds, ok := chk.conditions(kw.cs, kind, v, "lac"+structName+fn+"Pattern")
+		if !ok {
+			return result, false
+		}
+		if len(conds) == 0 {
+			// the keyword is about other kinds of values.
+			continue
+		}
+		chk.invalid = guard + negate(conds)
+		result = append(result, chk)
+	}
+	if kind != kindArray || cs.items == nil {
+		return result, true
+	}
+	itemKind := valueKind(tn[2:], f)
+	e := "e"
+	if f.enum && itemKind == kindString {
+		e = "string(e)"
+	}
+	keywords = keywordChecks(cs.items)
+	if len(keywords) > 0 && itemKind == "" {
+		return result, false
+	}
+	for _, kw := range keywords {
+		if kw.size {
+			// arrays of arrays are not generated.
+			continue
+		}
+		chk := fieldCheck{key: key, value: v, errorf: kw.errorf, items: true, imports: []string{"fmt"}}
+		conds, ok := chk.conditions(kw.cs, itemKind, e, "lac"+structName+fn+"ItemsPattern")
+		if !ok {
+			return result, false
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		chk.invalid = negate(conds)
+		result = append(result, chk)
+	}
+	return result, true
+}
+
+// markNestedChecks makes the types with fields holding types with a Validate method call it from
+// their own, until every type holding one, however deep, does.
+func markNestedChecks(c *config, types []*Type) {
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[capitalize(t.Name)] = t
+	}
+	validated := make(map[string]bool, len(types))
+	for _, t := range types {
+		validated[capitalize(t.Name)] = len(t.checks) > 0
+	}
+	nested := make(map[*Type][]fieldCheck, len(types))
+	for changed := true; changed; {
+		changed = false
+		for _, t := range types {
+			structName := capitalize(t.Name)
+			if len(t.Enum) > 0 || t.union != nil || hasValidateField(t) {
+				continue
+			}
+			var checks []fieldCheck
+			for _, fld := range t.Fields {
+				if fld.Name == "" {
+					continue
+				}
+				fn := fld.GoName()
+				tn, _ := fieldType(c, structName, fn, &fld.Type)
+				if chk, ok := nestedCheck(fn, fld.Name, tn, validated); ok {
+					checks = append(checks, chk)
+				}
+			}
+			nested[t] = checks
+			if len(checks) > 0 && !validated[structName] {
+				validated[structName] = true
+				changed = true
+			}
+		}
+	}
+	for _, t := range types {
+		t.checks = append(t.checks, nested[t]...)
+	}
+}
+
+// nestedCheck returns the check calling the Validate method of the value of a field of Go type
+// tn, or of its items, when it is one of the validated types.
+func nestedCheck(fn, key, tn string, validated map[string]bool) (fieldCheck, bool) {
+	v := "t." + fn
+	items := strings.HasPrefix(tn, "[]")
+	elem := strings.TrimPrefix(tn, "[]")
+	if !validated[strings.TrimPrefix(elem, "*")] {
+		return fieldCheck{}, false
+	}
+	chk := fieldCheck{key: key, value: v, nested: true, items: items, imports: []string{"fmt"}}
+	if strings.HasPrefix(elem, "*") {
+		chk.invalid = v + " != nil"
+		if items {
+			chk.invalid = "e != nil"
+		}
+	}
+	return chk, true
+}
+
+// hasValidateField returns true if a field of t takes the name of the Validate method.
+func hasValidateField(t *Type) bool {
+	for _, fld := range t.Fields {
+		if fld.Name != "" && fld.GoName() == "Validate" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel the run on interrupt so long generations stop cleanly instead of leaving a
+	// half written target behind. Only the first signal is caught, a second one kills the
+	// process as usual should the run not stop.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	if err := realMain(ctx); err != nil {
+		// --help prints the usage, of LAC or of its commands, and is not a failure.
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Printf("FAILED: %v\n", err)
+		var badUsage *ErrBadUsage
+		if errors.As(err, &badUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func realMain(ctx context.Context) error {
+	if len(os.Args) > 1 && os.Args[1] == snippetCommand {
+		return runSnippet(ctx, os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == reverseCommand {
+		return runReverse(ctx, os.Args[2:], nil, os.Stdout, os.Stderr)
+	}
+	c, err := parseFlags(flag.NewFlagSet(os.Args[0], flag.ContinueOnError), os.Args[1:], 0, nil)
+	if err != nil {
+		return fmt.Errorf("flags step: %w", err)
+	}
+	if c.rpc {
+		return serveRPC(ctx, c, os.Stdin, os.Stdout)
+	}
+	if c.overlay != "" {
+		return runOverlay(ctx, c, os.Stdout)
+	}
+	return run(ctx, c, os.Stdout)
+}
@@ -0,0 +1,720 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clientNames are the names the client is written with, a type of the spec taking them leaves
+// no room for it.
+var clientNames = []string{"Client", "ClientError", "NewClient", "DefaultBaseURL", "ClientOption", "RetryPolicy",
+	"WithHTTPClient", "WithRetry", "WithTimeout", "WithRequestInterceptor", "WithResponseInterceptor", "ClientHooks",
+	"WithHooks"}
+
+// clientFile returns the name of the file the client of target is written to, next to it.
+func clientFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_client.go"
+}
+
+// writeClient writes, with --client, a Client next to the target with a method for each
+// operation of the paths. The methods take the path parameters, a Params struct with the query
+// and header ones and the request body, and return the body of the 2xx response decoded into its
+// type or an error holding the one of the other responses. Request bodies are sent as JSON, the
+// XML and form ones by methods of their own, see requestBody. With --contract-tests the tests
+// calling them are written too.
+func writeClient(ctx context.Context, c *config, types []*Type) error {
+	spec, ops, err := readOperations(ctx, c, types, "client", clientNames)
+	if err != nil {
+		return err
+	}
+	errorTypes := nameErrors(c, types, ops)
+	err = writeFile(c, clientFile(c.targetFile), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		imports := []string{"bytes", "context", "encoding/json", "fmt", "io/ioutil", "net/http", "net/url", "reflect", "strings", "time"}
+		xmlBodies, multipartBodies := bodyKinds(ops)
+		if xmlBodies {
+			imports = append(imports, "encoding/xml")
+		}
+		if multipartBodies {
+			imports = append(imports, "mime/multipart")
+		}
+		sort.Strings(imports)
+		writeHeading(w, c, imports)
+		writeClientType(w, baseURL(spec))
+		writeEncoders(w, xmlBodies, multipartBodies)
+		for _, e := range errorTypes {
+			writeErrorType(w, e)
+		}
+		for _, op := range ops {
+			writeClientMethod(w, op)
+		}
+		return w.Flush()
+	})
+	if err != nil || !c.contractTests {
+		return err
+	}
+	return writeContractTests(c, types, ops)
+}
+
+// baseURL returns the URL of the first server of the spec, with its variables set to their
+// defaults, or the one made of the host of Swagger 2.0 specs. It is empty when there is none.
+func baseURL(spec *SwaggerSimplification) string {
+	if len(spec.Servers) > 0 {
+		server := spec.Servers[0]
+		return strings.TrimSuffix(pathTemplate.ReplaceAllStringFunc(server.URL, func(v string) string {
+			if variable, ok := server.Variables[v[1:len(v)-1]]; ok {
+				return variable.Default
+			}
+			return v
+		}), "/")
+	}
+	if spec.Host == "" {
+		return strings.TrimSuffix(spec.BasePath, "/")
+	}
+	scheme := "https"
+	if len(spec.Schemes) > 0 {
+		scheme = spec.Schemes[0]
+	}
+	return scheme + "://" + spec.Host + strings.TrimSuffix(spec.BasePath, "/")
+}
+
+// writeClientType writes the Client, its constructor, options and error, along with the helpers
+// sending the requests of all the methods and writing their query parameters.
+func writeClientType(w *bufio.Writer, base string) {
+	if base != "" {
+		fmt.Fprint(w, "// DefaultBaseURL is the URL of the first server of the spec.\n")
+		fmt.Fprintf(w, "const DefaultBaseURL = %s\n\n", strconv.Quote(base))
+	}
+	w.WriteString(`// Client sends the operations of the spec to the API at BaseURL.
+type Client struct {
+	// BaseURL is the URL the paths of the operations are relative to, without a trailing slash.
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Retry says which requests are sent again, none when MaxAttempts is below 2.
+	Retry RetryPolicy
+	// Timeout bounds each call, retries included, on top of the deadline of its context. Calls
+	// have no timeout of their own when it is 0.
+	Timeout time.Duration
+	// RequestInterceptors are called in order with each request before it is sent, an error
+	// stops the call.
+	RequestInterceptors []func(*http.Request) error
+	// ResponseInterceptors are called in order with each response before it is read, an error
+	// stops the call.
+	ResponseInterceptors []func(*http.Response) error
+	// Hooks are called in order around each request with the name of its operation.
+	Hooks []ClientHooks
+}
+
+// ClientHooks are called around each request, retries included, with the name of the method of
+// its operation, so spans can be started and ended without wrapping the calls.
+type ClientHooks struct {
+	// BeforeRequest is called before req is sent, the context it returns, which has to derive
+	// from ctx, is the one of the request and of AfterResponse.
+	BeforeRequest func(ctx context.Context, operation string, req *http.Request) context.Context
+	// AfterResponse is called once req was answered with resp or failed with err.
+	AfterResponse func(ctx context.Context, operation string, req *http.Request, resp *http.Response, err error)
+}
+
+// RetryPolicy says how many times and how often the requests are sent again when they fail.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a request is sent at most, the first one included.
+	MaxAttempts int
+	// Backoff is the wait before the second attempt, it doubles with each of the following ones.
+	Backoff time.Duration
+	// Retryable says whether a request, which failed with err or was answered with resp, is sent
+	// again. When nil the ones failing to be sent and the ones answered with 429, 502, 503 or 504
+	// are, except for POST and PATCH as they may have been applied.
+	Retryable func(req *http.Request, resp *http.Response, err error) bool
+}
+
+// ClientOption sets up a Client made by NewClient.
+type ClientOption func(*Client)
+
+// WithHTTPClient sends the requests with hc.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = hc
+	}
+}
+
+// WithRetry sends the failed requests again as policy says.
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithTimeout bounds each call to d.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.Timeout = d
+	}
+}
+
+// WithRequestInterceptor calls f with each request before it is sent, after the interceptors
+// already set.
+func WithRequestInterceptor(f func(*http.Request) error) ClientOption {
+	return func(c *Client) {
+		c.RequestInterceptors = append(c.RequestInterceptors, f)
+	}
+}
+
+// WithHooks calls the hooks of h around each request, after the ones already set.
+func WithHooks(h ClientHooks) ClientOption {
+	return func(c *Client) {
+		c.Hooks = append(c.Hooks, h)
+	}
+}
+
+// WithResponseInterceptor calls f with each response before it is read, after the interceptors
+// already set.
+func WithResponseInterceptor(f func(*http.Response) error) ClientOption {
+	return func(c *Client) {
+		c.ResponseInterceptors = append(c.ResponseInterceptors, f)
+	}
+}
+
+`)
+	if base != "" {
+		fmt.Fprint(w, "// NewClient returns a Client for the API at baseURL, DefaultBaseURL when empty, set up by opts.\n")
+		fmt.Fprint(w, "func NewClient(baseURL string, opts ...ClientOption) *Client {\n\tif baseURL == \"\" {\n\t\tbaseURL = DefaultBaseURL\n\t}\n")
+	} else {
+		fmt.Fprint(w, "// NewClient returns a Client for the API at baseURL set up by opts.\n")
+		fmt.Fprint(w, "func NewClient(baseURL string, opts ...ClientOption) *Client {\n")
+	}
+	w.WriteString(`	c := &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ClientError is returned for the responses with a status other than 2xx, along with their body,
+// unless the operation declares a JSON body for them that they decode into.
+type ClientError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// lacEncoded is a request body already encoded, sent as it is with its content type.
+type lacEncoded struct {
+	contentType string
+	payload     []byte
+}
+
+// lacResponseError is an error holding the body of a response outside 2xx an operation declares.
+type lacResponseError interface {
+	error
+	decode(status int, body []byte) error
+}
+
+// do sends the request of operation to path, with body encoded as JSON unless it is nil or
+// already encoded, and decodes the response into result unless it is nil. Failed requests are sent again as c.Retry
+// says. The responses outside 2xx are returned as the error errorFor gives for their status
+// when they decode into it, as a *ClientError otherwise.
+func (c *Client) do(ctx context.Context, operation, method, path string, query url.Values, header http.Header, body, result interface{}, errorFor func(status int) lacResponseError) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var payload []byte
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case *lacEncoded:
+		payload, contentType = b.payload, b.contentType
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding the request body: %w", err)
+		}
+		payload = encoded
+	}
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return err
+		}
+		if payload != nil {
+			// each attempt reads the body from the start.
+			req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+			req.ContentLength = int64(len(payload))
+			req.Header.Set("Content-Type", contentType)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err = c.send(operation, req)
+		if attempt >= c.Retry.MaxAttempts || ctx.Err() != nil || !c.Retry.retryable(req, resp, err) {
+			if err != nil {
+				return err
+			}
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.Retry.Backoff << (attempt - 1)):
+		}
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading the response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if errorFor != nil {
+			if e := errorFor(resp.StatusCode); e != nil && e.decode(resp.StatusCode, b) == nil {
+				return e
+			}
+		}
+		return &ClientError{StatusCode: resp.StatusCode, Body: b}
+	}
+	if result == nil || len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, result); err != nil {
+		return fmt.Errorf("decoding the response: %w", err)
+	}
+	return nil
+}
+
+// send sends req, the one of operation, through the hooks and the interceptors.
+func (c *Client) send(operation string, req *http.Request) (*http.Response, error) {
+	for _, h := range c.Hooks {
+		if h.BeforeRequest != nil {
+			req = req.WithContext(h.BeforeRequest(req.Context(), operation, req))
+		}
+	}
+	resp, err := c.intercept(req)
+	for _, h := range c.Hooks {
+		if h.AfterResponse != nil {
+			h.AfterResponse(req.Context(), operation, req, resp, err)
+		}
+	}
+	return resp, err
+}
+
+// intercept sends req through the interceptors.
+func (c *Client) intercept(req *http.Request) (*http.Response, error) {
+	for _, intercept := range c.RequestInterceptors {
+		if err := intercept(req); err != nil {
+			return nil, err
+		}
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, intercept := range c.ResponseInterceptors {
+		if err := intercept(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// retryable says whether req, which failed with err or was answered with resp, is sent again.
+func (p RetryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(req, resp, err)
+	}
+	if req.Method == http.MethodPost || req.Method == http.MethodPatch {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// lacQuery sets the query parameter name to v, an array or an object, as style and explode say.
+// Arrays are joined with the delimiter of the style, the fields of objects are written as
+// name[field] by deepObject, as keys of their own when exploded and joined with commas, after
+// their keys, otherwise.
+func lacQuery(query url.Values, name, style string, explode bool, v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Len() == 0 {
+			return
+		}
+		values := make([]string, rv.Len())
+		for i := range values {
+			values[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		if style == "form" && explode {
+			query[name] = append(query[name], values...)
+			return
+		}
+		query.Set(name, strings.Join(values, lacDelimiter(style)))
+	case reflect.Struct:
+		var pairs []string
+		for i := 0; i < rv.NumField(); i++ {
+			key, omitEmpty := lacFieldKey(rv.Type().Field(i))
+			f := rv.Field(i)
+			if key == "" || (f.Kind() == reflect.Ptr && f.IsNil()) || (omitEmpty && f.IsZero()) {
+				continue
+			}
+			value := fmt.Sprint(reflect.Indirect(f).Interface())
+			switch {
+			case style == "deepObject":
+				query.Set(name+"["+key+"]", value)
+			case explode:
+				query.Set(key, value)
+			default:
+				pairs = append(pairs, key, value)
+			}
+		}
+		if len(pairs) > 0 {
+			query.Set(name, strings.Join(pairs, ","))
+		}
+	default:
+		query.Set(name, fmt.Sprint(rv.Interface()))
+	}
+}
+`)
+	writeStyleHelpers(w)
+}
+
+// bodyKinds says whether any of the operations sends an XML body and whether any sends a
+// multipart one.
+func bodyKinds(ops []apiOperation) (bool, bool) {
+	xmlBodies, multipartBodies := false, false
+	for _, op := range ops {
+		for _, b := range op.bodies {
+			xmlBodies = xmlBodies || isXMLMedia(b.mediaType)
+			multipartBodies = multipartBodies || b.mediaType == formMultipart
+		}
+	}
+	return xmlBodies, multipartBodies
+}
+
+// writeEncoders writes the helpers encoding the XML and multipart bodies, when some are sent.
+func writeEncoders(w *bufio.Writer, xmlBodies, multipartBodies bool) {
+	if xmlBodies {
+		w.WriteString(`
+// lacXML returns body encoded as XML, sent as mediaType.
+func lacXML(body interface{}, mediaType string) (*lacEncoded, error) {
+	b, err := xml.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the request body: %w", err)
+	}
+	return &lacEncoded{contentType: mediaType, payload: b}, nil
+}
+`)
+	}
+	if multipartBodies {
+		w.WriteString(`
+// lacMultipart returns the multipart form write writes the parts of.
+func lacMultipart(write func(*multipart.Writer) error) (*lacEncoded, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := write(mw); err != nil {
+		return nil, fmt.Errorf("encoding the request body: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("encoding the request body: %w", err)
+	}
+	return &lacEncoded{contentType: mw.FormDataContentType(), payload: buf.Bytes()}, nil
+}
+`)
+	}
+}
+
+// writeStyleHelpers writes the helpers the client and the server share to write and read query
+// parameters with a style, the client writes them when there are both.
+func writeStyleHelpers(w *bufio.Writer) {
+	w.WriteString(`
+// lacDelimiter returns the delimiter of the values of arrays written with style.
+func lacDelimiter(style string) string {
+	switch style {
+	case "spaceDelimited":
+		return " "
+	case "pipeDelimited":
+		return "|"
+	}
+	return ","
+}
+
+// lacFieldKey returns the key of a field in the JSON of its struct, empty when it has none, and
+// whether it is left out when empty.
+func lacFieldKey(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" || f.Anonymous {
+		return "", false
+	}
+	tag := strings.Split(f.Tag.Get("json"), ",")
+	omitEmpty := false
+	for _, opt := range tag[1:] {
+		omitEmpty = omitEmpty || opt == "omitempty"
+	}
+	switch tag[0] {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, omitEmpty
+	}
+	return tag[0], omitEmpty
+}
+`)
+}
+
+// writeClientMethod writes the methods of op, one for each content type of its request body,
+// and, when it has query or header parameters, the struct holding them.
+func writeClientMethod(w *bufio.Writer, op apiOperation) {
+	if len(op.params) > 0 {
+		writeOperationParams(w, op)
+	}
+	if len(op.bodies) == 0 {
+		writeClientCall(w, op, apiBody{})
+		return
+	}
+	for _, b := range op.bodies {
+		writeClientCall(w, op, b)
+	}
+}
+
+// writeClientCall writes the method of op sending its request body as b.
+func writeClientCall(w *bufio.Writer, op apiOperation, b apiBody) {
+	name := op.name + b.suffix
+	fmt.Fprintf(w, "\n// %s sends %s %s", name, op.method, op.path)
+	if b.mediaType != "" && !isJSONMedia(b.mediaType) {
+		fmt.Fprintf(w, " with the body as %s", b.mediaType)
+	}
+	fmt.Fprint(w, ".\n")
+	writeSummary(w, "", op)
+	fmt.Fprintf(w, "func (c *Client) %s {\n", methodSignature(name, b.goType, op))
+	if op.result != "" {
+		fmt.Fprintf(w, "\tvar result %s\n", op.result)
+	}
+	query, header := "nil", "nil"
+	for _, p := range op.params {
+		if p.in == "query" && query == "nil" {
+			query = "query"
+			fmt.Fprint(w, "\tquery := url.Values{}\n")
+		}
+		if p.in == "header" && header == "nil" {
+			header = "header"
+			fmt.Fprint(w, "\theader := http.Header{}\n")
+		}
+	}
+	for _, p := range op.params {
+		target := "query"
+		if p.in == "header" {
+			target = "header"
+		}
+		v := "params." + p.goName
+		switch {
+		case p.in == "query" && !p.repeated():
+			fmt.Fprintf(w, "\tlacQuery(query, %q, %q, %t, %s)\n", p.name, p.style, p.explode, v)
+		case strings.HasPrefix(p.goType, "[]"):
+			fmt.Fprintf(w, "\tfor _, v := range %s {\n\t\t%s.Add(%q, %s)\n\t}\n", v, target, p.name, paramString("v", p.goType[2:]))
+		case p.required:
+			fmt.Fprintf(w, "\t%s.Set(%q, %s)\n", target, p.name, paramString(v, p.goType))
+		default:
+			fmt.Fprintf(w, "\tif %s != nil {\n\t\t%s.Set(%q, %s)\n\t}\n", v, target, p.name, paramString("*"+v, p.goType))
+		}
+	}
+	body, result := "nil", "nil"
+	if b.goType != "" {
+		body = "body"
+	}
+	if op.result != "" {
+		result = "&result"
+	}
+	// the bodies that are not JSON are encoded before the call, the urlencoded ones can not fail.
+	declare, failed := ":=", "return err"
+	if op.result != "" {
+		failed = "return result, err"
+	}
+	encoder := ""
+	switch {
+	case b.mediaType == "" || isJSONMedia(b.mediaType):
+	case isXMLMedia(b.mediaType):
+		encoder = fmt.Sprintf("lacXML(body, %q)", b.mediaType)
+	case b.mediaType == formMultipart:
+		encoder = "lacMultipart(body.WriteMultipart)"
+	default:
+		fmt.Fprintf(w, "\tpayload := &lacEncoded{contentType: %q, payload: []byte(body.FormValues().Encode())}\n", b.mediaType)
+		body = "payload"
+	}
+	if encoder != "" {
+		fmt.Fprintf(w, "\tpayload, err := %s\n\tif err != nil {\n\t\t%s\n\t}\n", encoder, failed)
+		body, declare = "payload", "="
+	}
+	call := fmt.Sprintf("c.do(ctx, %q, %q, %s, %s, %s, %s, %s, %s)", name, op.method, pathExpression(op), query, header, body, result, errorFor(op))
+	if op.result != "" {
+		fmt.Fprintf(w, "\terr %s %s\n\treturn result, err\n}\n", declare, call)
+	} else {
+		fmt.Fprintf(w, "\treturn %s\n}\n", call)
+	}
+}
+
+// errorFor returns the function giving the error of op for a status, exact statuses go before
+// ranges and default takes the rest. It is nil when op declares no JSON body outside 2xx.
+func errorFor(op apiOperation) string {
+	if len(op.errors) == 0 {
+		return "nil"
+	}
+	var cases []string
+	fallback := "nil"
+	for _, e := range op.errors {
+		switch {
+		case e.status == "default":
+			fallback = "&" + e.typeName + "{}"
+		case len(e.status) == 3 && strings.HasSuffix(strings.ToUpper(e.status), "XX"):
+			cases = append(cases, fmt.Sprintf("\t\tcase status/100 == %c:\n\t\t\treturn &%s{}\n", e.status[0], e.typeName))
+		default:
+			cases = append(cases, fmt.Sprintf("\t\tcase status == %s:\n\t\t\treturn &%s{}\n", e.status, e.typeName))
+		}
+	}
+	if len(cases) == 0 {
+		return "func(status int) lacResponseError {\n\t\treturn " + fallback + "\n\t}"
+	}
+	return "func(status int) lacResponseError {\n\t\tswitch {\n" + strings.Join(cases, "") + "\t\t}\n\t\treturn " + fallback + "\n\t}"
+}
+
+// nameErrors names the errors of the operations after their status, NotFoundError for 404, and
+// returns the types to write for them. Operations declaring a body for a status other than the
+// one of the first operation declaring it get an error of their own, ListPetsNotFoundError.
+// Errors whose name is taken are warned about and left to the ClientError.
+func nameErrors(c *config, types []*Type, ops []apiOperation) []apiError {
+	taken := make(map[string]bool, len(types)+len(clientNames))
+	for _, t := range types {
+		taken[capitalize(t.Name)] = true
+	}
+	for _, n := range clientNames {
+		taken[n] = true
+	}
+	for _, op := range ops {
+		taken[op.name+"Params"] = true
+	}
+	byName := map[string]apiError{}
+	var result []apiError
+	for i := range ops {
+		kept := ops[i].errors[:0]
+		for _, e := range ops[i].errors {
+			name := errorName(e.status)
+			if first, ok := byName[name]; ok && first.body != e.body {
+				name = ops[i].name + name
+			}
+			if _, ok := byName[name]; !ok {
+				if taken[name] {
+					c.warn(ops[i].method+" "+ops[i].path, "the name %s is taken, the %s responses are returned as a *ClientError", name, e.status)
+					continue
+				}
+				byName[name] = e
+				result = append(result, apiError{status: e.status, body: e.body, typeName: name})
+			}
+			e.typeName = name
+			kept = append(kept, e)
+		}
+		ops[i].errors = kept
+	}
+	return result
+}
+
+// errorName returns the name of the error of the responses with status, the text of the status
+// for codes, Status4XXError for ranges and DefaultResponseError for default.
+func errorName(status string) string {
+	if status == "default" {
+		return "DefaultResponseError"
+	}
+	text := ""
+	if code, err := strconv.Atoi(status); err == nil {
+		text = http.StatusText(code)
+	}
+	if text == "" {
+		return "Status" + strings.ToUpper(nonIdentifier.ReplaceAllString(status, "")) + "Error"
+	}
+	name := strings.ReplaceAll(strings.Title(strings.ToLower(nonIdentifier.ReplaceAllString(text, " "))), " ", "")
+	return strings.TrimSuffix(name, "Error") + "Error"
+}
+
+// writeErrorType writes the error holding the body of the responses e is for.
+func writeErrorType(w *bufio.Writer, e apiError) {
+	switch {
+	case e.status == "default":
+		fmt.Fprintf(w, "\n// %s is returned for the responses with a status other than the ones the\n", e.typeName)
+		w.WriteString("// operation declares, along with their body.\n")
+	case strings.HasSuffix(strings.ToUpper(e.status), "XX"):
+		fmt.Fprintf(w, "\n// %s is returned for the %s responses, along with their body.\n", e.typeName, strings.ToUpper(e.status))
+	default:
+		fmt.Fprintf(w, "\n// %s is returned for the %s responses, along with their body.\n", e.typeName, e.status)
+	}
+	fmt.Fprintf(w, "type %s struct {\n\tStatusCode int\n\tBody       %s\n}\n\n", e.typeName, e.body)
+	verb := "%+v"
+	if e.body == "json.RawMessage" {
+		verb = "%s"
+	}
+	fmt.Fprintf(w, "func (e *%s) Error() string {\n", e.typeName)
+	fmt.Fprintf(w, "\treturn fmt.Sprintf(\"unexpected status %%d: %s\", e.StatusCode, e.Body)\n}\n\n", verb)
+	fmt.Fprintf(w, "func (e *%s) decode(status int, body []byte) error {\n", e.typeName)
+	w.WriteString("\te.StatusCode = status\n\treturn json.Unmarshal(body, &e.Body)\n}\n")
+}
+
+// paramString returns the expression of v, of type tn, as the string it is sent as.
+func paramString(v, tn string) string {
+	if tn == "string" {
+		return v
+	}
+	return "fmt.Sprint(" + v + ")"
+}
+
+// pathExpression returns the expression of the path of op with its parameters escaped in it.
+func pathExpression(op apiOperation) string {
+	byName := make(map[string]apiParam, len(op.pathParams))
+	for _, p := range op.pathParams {
+		byName[p.name] = p
+	}
+	var parts []string
+	last := 0
+	for _, m := range pathTemplate.FindAllStringSubmatchIndex(op.path, -1) {
+		if m[0] > last {
+			parts = append(parts, strconv.Quote(op.path[last:m[0]]))
+		}
+		p := byName[op.path[m[2]:m[3]]]
+		parts = append(parts, "url.PathEscape("+paramString(p.goName, p.goType)+")")
+		last = m[1]
+	}
+	if last < len(op.path) || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(op.path[last:]))
+	}
+	return strings.Join(parts, "+")
+}
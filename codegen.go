@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -10,14 +14,56 @@ import (
 )
 
 type maybeType struct {
-	isArray          bool
-	typeOf           reflect.Type
-	nameOftype       string
-	originalFileName string
-	multiType        []string
-	description      string
+	isArray     bool
+	typeOf      reflect.Type
+	nameOftype  string
+	multiType   []string
+	description string
+	// nullable is set when the source says null is a valid value.
+	nullable bool
+	// optional is set when the source says the property is not required, samples do not say.
+	optional bool
+	// constraints holds the validation keywords of the source, if any.
+	constraints *constraints
+	// enum is set when nameOftype is a string enum rather than a struct.
+	enum bool
+	// xmlTag is the value of the xml tag when the source describes the field in XML.
+	xmlTag string
+	// contentType is the type the JSON held in a string decodes into, from its contentSchema.
+	contentType string
+	// format is the format of the source schema, of the elements for arrays.
+	format string
+	// pathType is the type given to the field with --typesforitems.
+	pathType string
+	// hint is the fully qualified type given to the field by the annotations of its sample.
+	hint string
+	// decimal is set for monetary fields, which --decimal turns into decimals.
+	decimal bool
+	// duration is set for strings holding durations, which --durations turns into Duration.
+	duration bool
+	// time is the kind of time held by strings, date or date-time, see timeFor.
+	time string
+	// base64 is set for strings holding base64, which --lenient-base64 turns into Base64.
+	base64 bool
+	// netip is the net/netip type of strings holding addresses or prefixes, used with --netip.
+	netip string
+	// pointer is set for struct fields too large to be held by value, from --pointer-above-bytes.
+	pointer bool
+	// readOnly is set for the properties set by servers, which --split-read-only leaves out of
+	// the request types.
+	readOnly bool
+	// goType is the Go type x-go-type gives the field, goImport the package it needs.
+	goType   string
+	goImport string
+	// keyPatterns are the patterns of the patternProperties of maps, for their comment.
+	keyPatterns []string
+	// defaultValue is the default of the property, which --constructors fills the field with.
+	defaultValue json.RawMessage
 }
 
+// bytesType is the type of strings holding base64 encoded data, encoding/json handles them.
+var bytesType = reflect.TypeOf([]byte(nil))
+
 func (m *maybeType) IsMultiple() bool {
 	return len(m.multiType) > 0
 }
@@ -49,6 +95,9 @@ func (m *maybeType) Resolve() (string, string) {
 
 	// This is a go primitive or not but we slipped through the other cracks.
 	tname := m.typeOf.Name()
+	if m.typeOf == bytesType {
+		tname = "[]byte"
+	}
 	if tname == "" {
 		tname = "interface{}"
 	}
@@ -58,6 +107,12 @@ func (m *maybeType) Resolve() (string, string) {
 	return m.typeOf.PkgPath(), tname
 }
 
+// isStructArray returns true for arrays of one of the generated structs.
+func (m *maybeType) isStructArray() bool {
+	return m.isArray && !m.IsMultiple() && m.typeOf == nil && !m.enum && m.nameOftype != "" &&
+		m.nameOftype != "interface{}" && !strings.HasPrefix(m.nameOftype, "map[")
+}
+
 // Equals roughly compares type metadatas, it is incomplete
 func (m *maybeType) Equals(mt *maybeType) bool {
 	if m.typeOf != nil && mt.typeOf != nil {
@@ -70,169 +125,563 @@ func (m *maybeType) Equals(mt *maybeType) bool {
 }
 
 func capitalize(s string) string {
+	return capitalizeMemo.get(s, func() string { return capitalizeUncached(s) })
+}
+
+// capitalizeUncached turns s into an exported Go name in a single pass over it, the parts
+// separated by any of ._-\ are capitalized and joined.
+func capitalizeUncached(s string) string {
 	if s == "interface{}" {
 		return s
 	}
 	if strings.HasPrefix(s, "map[") {
 		return s
 	}
-	// . is likely a parented type
-	s = strings.Replace(s, ".", "_", -1)
-	s = strings.Replace(s, "-", "_", -1)
-	s = strings.Replace(s, "\\", "_", -1)
-	parts := strings.Split(s, "_")
-	for i, p := range parts {
-		pl := strings.ToLower(p)
-		switch pl {
-		case "url":
-			p = "URL"
-		case "id":
-			p = "ID"
-		case "json":
-			p = "JSON"
-		case "html":
-			p = "HTML"
+	b := &strings.Builder{}
+	b.Grow(len(s))
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		// . is likely a parented type
+		if i < len(s) && s[i] != '.' && s[i] != '-' && s[i] != '\\' && s[i] != '_' {
+			continue
 		}
+		b.WriteString(capitalizePart(s[start:i]))
+		start = i + 1
+	}
+	return b.String()
+}
 
-		for _, s := range []string{"url", "id", "html"} {
-			if strings.HasSuffix(pl, s) {
-				p = p[:len(p)-len(s)] + strings.ToUpper(s)
-			}
-			if strings.HasPrefix(pl, s) {
-				p = strings.ToUpper(s) + p[len(s):]
-			}
-		}
+// capitalizePart capitalizes a single part of a name taking care of initialisms.
+func capitalizePart(p string) string {
+	pl := strings.ToLower(p)
+	switch pl {
+	case "url":
+		p = "URL"
+	case "id":
+		p = "ID"
+	case "json":
+		p = "JSON"
+	case "html":
+		p = "HTML"
+	}
 
-		parts[i] = strings.Title(p)
+	for _, s := range []string{"url", "id", "html"} {
+		if strings.HasSuffix(pl, s) {
+			p = p[:len(p)-len(s)] + strings.ToUpper(s)
+		}
+		if strings.HasPrefix(pl, s) {
+			p = strings.ToUpper(s) + p[len(s):]
+		}
 	}
-	return strings.Join(parts, "")
+
+	return strings.Title(p)
 }
 
 // makeMeCode will get our common structure and make it into go, we do not use AST or anything
-// else as it seems this is a more reasonable way.
-func makeMeCode(c *config, typeMap map[string]map[string]maybeType,
-	outerTypeNames map[string]string,
-	extraComments map[string]string,
-	out io.Writer) {
-	heading := &strings.Builder{}
-	heading.WriteString(fmt.Sprintf("package %s\n", c.targetPackage))
-	imports := map[string]bool{}
-	code := &strings.Builder{}
-	typeNames := make([]string, 0, len(typeMap))
-	for tk := range typeMap {
-		typeNames = append(typeNames, tk)
-	}
-	sort.Strings(typeNames)
-	for typeToFiles, fname := range outerTypeNames {
-		fmt.Printf("type %s is in file %s\n", typeToFiles, fname)
-	}
-	for _, tk := range typeNames {
-		// file used to generate this type, might be useful to trace back generation errors.
-		fileName, ok := outerTypeNames[tk]
-		if !ok {
-			fmt.Printf("could not find '%s' \n", tk)
-			fileName = "unknown"
-			if c.swaggerFile != "" {
-				fileName = c.swaggerFile
-			}
+// else as it seems this is a more reasonable way. Types are streamed one by one to out so memory
+// does not grow with the size of the output.
+func makeMeCode(ctx context.Context, c *config, types []*Type, out io.Writer) error {
+	for _, t := range types {
+		c.debugf("type %s is in file %s\n", t.Name, t.Source)
+	}
+	markUnions(c, types)
+	markTypePaths(c, types)
+	markDurations(c, types)
+	markTimes(c, types)
+	markBase64(c, types)
+	markSQLNulls(c, types)
+	if err := markPointers(ctx, c, types); err != nil {
+		return err
+	}
+	markExtra(c, types)
+	markChecks(c, types)
+	w := bufio.NewWriter(out)
+	writeHeading(w, c, collectImports(c, types))
+	for _, t := range types {
+		if t.extra && c.lossless {
+			fmt.Fprint(w, losslessHelpers)
+			break
+		}
+	}
+	if needsDurations(c, types) {
+		io.WriteString(w, durationHelpers)
+		io.WriteString(w, durationMarshalers[durationForm(c)])
+	}
+	writeTimeHelpers(w, c, types)
+	writeSQLNullHelpers(w, c, types)
+	if needsBase64(c, types) {
+		io.WriteString(w, base64Helpers)
+	}
+	if needsPatternHelpers(c, types) {
+		io.WriteString(w, patternHelpers)
+	}
+	validator := hasValidator(types)
+	if validator {
+		fmt.Fprint(w, validatorInterface)
+	}
+	var structs map[string]bool
+	if c.k8s {
+		structs = k8sStructs(types)
+	}
+	var taken map[string]bool
+	if c.constructors {
+		taken = make(map[string]bool, len(types))
+		for _, t := range types {
+			taken[capitalize(t.Name)] = true
 		}
-		tvs := typeMap[tk]
-		// Ensure the same JSON will always yield the same output (there are a few exceptions) for
-		// too repetitive JSONs with the parsing order causing one type to be named in one way and
-		// the next repeated name will have a prefix, swagger and reasonable JSON does not have this
-		// problem
-		fieldNames := make([]string, 0, len(tvs))
-		for tn := range tvs {
-			fieldNames = append(fieldNames, tn)
+	}
+	for _, t := range types {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("emitting %s: %w", t.Name, err)
 		}
-		sort.Strings(fieldNames)
-		structName := capitalize(tk)
-
-		// Add a comment that Go likes, if possible also add extra comments if source provides.
-		code.WriteString(fmt.Sprintf("// %s is auto generated by github.com/perrito666/LAC from \"%s\" json file\n", structName, fileName))
-		ec, ok := extraComments[tk]
-		if ok {
-			code.WriteString(fmt.Sprintf("// %s \n", strings.Replace(ec, "\n", "\n// ", -1)))
+		writeType(w, c, t)
+		writeConstructor(w, c, t, capitalize(t.Name), taken)
+		switch {
+		case t.union != nil:
+			writeUnionMethods(w, t, capitalize(t.Name))
+		case t.extra && c.lossless:
+			writeLosslessMethods(w, t, capitalize(t.Name))
+		case t.extra:
+			writeUnmarshalExtra(w, t, capitalize(t.Name))
+		case t.tuple:
+			writeTupleMethods(w, t, capitalize(t.Name))
 		}
+		writeContentHelpers(w, c, t, capitalize(t.Name))
+		writeFormMethods(w, c, t, capitalize(t.Name))
+		writeParamMethods(w, t, capitalize(t.Name))
+		writeValidate(w, t, capitalize(t.Name))
+		if c.k8s {
+			writeK8sMethods(w, c, t, structs)
+		}
+		writeAssertions(w, c, t, capitalize(t.Name), validator)
+	}
+	if c.serveSpec {
+		if err := writeSpecHandler(ctx, w, c); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing types: %w", err)
+	}
+	return nil
+}
 
-		// type definition
-		code.WriteString(fmt.Sprintf("type %s struct {\n", structName))
-		for _, fn := range fieldNames {
-			f := tvs[fn]
-			pkg, tn := f.Resolve()
-			// this comes from an external package, so we add an import.
-			if pkg != "" {
-				imports[pkg] = true
+// collectImports returns the sorted imports needed by types plus the ones the user asked for,
+// they need to be known before the first type is written.
+func collectImports(c *config, types []*Type) []string {
+	seen := map[string]bool{}
+	imports := make([]string, 0, len(c.imports))
+	for _, i := range c.imports {
+		if !seen[i] {
+			seen[i] = true
+			imports = append(imports, i)
+		}
+	}
+	if needsDurations(c, types) {
+		for _, pkg := range durationImports {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// this is an embeddable type, happens to anyOf, oneOf, allOf definitions.
-			if fn == "" {
-				code.WriteString(tn)
-				break
+		}
+	}
+	if needsPatternHelpers(c, types) {
+		for _, pkg := range patternImports {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// Make sure the name is as Go lint compliant as possible.
-			capitalizedFN := capitalize(fn)
-			if unicode.IsDigit(rune(capitalizedFN[0])) {
-				capitalizedFN = "N" + capitalizedFN
+		}
+	}
+	if needsBase64(c, types) {
+		for _, pkg := range base64Imports {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// is this type a type we want replaced?
-			replacementType, ok := c.replaceTypes[tn]
-			if ok {
-				tn = replacementType
+		}
+	}
+	if len(timeHelpersFor(c, types)) > 0 {
+		for _, pkg := range []string{"encoding/json", "time"} {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// is this one of the paths for which we specified a type?
-			typeForPath, ok := c.typesForItems[fmt.Sprintf("%s.%s", structName, capitalizedFN)]
-			if ok {
-				tn = typeForPath
+		}
+	}
+	if len(sqlNullHelpersFor(c, types)) > 0 {
+		for _, pkg := range sqlNullImports {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// if somehow this got all the way through empty, it becomes empty interface.
-			if tn == "" {
-				tn = "interface{}"
+		}
+	}
+	if c.serveSpec {
+		for _, pkg := range []string{"io", "net/http"} {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
 			}
-
-			// this kind of recursion is not allowed in Go without pointers
-			if tn == structName {
-				tn = "*" + tn // otherwise we get an illegal cycle
+		}
+	}
+	for _, t := range types {
+		if t.extra || t.tuple || t.param || t.union != nil || hasContentHelpers(t) {
+			pkgs := []string{"encoding/json"}
+			switch {
+			case t.union != nil:
+				pkgs = append(pkgs, "fmt")
+			case t.extra && c.lossless:
+				pkgs = losslessImports
 			}
-
-			// We have a description for the field, we add it formatting for go linter to be happy.
-			if f.description != "" {
-				code.WriteString(fmt.Sprintf("// %s is the %s\n", capitalizedFN, strings.Replace(f.description, "\n", "\n// ", -1)))
+			for _, pkg := range pkgs {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
+			}
+		}
+		if c.k8s && isK8sRoot(t) {
+			for _, pkg := range []string{k8sMetaImport, k8sRuntimeImport} {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
+			}
+		}
+		for _, pkg := range formImports(c, t) {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
+			}
+		}
+		for _, chk := range t.checks {
+			for _, pkg := range chk.imports {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
+			}
+		}
+		if len(t.Enum) > 0 && (c.enumUnknown == enumUnknownError || c.enumUnknown == enumUnknownUnknown) {
+			pkgs := []string{"encoding/json"}
+			if c.enumUnknown == enumUnknownError {
+				pkgs = append(pkgs, "fmt")
+			}
+			for _, pkg := range pkgs {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
+			}
+		}
+		if len(t.Enum) > 0 && c.gqlgen {
+			for _, pkg := range []string{"fmt", "io", "strconv"} {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
 			}
+		}
+		structName := capitalize(t.Name)
+		for _, fld := range t.Fields {
+			if fld.Name == "" {
+				continue
+			}
+			_, pkgs := fieldType(c, structName, fld.GoName(), &fld.Type)
+			for _, pkg := range pkgs {
+				if !seen[pkg] {
+					seen[pkg] = true
+					imports = append(imports, pkg)
+				}
+			}
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
 
-			// this is either anyOf, oneOf or allOf so we embed the components into an anonymous
-			// struct and hope for the best.
-			// TODO make this a more complex struct and gemerate marshaling functions.
-			if f.IsMultiple() {
-				code.WriteString(fmt.Sprintf("\t%s  struct {\n", capitalizedFN))
-				code.WriteString(fmt.Sprintf("\t%s \n", tn))
-				code.WriteString(fmt.Sprintf("\t} `json:\"%s\"`\n", fn))
+// writeHeading writes the package clause and imports.
+func writeHeading(w io.Writer, c *config, imports []string) {
+	if c.snippet {
+		return
+	}
+	fmt.Fprintf(w, "package %s\n", c.targetPackage)
+	if len(imports) > 0 {
+		fmt.Fprint(w, "import (\n")
+		for _, i := range imports {
+			// named imports come already quoted.
+			if strings.Contains(i, `"`) {
+				fmt.Fprintf(w, "\t%s\n", i)
 				continue
 			}
+			fmt.Fprintf(w, "\t\"%s\"\n", i)
+		}
+		fmt.Fprint(w, ")\n")
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// fieldName returns the Go name of a field, making sure it is as Go lint compliant as possible.
+func fieldName(fn string) string {
+	capitalizedFN := capitalize(fn)
+	if unicode.IsDigit(rune(capitalizedFN[0])) {
+		capitalizedFN = "N" + capitalizedFN
+	}
+	return capitalizedFN
+}
+
+// fieldType returns the Go type of a field after applying all the replacements requested, along
+// with the packages that type needs imported.
+func fieldType(c *config, structName, capitalizedFN string, f *maybeType) (string, []string) {
+	pkg, tn := f.Resolve()
+	var imports []string
+	// arrays of structs can be asked to hold pointers.
+	if c.arrayStyle == arrayStylePointer && f.isStructArray() {
+		tn = "[]*" + strings.TrimPrefix(tn, "[]")
+	}
+	// money should not be held in floats.
+	if dt, dtPkg, ok := decimalFor(c, f); ok {
+		tn, pkg = dt, dtPkg
+	}
+	if c.durations && f.duration {
+		tn, pkg = durationType, ""
+		if f.isArray {
+			tn = "[]" + tn
+		}
+	}
+	if c.netip && f.netip != "" {
+		tn, pkg = f.netip, netipImport
+		if f.isArray {
+			tn = "[]" + tn
+		}
+	}
+	if tt, ttPkg, ok := timeFor(c, f); ok {
+		tn, pkg = tt, ttPkg
+	}
+	if c.lenientBase64 && f.base64 {
+		tn, pkg = base64Type, ""
+		if f.isArray {
+			tn = "[]" + tn
+		}
+	}
+	// this comes from an external package, so we add an import.
+	if pkg != "" {
+		imports = append(imports, pkg)
+	}
+
+	// nullable columns need a type that can hold NULL.
+	nullType := false
+	if c.dbMode != "" && f.nullable && !f.isArray {
+		if nt, ntPkg, ok := dbNullType(c.dbMode, tn); ok && !c.sqlNullsTaken[nt] {
+			// the null type replaces the one of the package, ie time.Time.
+			tn = nt
+			imports = append(imports[:0], ntPkg)
+			nullType = true
+		}
+	}
 
-			// Add a tag
-			code.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\"`\n", capitalizedFN, tn, fn))
+	// is this type a type we want replaced?
+	replacementType, ok := c.replaceTypes[tn]
+	if ok {
+		tn = replacementType
+	}
+
+	// does the scalar map have a type for it?
+	if scalar, scalarPkg, ok := scalarFor(c, structName, capitalizedFN, f); ok {
+		tn = scalar
+		imports = imports[:0]
+		if scalarPkg != "" {
+			imports = append(imports, scalarPkg)
+		}
+	}
+
+	// does the spec say what Go type it is? It wins over --replacetypes and --scalar-map.
+	if f.goType != "" {
+		tn = f.goType
+		nullType = false
+		imports = imports[:0]
+		if f.goImport != "" {
+			imports = append(imports, f.goImport)
 		}
-		code.WriteString(fmt.Sprintf("}\n\n"))
 	}
 
-	// add the imports
-	for i := range imports {
-		c.imports = append(c.imports, i)
+	// do the annotations of the sample say what it is?
+	if f.hint != "" {
+		hint, pkg := qualifiedType(f.hint)
+		tn = hint
+		imports = imports[:0]
+		if pkg != "" {
+			imports = append(imports, pkg)
+		}
+	}
+
+	// is this one of the paths for which we specified a type?
+	if f.pathType != "" {
+		tn = f.pathType
+	}
+
+	// if somehow this got all the way through empty, it becomes empty interface.
+	if tn == "" {
+		tn = "interface{}"
+	}
+
+	// large structs are cheaper to copy behind a pointer.
+	if f.pointer && !strings.HasPrefix(tn, "*") {
+		tn = "*" + tn
+	}
+
+	// nullable fields are pointers so null can be told apart from the zero value, gqlgen expects
+	// them even for the types that can be nil or hold NULL already. With --optional pointer so are
+	// the fields that can be missing.
+	if f.nullable && !f.isArray && !strings.HasPrefix(tn, "*") && (c.gqlgen || !nullType && !canBeNil(tn)) {
+		tn = "*" + tn
+	}
+	if c.optional == optionalPointer && f.optional && !f.isArray && !strings.HasPrefix(tn, "*") && !canBeNil(tn) {
+		tn = "*" + tn
+	}
+	return tn, imports
+}
+
+// provenanceRoot returns the absolute directory of --trim-prefix or, without one, the root of the
+// module the target is in, the current one when writing to stdout. It is empty for neither.
+func provenanceRoot(c *config) string {
+	dir := c.trimPrefix
+	if dir == "" {
+		dir = filepath.Dir(c.targetFile)
+	}
+	if isRemote(dir) {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	if c.trimPrefix != "" {
+		return abs
+	}
+	root, _, _ := moduleRoot(c, abs)
+	return root
+}
+
+// provenancePath returns the source of a type as written by --provenance full, relative to the
+// sourceRoot with forward slashes so it reads the same on every machine. Sources out of it, and
+// URLs not starting with --trim-prefix, are written as given.
+func provenancePath(c *config, source string) string {
+	if isRemote(source) {
+		if c.trimPrefix != "" && strings.HasPrefix(source, c.trimPrefix) {
+			return strings.TrimPrefix(strings.TrimPrefix(source, c.trimPrefix), "/")
+		}
+		return source
+	}
+	if c.sourceRoot == "" {
+		return source
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return source
+	}
+	rel, err := filepath.Rel(c.sourceRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return source
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeType writes the definition of a single type.
+func writeType(w io.Writer, c *config, t *Type) {
+	structName := capitalize(t.Name)
+
+	// Add a comment that Go likes, if possible also add extra comments if source provides.
+	// The first line is kept with --provenance none as --aliases tells the generated types by it.
+	if c.provenance == provenanceNone {
+		fmt.Fprintf(w, "// %s is auto generated by github.com/perrito666/LAC.\n", structName)
+	} else {
+		// file used to generate this type, might be useful to trace back generation errors.
+		fileName := t.Source
+		if fileName == "" {
+			fileName = "unknown"
+			c.warn(t.Name, "could not find the file this type was generated from")
+		} else if c.provenance == provenanceFilename {
+			fileName = filepath.Base(fileName)
+		} else {
+			fileName = provenancePath(c, fileName)
+		}
+		fmt.Fprintf(w, "// %s is auto generated by github.com/perrito666/LAC from \"%s\" json file\n", structName, fileName)
+	}
+	writeDescription(w, c, "", t.Description)
+	if c.k8s {
+		writeK8sTypeMarkers(w, t)
+	}
+
+	if len(t.Enum) > 0 {
+		writeEnum(w, c, t, structName)
+		return
+	}
+	if t.union != nil {
+		writeUnion(w, t, structName)
+		return
+	}
+
+	// type definition
+	fmt.Fprintf(w, "type %s struct {\n", structName)
+	root := c.k8s && isK8sRoot(t)
+	if root {
+		writeK8sRootMeta(w, t)
+	}
+	for _, fld := range t.Fields {
+		fn, f := fld.Name, fld.Type
+		if root && isK8sMetaField(fn) {
+			continue
+		}
+		_, tn := f.Resolve()
+
+		// this is an embeddable type, happens to anyOf, oneOf, allOf definitions.
+		if fn == "" {
+			fmt.Fprint(w, tn)
+			continue
+		}
+
+		capitalizedFN := fld.GoName()
+		tn, _ = fieldType(c, structName, capitalizedFN, &f)
+
+		// this kind of recursion is not allowed in Go without pointers
+		if tn == structName {
+			tn = "*" + tn // otherwise we get an illegal cycle
+		}
+
+		// We have a description for the field, we add it formatting for go linter to be happy.
+		writeDescription(w, c, capitalizedFN+" is the ", f.description)
+		if cs := f.constraints; cs != nil && cs.keys != nil {
+			if doc := keysDoc(cs.keys); doc != "" {
+				fmt.Fprintf(w, "// The keys of %s must %s.\n", capitalizedFN, doc)
+			}
+		}
+		if len(f.keyPatterns) > 0 {
+			fmt.Fprintf(w, "// The values of %s are described for the keys matching %s.\n", capitalizedFN, sanitizeComment(strings.Join(f.keyPatterns, " or ")))
+		}
+		if c.k8s {
+			writeK8sFieldMarkers(w, f.constraints)
+		}
+
+		// this is either anyOf, oneOf or allOf so we embed the components into an anonymous
+		// struct and hope for the best.
+		// TODO make this a more complex struct and gemerate marshaling functions.
+		if f.IsMultiple() {
+			fmt.Fprintf(w, "\t%s  struct {\n", capitalizedFN)
+			fmt.Fprintf(w, "\t%s \n", tn)
+			fmt.Fprintf(w, "\t} %s\n", fieldTag(c, t, fn, "", &f))
+			continue
+		}
+
+		// Add a tag
+		fmt.Fprintf(w, "\t%s %s %s\n", capitalizedFN, tn, fieldTag(c, t, fn, tn, &f))
 	}
-	sort.Strings(c.imports)
-	if len(c.imports) > 0 {
-		heading.WriteString("import (\n")
-		for _, i := range c.imports {
-			heading.WriteString(fmt.Sprintf(`\t"%s"\n`, i))
+	if t.extra {
+		writeExtraField(w, c, t)
+		if c.lossless {
+			writeLosslessField(w)
 		}
-		heading.WriteString(")\n")
 	}
-	heading.WriteString("\n")
-	out.Write([]byte(heading.String()))
-	out.Write([]byte(code.String()))
+	fmt.Fprint(w, "}\n\n")
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// codeFormats are the formats of the well known codes --codes can map, by the kind of code.
+var codeFormats = map[string][]string{
+	"currency": {"iso-4217", "iso4217", "currency", "currency-code"},
+	"country":  {"iso-3166", "iso3166", "iso-3166-1", "iso-3166-alpha-2", "iso-3166-alpha-3", "country", "country-code"},
+	"language": {"iso-639", "iso-639-1", "bcp47", "bcp-47", "language", "language-code"},
+}
+
+// codeRules returns the scalar rules mapping the formats of the kinds of codes to the fully
+// qualified types they were given, as passed in --codes.
+func codeRules(codes map[string]string) ([]scalarRule, error) {
+	kinds := make([]string, 0, len(codes))
+	for kind := range codes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	var rules []scalarRule
+	for _, kind := range kinds {
+		formats, ok := codeFormats[strings.ToLower(kind)]
+		if !ok {
+			return nil, fmt.Errorf("unknown kind of code %q, use currency, country or language", kind)
+		}
+		if codes[kind] == "" {
+			return nil, fmt.Errorf("no type given for %s codes", kind)
+		}
+		name, pkg := qualifiedType(codes[kind])
+		for _, format := range formats {
+			rules = append(rules, scalarRule{Type: string(STString), Format: format, Go: codes[kind], name: name, pkg: pkg})
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// writeDescription writes a description from the source as a comment, prefix is prepended to
+// the first line, ie "Name is the " for fields. Nothing is written with --no-descriptions.
+func writeDescription(w io.Writer, c *config, prefix, description string) {
+	if c.noDescription || description == "" {
+		return
+	}
+	for _, l := range commentLines(c, prefix+description) {
+		if l == "" {
+			fmt.Fprint(w, "//\n")
+			continue
+		}
+		fmt.Fprintf(w, "// %s\n", l)
+	}
+}
+
+// commentLines sanitizes text and splits it in lines that fit in --comment-width, the line
+// breaks of the source are kept.
+func commentLines(c *config, text string) []string {
+	text = sanitizeComment(rewriteDescription(c, text))
+	if c.stripMarkdown {
+		text = stripMarkdown(text)
+	}
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		lines = append(lines, wrapLine(strings.TrimRightFunc(l, unicode.IsSpace), c.commentWidth-len("// "))...)
+	}
+	// trailing empty lines would be empty comments.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// sanitizeComment removes what can not be in a line comment or would end a block comment if the
+// text is ever used in one, carriage returns and other control characters are dropped.
+func sanitizeComment(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "*/", "* /", -1)
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// wrapLine splits l at spaces so no line is longer than width, unless a single word is. Leading
+// indentation, such as that of markdown lists, is kept in the first line only.
+func wrapLine(l string, width int) []string {
+	if width <= 0 || len(l) <= width {
+		return []string{l}
+	}
+	var lines []string
+	indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+	current := indent
+	for _, word := range strings.Fields(l) {
+		switch {
+		case strings.TrimSpace(current) == "":
+			current += word
+		case len(current)+1+len(word) > width:
+			lines = append(lines, current)
+			current = word
+		default:
+			current += " " + word
+		}
+	}
+	return append(lines, current)
+}
+
+var (
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdBold     = regexp.MustCompile(`(\*\*|__)([^*_]+)(\*\*|__)`)
+	mdEmphasis = regexp.MustCompile(`(^|[\s(])[*_]([^*_\s][^*_]*)[*_]`)
+	mdCode     = regexp.MustCompile("`+([^`]+)`+")
+	mdHeading  = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	mdHTML     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// stripMarkdown turns the markdown commonly found in descriptions into plain text, links keep
+// their target between parenthesis so it is not lost.
+func stripMarkdown(text string) string {
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1 ($2)")
+	text = mdBold.ReplaceAllString(text, "$2")
+	text = mdEmphasis.ReplaceAllString(text, "$1$2")
+	text = mdCode.ReplaceAllString(text, "$1")
+	text = mdHeading.ReplaceAllString(text, "")
+	return mdHTML.ReplaceAllString(text, "")
+}
+
+// descRewrite is a replacement applied to descriptions before they become comments.
+type descRewrite struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// parseLinkPrefix parses an old=new --link-prefix, old is replaced by new wherever a link or path
+// starts with it, ie in markdown link targets, href attributes and bare words.
+func parseLinkPrefix(rule string) (descRewrite, error) {
+	i := strings.Index(rule, "=")
+	if i <= 0 {
+		return descRewrite{}, fmt.Errorf("link prefix %q must be old=new", rule)
+	}
+	return descRewrite{
+		re:          regexp.MustCompile(`(^|[\s("'=])` + regexp.QuoteMeta(rule[:i])),
+		replacement: "${1}" + strings.Replace(rule[i+1:], "$", "$$", -1),
+	}, nil
+}
+
+// parseDescRewrite parses a sed like /regex/replacement/ --desc-rewrite, the first character is
+// the delimiter so any can be used, the replacement can refer to groups as in regexp.Expand.
+func parseDescRewrite(rule string) (descRewrite, error) {
+	if len(rule) < 3 {
+		return descRewrite{}, fmt.Errorf("rewrite %q must be /regex/replacement/", rule)
+	}
+	delim := rule[:1]
+	parts := strings.Split(strings.TrimSuffix(rule[1:], delim), delim)
+	if len(parts) != 2 {
+		return descRewrite{}, fmt.Errorf("rewrite %q must be /regex/replacement/, with any delimiter instead of /", rule)
+	}
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return descRewrite{}, fmt.Errorf("compiling rewrite %q: %w", rule, err)
+	}
+	return descRewrite{re: re, replacement: parts[1]}, nil
+}
+
+// rewriteDescription applies the rewrites in the order they were given.
+func rewriteDescription(c *config, text string) string {
+	for _, r := range c.descRewrites {
+		text = r.re.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
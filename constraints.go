@@ -0,0 +1,195 @@
+package main
+
+import "strings"
+
+// constraints holds the validation keywords a schema declares for a property, only the ones
+// present in the source are set.
+type constraints struct {
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum bool
+	minLength, maxLength               *int64
+	minItems, maxItems                 *int64
+	minProperties, maxProperties       *int64
+	pattern                            string
+	required                           bool
+	enum                               []interface{}
+	// items holds the constraints of the elements of an array.
+	items *constraints
+	// not holds the constraints of the values excluded with not, they are checked by Validate.
+	not *constraints
+	// keys holds the constraints of the keys of maps, from propertyNames.
+	keys *constraints
+}
+
+// constraintsOf returns the constraints declared in m or nil if it declares none.
+func constraintsOf(m MetaSwaggerProperty) *constraints {
+	cs := &constraints{
+		minimum:          m.Minimum,
+		maximum:          m.Maximum,
+		exclusiveMinimum: m.ExclusiveMinimum,
+		exclusiveMaximum: m.ExclusiveMaximum,
+		minLength:        m.MinLength,
+		maxLength:        m.MaxLength,
+		minItems:         m.MinItems,
+		maxItems:         m.MaxItems,
+		minProperties:    m.MinProperties,
+		maxProperties:    m.MaxProperties,
+		pattern:          m.Pattern,
+		required:         m.Required.Self,
+		enum:             m.Enum,
+	}
+	if cs.empty() {
+		return nil
+	}
+	return cs
+}
+
+// empty returns true if no constraint is set.
+func (cs *constraints) empty() bool {
+	return cs.minimum == nil && cs.maximum == nil && cs.minLength == nil && cs.maxLength == nil &&
+		cs.minItems == nil && cs.maxItems == nil && cs.minProperties == nil && cs.maxProperties == nil &&
+		cs.pattern == "" && !cs.required &&
+		len(cs.enum) == 0 && cs.items == nil && cs.not == nil && cs.keys == nil
+}
+
+// propertyConstraints returns the constraints of prop, for arrays the ones of the items are
+// nested.
+func propertyConstraints(prop SwaggerProperty) *constraints {
+	cs := constraintsOf(prop.MetaSwaggerProperty)
+	if prop.Type != STArray {
+		return cs
+	}
+	items := constraintsOf(prop.Items.MetaSwaggerProperty)
+	if items == nil {
+		return cs
+	}
+	if cs == nil {
+		cs = &constraints{}
+	}
+	cs.items = items
+	return cs
+}
+
+// notConstraints returns the constraints of the not schema of prop, values meeting all of them are
+// excluded. Only validation keywords can be checked, nil is returned for the rest and for schemas
+// excluding nothing.
+func notConstraints(c *config, source string, prop SwaggerProperty) *constraints {
+	not := prop.Not
+	if not.Ref != "" || len(not.AllOf)+len(not.AnyOf)+len(not.OneOf) > 0 || not.AdditionalProperties != nil {
+		c.warn(source, "not can only be checked for validation keywords, the exclusion is ignored")
+		return nil
+	}
+	switch {
+	case not.Type == "" || not.Type == prop.Type:
+	case prop.Type != "" && !(prop.Type == STNumber && not.Type == STInteger):
+		// no value is of both types, so nothing is excluded.
+		return nil
+	default:
+		c.warn(source, "not can only exclude values of the type of the field, excluding %q is ignored", not.Type)
+		return nil
+	}
+	cs := constraintsOf(not.MetaSwaggerProperty)
+	if cs != nil {
+		// required is about the property, not its values.
+		cs.required = false
+	}
+	if cs == nil || cs.empty() {
+		c.warn(source, "not excludes every value, which is ignored")
+		return nil
+	}
+	c.warn(source, "not can not be expressed with Go types, the field keeps its type and the exclusion is checked by Validate")
+	return cs
+}
+
+// withNot returns a copy of cs excluding the values that meet not.
+func withNot(cs, not *constraints) *constraints {
+	if not == nil {
+		return cs
+	}
+	result := constraints{}
+	if cs != nil {
+		result = *cs
+	}
+	result.not = not
+	return &result
+}
+
+// keyConstraints returns the constraints of the propertyNames of prop, which only apply to the
+// maps made of additionalProperties or patternProperties.
+func keyConstraints(c *config, source string, prop SwaggerProperty) *constraints {
+	names := prop.PropertyNames
+	if !isMapSchema(prop) {
+		c.warn(source, "propertyNames is only checked for additionalProperties and patternProperties, it is ignored")
+		return nil
+	}
+	if names.Ref != "" || len(names.AllOf)+len(names.AnyOf)+len(names.OneOf) > 0 || names.Not != nil {
+		c.warn(source, "propertyNames can only be checked for validation keywords, it is ignored")
+		return nil
+	}
+	cs := constraintsOf(names.MetaSwaggerProperty)
+	if cs != nil {
+		cs.required = false
+	}
+	if cs == nil || cs.empty() {
+		return nil
+	}
+	return cs
+}
+
+// patternKeys returns the key constraints of cs requiring the keys to match one of patterns too,
+// as patternProperties do when additionalProperties is false.
+func patternKeys(c *config, source string, cs *constraints, patterns []string) *constraints {
+	keys := constraints{}
+	if cs != nil && cs.keys != nil {
+		keys = *cs.keys
+	}
+	if keys.pattern != "" {
+		c.warn(source, "the keys already match the pattern of propertyNames, the ones of patternProperties are not checked")
+		return cs.keys
+	}
+	alternatives := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	keys.pattern = strings.Join(alternatives, "|")
+	return &keys
+}
+
+// withRequired returns a copy of cs for a required property.
+func withRequired(cs *constraints) *constraints {
+	result := constraints{}
+	if cs != nil {
+		result = *cs
+	}
+	result.required = true
+	return &result
+}
+
+// withKeys returns a copy of cs constraining the keys of maps with keys.
+func withKeys(cs, keys *constraints) *constraints {
+	if keys == nil {
+		return cs
+	}
+	result := constraints{}
+	if cs != nil {
+		result = *cs
+	}
+	result.keys = keys
+	return &result
+}
+
+// withoutEnum returns a copy of cs without the enum values, of the field or its items.
+func withoutEnum(cs *constraints) *constraints {
+	if cs == nil {
+		return nil
+	}
+	result := *cs
+	result.enum = nil
+	if cs.items != nil {
+		result.items = withoutEnum(cs.items)
+	}
+	if result.empty() {
+		return nil
+	}
+	return &result
+}
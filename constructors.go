@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// writeConstructor writes, with --constructors, a NewType function for the structs with fields
+// that have a default. It takes the required fields as parameters, in their order, and fills the
+// ones with a default with it. Defaults that can not be written as a Go literal of the type of
+// their field are warned about and left out, as are the functions whose name is taken by one of
+// the types.
+func writeConstructor(w io.Writer, c *config, t *Type, structName string, taken map[string]bool) {
+	if !c.constructors || len(t.Enum) > 0 || t.union != nil || t.tuple {
+		return
+	}
+	var params, locals, values []string
+	defaults := 0
+	for _, fld := range t.Fields {
+		f := fld.Type
+		if fld.Name == "" || f.IsMultiple() {
+			continue
+		}
+		fn := fld.GoName()
+		tn, _ := fieldType(c, structName, fn, &f)
+		if tn == structName {
+			tn = "*" + tn
+		}
+		if !f.optional {
+			name := paramName(fn)
+			params = append(params, name+" "+tn)
+			values = append(values, fn+": "+name)
+			continue
+		}
+		if len(f.defaultValue) == 0 {
+			continue
+		}
+		literal, ok := defaultLiteral(strings.TrimPrefix(tn, "*"), &f, f.defaultValue)
+		if !ok {
+			c.warn(structName+"."+fn, "the default %s can not be written as a %s, it is left out of New%s", f.defaultValue, tn, structName)
+			continue
+		}
+		defaults++
+		if !strings.HasPrefix(tn, "*") {
+			values = append(values, fn+": "+literal)
+			continue
+		}
+		// pointers need a variable to point to.
+		name := paramName(fn)
+		if base := tn[1:]; base != "string" && base != "bool" && !strings.HasSuffix(literal, "}") {
+			literal = tn[1:] + "(" + literal + ")"
+		}
+		locals = append(locals, name+" := "+literal)
+		values = append(values, fn+": &"+name)
+	}
+	if defaults == 0 {
+		return
+	}
+	if taken["New"+structName] {
+		c.warn(t.Name, "the type New%s takes the name of its constructor, it is not written", structName)
+		return
+	}
+	fmt.Fprintf(w, "// New%s returns a %s holding the defaults of the spec and the required fields given.\n", structName, structName)
+	fmt.Fprintf(w, "func New%s(%s) %s {\n", structName, strings.Join(params, ", "), structName)
+	for _, l := range locals {
+		fmt.Fprintf(w, "\t%s\n", l)
+	}
+	fmt.Fprintf(w, "\treturn %s{\n", structName)
+	for _, v := range values {
+		fmt.Fprintf(w, "\t\t%s,\n", v)
+	}
+	fmt.Fprint(w, "\t}\n}\n\n")
+}
+
+// paramName returns the name of the parameter, or variable, holding the field fn, its leading
+// initialism or letter lowered. Keywords get Value appended.
+func paramName(fn string) string {
+	runes := []rune(fn)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// the last upper of an initialism followed by lowers starts the next word, as in IDToken.
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	if upper == 0 {
+		upper = 1
+	}
+	name := strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+	if token.IsKeyword(name) {
+		name += "Value"
+	}
+	return name
+}
+
+// defaultLiteral returns the Go literal of the default value v of a field of type tn, false when
+// there is none, as for structs.
+func defaultLiteral(tn string, f *maybeType, v json.RawMessage) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(v))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil || value == nil {
+		return "", false
+	}
+	switch valueKind(tn, f) {
+	case kindString:
+		s, ok := value.(string)
+		return strconv.Quote(s), ok
+	case kindInt:
+		n, ok := value.(json.Number)
+		if !ok {
+			return "", false
+		}
+		if _, err := n.Int64(); err != nil {
+			return "", false
+		}
+		return n.String(), true
+	case kindFloat:
+		n, ok := value.(json.Number)
+		return string(n), ok
+	case kindBool:
+		b, ok := value.(bool)
+		return strconv.FormatBool(b), ok
+	case kindArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return "", false
+		}
+		elem := *f
+		elem.isArray = false
+		literals := make([]string, 0, len(items))
+		for _, item := range items {
+			b, _ := json.Marshal(item)
+			literal, ok := defaultLiteral(tn[len("[]"):], &elem, b)
+			if !ok {
+				return "", false
+			}
+			literals = append(literals, literal)
+		}
+		return tn + "{" + strings.Join(literals, ", ") + "}", true
+	case kindMap:
+		vt := tn[len("map[string]"):]
+		elem := maybeType{}
+		var literals []string
+		err := decodeObjectInOrder(v, func(key string, value []byte) error {
+			literal, ok := defaultLiteral(vt, &elem, value)
+			if !ok {
+				return fmt.Errorf("no literal for %s", key)
+			}
+			literals = append(literals, strconv.Quote(key)+": "+literal)
+			return nil
+		})
+		if err != nil {
+			return "", false
+		}
+		return tn + "{" + strings.Join(literals, ", ") + "}", true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// applyContent turns base64 strings into []byte and, for JSON content with a contentSchema,
+// records the type it decodes into so decode helpers are generated for the field.
+func applyContent(c *config, source string, prop SwaggerProperty, f *maybeType) {
+	switch strings.ToLower(prop.ContentEncoding) {
+	case "":
+	case "base64":
+		// encoding/json reads and writes []byte as standard base64.
+		f.typeOf = bytesType
+		f.base64 = true
+	default:
+		c.warn(source, "contentEncoding %q is not supported, the field is kept as a string", prop.ContentEncoding)
+	}
+	if !isJSONMedia(prop.ContentMediaType) {
+		return
+	}
+	if prop.ContentSchema == nil || prop.ContentSchema.Ref == "" {
+		c.debugf("no contentSchema $ref for %s, no decode helpers generated\n", source)
+		return
+	}
+	f.contentType = typeFromRef(prop.ContentSchema.Ref)
+}
+
+// isJSONMedia returns true for application/json and the media types with the +json suffix.
+func isJSONMedia(mediaType string) bool {
+	if mediaType == "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// isXMLMedia returns true for application/xml, text/xml and the media types with the +xml suffix.
+func isXMLMedia(mediaType string) bool {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml")
+}
+
+// hasContentHelpers returns true if any field of t gets decode helpers.
+func hasContentHelpers(t *Type) bool {
+	for _, fld := range t.Fields {
+		if fld.Name != "" && fld.Type.contentType != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeContentHelpers writes a Decode and an Encode method for every field of t holding JSON with
+// a known schema, they convert between the encoded field and the type of its content.
+func writeContentHelpers(w io.Writer, c *config, t *Type, structName string) {
+	if !hasContentHelpers(t) {
+		return
+	}
+	taken := map[string]bool{}
+	for _, fld := range t.Fields {
+		if fld.Name != "" {
+			taken[fld.GoName()] = true
+		}
+	}
+	for _, fld := range t.Fields {
+		if fld.Name == "" || fld.Type.contentType == "" {
+			continue
+		}
+		fn := fld.GoName()
+		tn, _ := fieldType(c, structName, fn, &fld.Type)
+		content := capitalize(fld.Type.contentType)
+		if taken["Decode"+fn] || taken["Encode"+fn] {
+			c.warn(structName+"."+fn, "a field is named Decode%s or Encode%s so no decode helpers can be generated", fn, fn)
+			continue
+		}
+		// nullable fields can be pointers, a nil one decodes into the zero value.
+		pointer := strings.HasPrefix(tn, "*")
+		data, set := "t."+fn, "b"
+		switch strings.TrimPrefix(tn, "*") {
+		case "[]byte", base64Type:
+		case "string":
+			data, set = "[]byte(t."+fn+")", "string(b)"
+		default:
+			c.warn(structName+"."+fn, "no decode helpers can be generated for %s", tn)
+			continue
+		}
+		if pointer {
+			data = strings.Replace(data, "t."+fn, "*t."+fn, 1)
+		}
+
+		fmt.Fprintf(w, "// Decode%s decodes the %s held in %s.\n", fn, content, fn)
+		fmt.Fprintf(w, "func (t %s) Decode%s() (%s, error) {\n", structName, fn, content)
+		fmt.Fprintf(w, "\tvar v %s\n", content)
+		if pointer {
+			fmt.Fprintf(w, "\tif t.%s == nil {\n\t\treturn v, nil\n\t}\n", fn)
+		}
+		fmt.Fprintf(w, "\terr := json.Unmarshal(%s, &v)\n", data)
+		fmt.Fprint(w, "\treturn v, err\n}\n\n")
+
+		fmt.Fprintf(w, "// Encode%s sets %s to the encoding of v.\n", fn, fn)
+		fmt.Fprintf(w, "func (t *%s) Encode%s(v %s) error {\n", structName, fn, content)
+		fmt.Fprint(w, "\tb, err := json.Marshal(v)\n\tif err != nil {\n\t\treturn err\n\t}\n")
+		if pointer {
+			fmt.Fprintf(w, "\tdata := %s\n\tt.%s = &data\n", set, fn)
+		} else {
+			fmt.Fprintf(w, "\tt.%s = %s\n", fn, set)
+		}
+		fmt.Fprint(w, "\treturn nil\n}\n\n")
+	}
+}
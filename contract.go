@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// contractFile returns the name of the file the contract tests of target are written to, next to
+// it.
+func contractFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_contract_test.go"
+}
+
+// safeMethods are the methods the contract tests send without LAC_CONTRACT_UNSAFE, the ones that
+// change nothing on the server.
+var safeMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// writeContractTests writes, with --contract-tests, a test calling each operation of the Client
+// on the server at the URL in LAC_CONTRACT_URL and checking it answers as the spec says. The
+// parameters hold their examples, or their defaults, and the request bodies the fixture of their
+// type with --fixtures, the rest is left to its zero value. The operations with a path parameter
+// without either are skipped, as there is no telling what to request.
+func writeContractTests(c *config, types []*Type, ops []apiOperation) error {
+	if len(ops) == 0 {
+		c.warn(c.swaggerFile, "the client has no operations, no contract tests are written")
+		return nil
+	}
+	fixtures := map[string]bool{}
+	if c.fixtures {
+		for _, t := range types {
+			for _, example := range t.examples {
+				if _, err := exampleLiteral(example); err == nil {
+					fixtures[capitalize(t.Name)] = true
+					break
+				}
+			}
+		}
+	}
+	return writeFile(c, contractFile(c.targetFile), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		writeHeading(w, c, []string{"context", "encoding/json", "errors", "net/http", "os", "reflect", "strconv", "strings", "testing"})
+		w.WriteString(`// TestContract calls each operation of the spec on the server at the URL in LAC_CONTRACT_URL,
+// checking it answers with a status the spec declares and a body that decodes into its type and
+// passes its Validate method. Operations other than GET, HEAD and OPTIONS are only called with
+// LAC_CONTRACT_UNSAFE set, as they may change what the server holds.
+func TestContract(t *testing.T) {
+	base := os.Getenv("LAC_CONTRACT_URL")
+	if base == "" {
+		t.Skip("LAC_CONTRACT_URL is not set")
+	}
+`)
+		for _, op := range ops {
+			if !safeMethods[op.method] {
+				w.WriteString("\tall := os.Getenv(\"LAC_CONTRACT_UNSAFE\") != \"\"\n")
+				break
+			}
+		}
+		w.WriteString(`	// status is the one of the last response, the operations run one at a time.
+	status := new(int)
+	c := NewClient(base, WithResponseInterceptor(func(resp *http.Response) error {
+		*status = resp.StatusCode
+		return nil
+	}))
+	ctx := context.Background()
+`)
+		for _, op := range ops {
+			writeContractTest(w, op, fixtures)
+		}
+		w.WriteString("}\n")
+		writeContractHelpers(w)
+		return w.Flush()
+	})
+}
+
+// writeContractTest writes the subtest of op, fixtures are the types with a fixture function.
+func writeContractTest(w *bufio.Writer, op apiOperation, fixtures map[string]bool) {
+	fmt.Fprintf(w, "\tt.Run(%q, func(t *testing.T) {\n", op.name)
+	if !safeMethods[op.method] {
+		fmt.Fprintf(w, "\t\tif !all {\n\t\t\tt.Skip(\"%s is only sent with LAC_CONTRACT_UNSAFE set\")\n\t\t}\n", op.method)
+	}
+	args := []string{"ctx"}
+	literals := make([]string, 0, len(op.pathParams))
+	for _, p := range op.pathParams {
+		literal, err := exampleLiteral(p.example)
+		if len(p.example) == 0 || err != nil {
+			fmt.Fprintf(w, "\t\tt.Skip(\"the path parameter %s has no example or default to request\")\n\t})\n", p.name)
+			return
+		}
+		literals = append(literals, literal)
+	}
+	for i, p := range op.pathParams {
+		fmt.Fprintf(w, "\t\tvar %s %s\n", p.goName, p.goType)
+		fmt.Fprintf(w, "\t\tlacContractValue(t, %s, &%s)\n", literals[i], p.goName)
+		args = append(args, p.goName)
+	}
+	if len(op.params) > 0 {
+		fmt.Fprintf(w, "\t\tvar params %sParams\n", op.name)
+		for _, p := range op.params {
+			if literal, err := exampleLiteral(p.example); err == nil {
+				fmt.Fprintf(w, "\t\tlacContractValue(t, %s, &params.%s)\n", literal, p.goName)
+			}
+		}
+		args = append(args, "params")
+	}
+	if op.body != "" {
+		if fixtures[op.body] {
+			fmt.Fprintf(w, "\t\tbody := %sFixture()\n", op.body)
+		} else {
+			fmt.Fprintf(w, "\t\tvar body %s\n", op.body)
+		}
+		args = append(args, "body")
+	}
+	call := fmt.Sprintf("c.%s(%s)", op.name, strings.Join(args, ", "))
+	result := "nil"
+	if op.result != "" {
+		fmt.Fprintf(w, "\t\tresult, err := %s\n", call)
+		result = "result"
+	} else {
+		fmt.Fprintf(w, "\t\terr := %s\n", call)
+	}
+	declared := ""
+	for _, status := range op.statuses {
+		declared += ", " + strconv.Quote(status)
+	}
+	fmt.Fprintf(w, "\t\tlacContractCheck(t, *status, %s, err%s)\n\t})\n", result, declared)
+}
+
+// writeContractHelpers writes the helpers of the contract tests.
+func writeContractHelpers(w *bufio.Writer) {
+	w.WriteString(`
+// lacContractValue decodes the example of a parameter into v, examples that do not fit its type
+// are a mistake of the spec.
+func lacContractValue(t *testing.T, example string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(example), v); err != nil {
+		t.Fatalf("the example %s does not fit the parameter: %v", example, err)
+	}
+}
+
+// lacContractCheck fails t when an operation answered with a status its spec does not declare,
+// with a body that does not decode into its type or breaks its Validate method, or not at all.
+// status is the one it answered with and declared the ones of the responses of the spec.
+func lacContractCheck(t *testing.T, status int, result interface{}, err error, declared ...string) {
+	t.Helper()
+	var re lacResponseError
+	var ce *ClientError
+	switch {
+	case err != nil && !errors.As(err, &re) && !errors.As(err, &ce):
+		t.Error(err)
+	case !lacContractDeclared(status, declared):
+		t.Errorf("answered with %d, which the spec does not declare", status)
+	case err == nil:
+		lacContractValidate(t, result)
+	case re != nil:
+		t.Logf("answered with a declared error: %v", err)
+		lacContractValidate(t, reflect.Indirect(reflect.ValueOf(re)).FieldByName("Body").Interface())
+	default:
+		t.Logf("answered with a declared status: %v", err)
+	}
+}
+
+// lacContractDeclared says whether status is one of declared, a code, a range such as 4XX or
+// default.
+func lacContractDeclared(status int, declared []string) bool {
+	for _, d := range declared {
+		switch {
+		case d == "default", d == strconv.Itoa(status):
+			return true
+		case strings.HasSuffix(strings.ToUpper(d), "XX") && d[:1] == strconv.Itoa(status/100):
+			return true
+		}
+	}
+	return false
+}
+
+// lacContractValidate fails t when v, or any of its items, breaks its Validate method.
+func lacContractValidate(t *testing.T, v interface{}) {
+	t.Helper()
+	if validator, ok := v.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			t.Error(err)
+		}
+		return
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			lacContractValidate(t, rv.Index(i).Interface())
+		}
+	}
+}
+`)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// decodeGeneric writes a single Decode[T any], which needs Go 1.18.
+	decodeGeneric = "generic"
+	// decodeTypes writes a DecodeX for each type.
+	decodeTypes = "types"
+)
+
+// decodeFile returns the name of the file the decode helpers of target are written to, next to
+// it.
+func decodeFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_decode.go"
+}
+
+// writeDecode writes, with --decode, the functions decoding the types of the package from a
+// reader next to the target, so every consumer decodes them with the same settings: UseNumber
+// with --use-number and DisallowUnknownFields with --strict-decode.
+func writeDecode(c *config, types []*Type) error {
+	names := make(map[string]bool, len(types))
+	for _, t := range types {
+		names[capitalize(t.Name)] = true
+	}
+	var decoded []*Type
+	if c.decode == decodeTypes {
+		for _, t := range types {
+			// enums are decoded as part of the structs holding them.
+			if len(t.Enum) > 0 {
+				continue
+			}
+			if names["Decode"+capitalize(t.Name)] {
+				c.warn(t.Name, "the type Decode%s is taken, no function decodes %s", capitalize(t.Name), capitalize(t.Name))
+				continue
+			}
+			decoded = append(decoded, t)
+		}
+	} else if names["Decode"] {
+		return fmt.Errorf("the type Decode is taken, use --decode=%s", decodeTypes)
+	}
+	return writeFile(c, decodeFile(c.targetFile), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		writeHeading(w, c, []string{"encoding/json", "io"})
+		if c.decode == decodeGeneric {
+			w.WriteString("// Decode reads a T from the JSON document in r, all the types of the package are meant to be\n")
+			w.WriteString("// decoded by it so they are decoded alike.\n")
+			w.WriteString("func Decode[T any](r io.Reader) (T, error) {\n")
+			w.WriteString("\tvar v T\n")
+			writeDecoder(w, c)
+			w.WriteString("\terr := dec.Decode(&v)\n\treturn v, err\n}\n")
+			return w.Flush()
+		}
+		for _, t := range decoded {
+			structName := capitalize(t.Name)
+			doc := fmt.Sprintf("Decode%s decodes %s from the JSON document in r, as all the types of the package are decoded.", structName, structName)
+			for _, l := range wrapLine(doc, 97) {
+				fmt.Fprintf(w, "// %s\n", l)
+			}
+			fmt.Fprintf(w, "func Decode%s(r io.Reader) (%s, error) {\n", structName, structName)
+			fmt.Fprintf(w, "\tvar v %s\n", structName)
+			w.WriteString("\terr := lacDecode(r, &v)\n\treturn v, err\n}\n\n")
+		}
+		w.WriteString("// lacDecode reads v from the JSON document in r, the types of the package are all decoded by it\n")
+		w.WriteString("// so they are decoded alike.\n")
+		w.WriteString("func lacDecode(r io.Reader, v interface{}) error {\n")
+		writeDecoder(w, c)
+		w.WriteString("\treturn dec.Decode(v)\n}\n")
+		return w.Flush()
+	})
+}
+
+// writeDecoder writes the declaration of dec, the decoder of r with the settings of the flags.
+func writeDecoder(w *bufio.Writer, c *config) {
+	w.WriteString("\tdec := json.NewDecoder(r)\n")
+	if c.useNumber {
+		w.WriteString("\t// numbers held by interface{} values are kept as json.Number, not float64.\n")
+		w.WriteString("\tdec.UseNumber()\n")
+	}
+	if c.strictDecode {
+		w.WriteString("\t// keys the types do not know are an error.\n")
+		w.WriteString("\tdec.DisallowUnknownFields()\n")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readDescriptions reads a yaml file mapping type names, as found in the source or once turned
+// into Go, to the description of the type.
+func readDescriptions(c *config, p string) (map[string]string, error) {
+	b, err := readFile(c.files(), p)
+	if err != nil {
+		return nil, fmt.Errorf("reading descriptions: %w", err)
+	}
+	descriptions := map[string]string{}
+	if err := yaml.Unmarshal(b, &descriptions); err != nil {
+		return nil, fmt.Errorf("parsing descriptions %s: %w", p, err)
+	}
+	return descriptions, nil
+}
+
+// applyDescriptions gives types the descriptions from --descriptions, they replace the ones of
+// the source so samples, which have none, get the same comments specs do.
+func applyDescriptions(c *config, types []*Type) {
+	if len(c.descriptions) == 0 {
+		return
+	}
+	used := make(map[string]bool, len(c.descriptions))
+	for _, t := range types {
+		for name, d := range c.descriptions {
+			if name == t.Name || capitalize(name) == capitalize(t.Name) {
+				t.Description = d
+				used[name] = true
+			}
+		}
+	}
+	unused := make([]string, 0, len(c.descriptions))
+	for name := range c.descriptions {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		c.warn(name, "there is a description for the type but no type by that name")
+	}
+}
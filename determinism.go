@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// assertDeterministic generates the output runs times and returns an error pointing at the first
+// line that differs between runs. Warnings, progress and the report are left to the run that
+// writes the output.
+func assertDeterministic(ctx context.Context, c *config, runs int) error {
+	verbose, report, handler := c.verbose, c.report, c.warningHandler
+	c.verbose, c.report, c.warningHandler = false, nil, func(warning) {}
+	defer func() { c.verbose, c.report, c.warningHandler = verbose, report, handler }()
+
+	var first []byte
+	for run := 1; run <= runs; run++ {
+		types, err := readTypes(ctx, c)
+		if err != nil {
+			return fmt.Errorf("determinism run %d: %w", run, err)
+		}
+		buf := &bytes.Buffer{}
+		if err := makeMeCode(ctx, c, types, buf); err != nil {
+			return fmt.Errorf("determinism run %d: generating code: %w", run, err)
+		}
+		if run == 1 {
+			first = buf.Bytes()
+			continue
+		}
+		if line, a, b, differ := firstDifference(first, buf.Bytes()); differ {
+			return fmt.Errorf("the output is not deterministic, run %d differs from the first at line %d: %q != %q", run, line, a, b)
+		}
+	}
+	return nil
+}
+
+// firstDifference returns the number and contents of the first line that differs between a and b.
+func firstDifference(a, b []byte) (int, string, string, bool) {
+	al, bl := bytes.Split(a, []byte("\n")), bytes.Split(b, []byte("\n"))
+	for i := 0; i < len(al) || i < len(bl); i++ {
+		var x, y []byte
+		if i < len(al) {
+			x = al[i]
+		}
+		if i < len(bl) {
+			y = bl[i]
+		}
+		if i >= len(al) || i >= len(bl) || !bytes.Equal(x, y) {
+			return i + 1, string(x), string(y), true
+		}
+	}
+	return 0, "", "", false
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"regexp"
+)
+
+// durationImports are the packages needed by durationHelpers.
+var durationImports = []string{"encoding/json", "fmt", "regexp", "strconv", "time"}
+
+// durationHelpers is written once per file when a field holds a duration, encoding/json writes
+// time.Duration as nanoseconds while APIs send strings. The MarshalJSON of durationMarshalers for
+// the run is written after it.
+const durationHelpers = `// Duration is a time.Duration read from and written as a string, it reads both Go durations, ie
+// 1h15m, and ISO 8601 ones made of days and time, ie P1DT2H.
+type Duration time.Duration
+
+// lacISODuration matches the ISO 8601 durations Duration reads, years and months have no fixed
+// length so they are not.
+var lacISODuration = regexp.MustCompile(` + "`" + `^(-)?P(?:([0-9.]+)W)?(?:([0-9.]+)D)?(?:T(?:([0-9.]+)H)?(?:([0-9.]+)M)?(?:([0-9.]+)S)?)?$` + "`" + `)
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if m := lacISODuration.FindStringSubmatch(s); m != nil {
+		units := []time.Duration{0, 0, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+		var total float64
+		found := false
+		for i := 2; i < len(m); i++ {
+			if m[i] == "" {
+				continue
+			}
+			n, err := strconv.ParseFloat(m[i], 64)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			total += n * float64(units[i])
+			found = true
+		}
+		if m[1] != "" {
+			total = -total
+		}
+		if found {
+			*d = Duration(total)
+			return nil
+		}
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+var (
+	_ json.Marshaler   = Duration(0)
+	_ json.Unmarshaler = (*Duration)(nil)
+)
+
+`
+
+// Forms durations are written in, see durationForm.
+const (
+	durationGo      = "go"
+	durationISO     = "iso"
+	durationSeconds = "seconds"
+)
+
+// durationMarshalers are the MarshalJSON of Duration by the form it writes.
+var durationMarshalers = map[string]string{
+	durationGo: `// MarshalJSON implements json.Marshaler, writing a Go duration.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+`,
+	durationSeconds: `// MarshalJSON implements json.Marshaler, writing the seconds with an s suffix, ie 1.5s.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(time.Duration(d).Seconds(), 'f', -1, 64) + "s")
+}
+
+`,
+	durationISO: `// MarshalJSON implements json.Marshaler, writing an ISO 8601 duration made of days and time,
+// ie P1DT2H.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	v := time.Duration(d)
+	if v == 0 {
+		return json.Marshal("PT0S")
+	}
+	s := "P"
+	if v < 0 {
+		s, v = "-P", -v
+	}
+	if days := v / (24 * time.Hour); days > 0 {
+		s += strconv.FormatInt(int64(days), 10) + "D"
+		v -= days * 24 * time.Hour
+	}
+	if v > 0 {
+		s += "T"
+	}
+	if hours := v / time.Hour; hours > 0 {
+		s += strconv.FormatInt(int64(hours), 10) + "H"
+		v -= hours * time.Hour
+	}
+	if minutes := v / time.Minute; minutes > 0 {
+		s += strconv.FormatInt(int64(minutes), 10) + "M"
+		v -= minutes * time.Minute
+	}
+	if v > 0 {
+		s += strconv.FormatFloat(v.Seconds(), 'f', -1, 64) + "S"
+	}
+	return json.Marshal(s)
+}
+
+`,
+}
+
+// durationForm returns the form the durations of the run are written in, the one they are read
+// in: format duration is ISO 8601 in schemas, the JSON of protobuf writes seconds and samples
+// hold Go durations.
+func durationForm(c *config) string {
+	switch {
+	case c.proto:
+		return durationSeconds
+	case c.swaggerFile != "":
+		return durationISO
+	}
+	return durationGo
+}
+
+// durationType is the name of the type written by durationHelpers.
+const durationType = "Duration"
+
+// goDuration matches the strings samples use for durations, ie 30s or 1h15m.
+var goDuration = regexp.MustCompile(`^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`)
+
+// isSampleDuration returns true for the values of samples that look like durations.
+func isSampleDuration(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && goDuration.MatchString(s)
+}
+
+// isSchemaDuration returns true for strings with format duration.
+func isSchemaDuration(t SwaggerType, format string) bool {
+	return t == STString && format == "duration"
+}
+
+// markDurations keeps durations as strings when a type takes the name of the helper.
+func markDurations(c *config, types []*Type) {
+	if !c.durations {
+		return
+	}
+	for _, t := range types {
+		if capitalize(t.Name) != durationType {
+			continue
+		}
+		c.warn(t.Name, "the type is named %s, durations are kept as strings", durationType)
+		for _, t := range types {
+			for i := range t.Fields {
+				t.Fields[i].Type.duration = false
+			}
+		}
+		return
+	}
+}
+
+// needsDurations returns true if any field of types is a duration.
+func needsDurations(c *config, types []*Type) bool {
+	if !c.durations {
+		return false
+	}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			if fld.Name != "" && fld.Type.duration {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// byteOrderMarks as written by Windows tools at the start of text files.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// textEncoding returns the encoding of a document starting with head, UTF-16 documents without
+// a byte order mark are told apart by the zero bytes of their first, ASCII, character.
+func textEncoding(head []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return "utf-8", len(bomUTF8)
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return "utf-16le", len(bomUTF16LE)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return "utf-16be", len(bomUTF16BE)
+	case len(head) >= 2 && head[0] != 0 && head[1] == 0:
+		return "utf-16le", 0
+	case len(head) >= 2 && head[0] == 0 && head[1] != 0:
+		return "utf-16be", 0
+	}
+	return "utf-8", 0
+}
+
+// toUTF8 returns b as UTF-8 without byte order mark, transcoding it from UTF-16.
+func toUTF8(b []byte) ([]byte, error) {
+	enc, skip := textEncoding(b)
+	b = b[skip:]
+	if enc == "utf-8" {
+		return b, nil
+	}
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("the document looks %s but has an odd number of bytes", enc)
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if enc == "utf-16le" {
+			units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+		} else {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+	}
+	runes := utf16.Decode(units)
+	out := make([]byte, 0, len(runes))
+	buf := make([]byte, utf8.UTFMax)
+	for _, r := range runes {
+		n := utf8.EncodeRune(buf, r)
+		out = append(out, buf[:n]...)
+	}
+	return out, nil
+}
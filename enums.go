@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// stringEnum returns the values of a schema enum if all of them are strings, only those can be
+// turned into Go enum types.
+func stringEnum(values []interface{}) ([]string, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
+
+// enumConstName returns the name of the constant for an enum value, values can contain pretty
+// much anything so whatever is not valid in an identifier is treated as a word separator.
+func enumConstName(typeName, value string, i int) string {
+	clean := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, value)
+	n := capitalize(clean)
+	if n == "" {
+		n = fmt.Sprintf("Value%d", i)
+	}
+	return typeName + n
+}
+
+// enumValueName returns the name of the constant for the i-th value of an enum, the one the source
+// gave it when it did.
+func enumValueName(t *Type, typeName string, i int) string {
+	if i < len(t.enumNames) && t.enumNames[i] != "" {
+		return typeName + t.enumNames[i]
+	}
+	return enumConstName(typeName, t.Enum[i], i)
+}
+
+// writeEnum writes a string based enum type, its constants and, for gqlgen, the methods that
+// make it a valid autobind model.
+func writeEnum(w io.Writer, c *config, t *Type, typeName string) {
+	fmt.Fprintf(w, "type %s string\n\n", typeName)
+	names := make([]string, 0, len(t.Enum))
+	seen := map[string]bool{}
+	fmt.Fprint(w, "const (\n")
+	// an enum listing "" has it as its zero value already, an Unknown constant would be the same.
+	if c.enumUnknown != "" && enumHasEmpty(t) {
+		c.warn(t.Source, "%s lists \"\" as a value, it is the zero value so no %s is written", typeName, enumUnknownName(t, typeName))
+	} else if c.enumUnknown != "" {
+		unknown := enumUnknownName(t, typeName)
+		seen[unknown] = true
+		fmt.Fprintf(w, "\t// %s is the zero value, it is not a value of the source.\n", unknown)
+		fmt.Fprintf(w, "\t%s %s = \"\"\n", unknown, typeName)
+	}
+	for i, v := range t.Enum {
+		n := enumValueName(t, typeName, i)
+		if seen[n] {
+			c.report.collision(Collision{
+				Kind:       "enum",
+				GoName:     n,
+				Names:      []string{v},
+				Resolution: fmt.Sprintf("%s took the name so %q is %s%d", n, v, n, i),
+			})
+			n = fmt.Sprintf("%s%d", n, i)
+		}
+		seen[n] = true
+		names = append(names, n)
+		fmt.Fprintf(w, "\t%s %s = %q\n", n, typeName, v)
+	}
+	fmt.Fprint(w, ")\n\n")
+	writeEnumUnmarshal(w, c, t, typeName, names)
+	if !c.gqlgen {
+		return
+	}
+	fmt.Fprintf(w, "// All%s holds all the valid values of %s.\n", typeName, typeName)
+	fmt.Fprintf(w, "var All%s = []%s{\n", typeName, typeName)
+	for _, n := range names {
+		fmt.Fprintf(w, "\t%s,\n", n)
+	}
+	fmt.Fprint(w, "}\n\n")
+	fmt.Fprintf(w, "// IsValid returns true if e is one of the known values of %s.\n", typeName)
+	fmt.Fprintf(w, "func (e %s) IsValid() bool {\n\tswitch e {\n\tcase %s:\n\t\treturn true\n\t}\n\treturn false\n}\n\n", typeName, strings.Join(names, ", "))
+	fmt.Fprintf(w, "func (e %s) String() string {\n\treturn string(e)\n}\n\n", typeName)
+	fmt.Fprintf(w, "// UnmarshalGQL implements graphql.Unmarshaler.\n")
+	fmt.Fprintf(w, "func (e *%s) UnmarshalGQL(v interface{}) error {\n", typeName)
+	fmt.Fprint(w, "\tstr, ok := v.(string)\n\tif !ok {\n\t\treturn fmt.Errorf(\"enums must be strings\")\n\t}\n\n")
+	fmt.Fprintf(w, "\t*e = %s(str)\n\tif !e.IsValid() {\n\t\treturn fmt.Errorf(\"%%s is not a valid %s\", str)\n\t}\n\treturn nil\n}\n\n", typeName, typeName)
+	fmt.Fprintf(w, "// MarshalGQL implements graphql.Marshaler.\n")
+	fmt.Fprintf(w, "func (e %s) MarshalGQL(w io.Writer) {\n\tfmt.Fprint(w, strconv.Quote(e.String()))\n}\n\n", typeName)
+}
+
+// enumUnknownName returns the name of the Unknown zero value of an enum, unless one of its values
+// takes it.
+func enumUnknownName(t *Type, typeName string) string {
+	taken := map[string]bool{}
+	for i := range t.Enum {
+		taken[enumValueName(t, typeName, i)] = true
+	}
+	n := typeName + "Unknown"
+	for taken[n] {
+		n += "_"
+	}
+	return n
+}
+
+// enumHasEmpty returns true if "" is one of the values of an enum.
+func enumHasEmpty(t *Type) bool {
+	for _, v := range t.Enum {
+		if v == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// enumZeroName returns the name of the constant of the zero value of an enum, the one of its ""
+// value when it lists one and its Unknown constant otherwise. names are the ones of the values.
+func enumZeroName(t *Type, typeName string, names []string) string {
+	for i, v := range t.Enum {
+		if v == "" {
+			return names[i]
+		}
+	}
+	return enumUnknownName(t, typeName)
+}
+
+// writeEnumUnmarshal writes the UnmarshalJSON deciding what happens with the values that are not
+// known, as asked with --enum-unknown. Passing them through is what a string does already.
+func writeEnumUnmarshal(w io.Writer, c *config, t *Type, typeName string, names []string) {
+	if c.enumUnknown != enumUnknownError && c.enumUnknown != enumUnknownUnknown {
+		return
+	}
+	if c.enumUnknown == enumUnknownError {
+		fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, values that are not known are an error.\n")
+	} else {
+		fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, values that are not known become %s.\n", enumZeroName(t, typeName, names))
+	}
+	fmt.Fprintf(w, "func (e *%s) UnmarshalJSON(b []byte) error {\n", typeName)
+	fmt.Fprint(w, "\tvar s string\n\tif err := json.Unmarshal(b, &s); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprintf(w, "\tswitch v := %s(s); v {\n", typeName)
+	fmt.Fprintf(w, "\tcase %s:\n\t\t*e = v\n", strings.Join(names, ", "))
+	if c.enumUnknown == enumUnknownError {
+		fmt.Fprintf(w, "\tdefault:\n\t\treturn fmt.Errorf(\"%%q is not a valid %s\", s)\n", typeName)
+	} else {
+		fmt.Fprintf(w, "\tdefault:\n\t\t*e = %s\n", enumZeroName(t, typeName, names))
+	}
+	fmt.Fprint(w, "\t}\n\treturn nil\n}\n\n")
+}
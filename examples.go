@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SwaggerExamples holds the examples of a schema. JSON Schema lists them in an array while
+// OpenAPI maps names to Example Objects holding them in value, which are kept in order.
+type SwaggerExamples []json.RawMessage
+
+// UnmarshalJSON implements json.Unmarshaler accepting both forms, examples only given by
+// externalValue are left out.
+func (se *SwaggerExamples) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		var examples []json.RawMessage
+		if err := json.Unmarshal(trimmed, &examples); err != nil {
+			return withinPart(trimmed, err)
+		}
+		*se = examples
+		return nil
+	}
+	return decodeObjectInOrder(trimmed, func(key string, value []byte) error {
+		var example struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(value, &example); err != nil {
+			return fmt.Errorf("decoding example %s: %w", key, err)
+		}
+		if len(example.Value) > 0 {
+			*se = append(*se, example.Value)
+		}
+		return nil
+	})
+}
+
+// declaredExamples returns example followed by examples.
+func declaredExamples(example json.RawMessage, examples SwaggerExamples) []json.RawMessage {
+	var result []json.RawMessage
+	if len(example) > 0 {
+		result = append(result, example)
+	}
+	return append(result, examples...)
+}
+
+// markExamples sets the examples of the types of the schemas, the ones they declare or, lacking
+// them, one made of the examples of their properties. It runs before the types are renamed.
+func markExamples(ctx context.Context, types []*Type, schemas SwaggerSchemas) error {
+	f := &exampleFinder{schemas: schemas, found: map[string][]json.RawMessage{}, inProgress: map[string]bool{}}
+	for _, t := range types {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("finding the examples of %s: %w", t.Name, err)
+		}
+		if _, ok := schemas.ByName[t.Name]; ok {
+			t.examples = f.schemaExamples(t.Name)
+		}
+	}
+	return nil
+}
+
+// maxMadeUpExample is the size past which the example made up for a schema is dropped, leaving
+// the schema without one.
+const maxMadeUpExample = 64 << 10
+
+// exampleFinder makes up the examples of the schemas, each of them once as the schemas
+// referencing one another would otherwise be walked again for each path reaching them.
+type exampleFinder struct {
+	schemas SwaggerSchemas
+	// found holds the examples of the schemas already made up, inProgress the ones being made up
+	// so recursive schemas end.
+	found      map[string][]json.RawMessage
+	inProgress map[string]bool
+}
+
+// schemaExamples returns the examples of the schema name.
+func (f *exampleFinder) schemaExamples(name string) []json.RawMessage {
+	if examples, ok := f.found[name]; ok {
+		return examples
+	}
+	s, ok := f.schemas.ByName[name]
+	if !ok || f.inProgress[name] {
+		return nil
+	}
+	if examples := declaredExamples(s.Example, s.Examples); len(examples) > 0 {
+		f.found[name] = examples
+		return examples
+	}
+	f.inProgress[name] = true
+	defer delete(f.inProgress, name)
+	var obj exampleObject
+	// the schemas of allOf are merged, the properties next to it go last.
+	for _, ref := range s.AllOf {
+		if examples := f.schemaExamples(typeFromRef(ref.Ref)); len(examples) > 0 {
+			obj.merge(examples[0])
+		}
+	}
+	for _, pName := range s.Properties.Names {
+		if example := f.propertyExample(s.Properties.ByName[pName]); example != nil {
+			obj.set(pName, example)
+		}
+	}
+	var examples []json.RawMessage
+	if len(obj.keys) > 0 {
+		// the examples of schemas nesting each other many times over would outgrow any fixture.
+		if example := obj.encode(); len(example) <= maxMadeUpExample {
+			examples = []json.RawMessage{example}
+		}
+	}
+	f.found[name] = examples
+	return examples
+}
+
+// propertyExample returns the first example of a property, made up like the ones of schemas
+// when it declares none. It is nil when there is none.
+func (f *exampleFinder) propertyExample(prop SwaggerProperty) json.RawMessage {
+	if examples := declaredExamples(prop.Example, prop.Examples); len(examples) > 0 {
+		return examples[0]
+	}
+	if prop.Ref != "" {
+		if examples := f.schemaExamples(typeFromRef(prop.Ref)); len(examples) > 0 {
+			return examples[0]
+		}
+		return nil
+	}
+	if prop.Type == STArray {
+		item := f.propertyExample(SwaggerProperty{MetaSwaggerProperty: prop.Items.MetaSwaggerProperty})
+		if item == nil {
+			return nil
+		}
+		return json.RawMessage("[" + string(item) + "]")
+	}
+	var obj exampleObject
+	for _, pName := range prop.Properties.Names {
+		if example := f.propertyExample(prop.Properties.ByName[pName]); example != nil {
+			obj.set(pName, example)
+		}
+	}
+	if len(obj.keys) == 0 {
+		return nil
+	}
+	return obj.encode()
+}
+
+// exampleObject is a JSON object made up of the examples of properties, in their order.
+type exampleObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *exampleObject) set(key string, value json.RawMessage) {
+	if o.values == nil {
+		o.values = map[string]json.RawMessage{}
+	}
+	if _, dup := o.values[key]; !dup {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// merge sets the keys of the object in value, which is ignored if it is not an object.
+func (o *exampleObject) merge(value json.RawMessage) {
+	_ = decodeObjectInOrder(value, func(key string, v []byte) error {
+		o.set(key, append(json.RawMessage(nil), v...))
+		return nil
+	})
+}
+
+func (o *exampleObject) encode() json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// fixturesFile returns the name of the file the fixtures of target are written to, next to it.
+func fixturesFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_fixtures.go"
+}
+
+// writeFixtures writes, next to the target, a function returning each type with examples filled
+// with the first one and, for the types with several, one returning them all. They are decoded
+// with encoding/json so the types read them as they would read a response.
+func writeFixtures(c *config, types []*Type) error {
+	var withExamples []*Type
+	for _, t := range types {
+		if len(t.examples) > 0 {
+			withExamples = append(withExamples, t)
+		}
+	}
+	if len(withExamples) == 0 {
+		c.warn(c.swaggerFile, "no schema has examples, no fixtures are written")
+		return nil
+	}
+	name := fixturesFile(c.targetFile)
+	return writeFile(c, name, func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		writeHeading(w, c, []string{"encoding/json", "fmt"})
+		for _, t := range withExamples {
+			structName := capitalize(t.Name)
+			literals := make([]string, 0, len(t.examples))
+			for _, example := range t.examples {
+				literal, err := exampleLiteral(example)
+				if err != nil {
+					c.warn(t.Name, "skipping an example that is not valid JSON: %v", err)
+					continue
+				}
+				literals = append(literals, literal)
+			}
+			if len(literals) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "// %sFixture returns a %s holding the example of the spec.\n", structName, structName)
+			fmt.Fprintf(w, "func %sFixture() %s {\n", structName, structName)
+			fmt.Fprintf(w, "\tvar v %s\n", structName)
+			fmt.Fprintf(w, "\tlacFixture(%s, &v)\n", literals[0])
+			fmt.Fprint(w, "\treturn v\n}\n\n")
+			if len(literals) < 2 {
+				continue
+			}
+			fmt.Fprintf(w, "// %sFixtures returns a %s for each of the examples of the spec.\n", structName, structName)
+			fmt.Fprintf(w, "func %sFixtures() []%s {\n", structName, structName)
+			fmt.Fprint(w, "\texamples := []string{\n")
+			for _, literal := range literals {
+				fmt.Fprintf(w, "\t\t%s,\n", literal)
+			}
+			fmt.Fprint(w, "\t}\n")
+			fmt.Fprintf(w, "\tresult := make([]%s, len(examples))\n", structName)
+			fmt.Fprint(w, "\tfor i, example := range examples {\n\t\tlacFixture(example, &result[i])\n\t}\n")
+			fmt.Fprint(w, "\treturn result\n}\n\n")
+		}
+		w.WriteString(`// lacFixture decodes an example of the spec into v, examples that do not fit the types are a
+// mistake of the spec.
+func lacFixture(example string, v interface{}) {
+	if err := json.Unmarshal([]byte(example), v); err != nil {
+		panic(fmt.Sprintf("example %s does not fit %T: %v", example, v, err))
+	}
+}
+`)
+		return w.Flush()
+	})
+}
+
+// exampleLiteral returns example compacted as a Go string literal, raw unless it holds a
+// backquote.
+func exampleLiteral(example json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, example); err != nil {
+		return "", err
+	}
+	if bytes.ContainsRune(buf.Bytes(), '`') {
+		return strconv.Quote(buf.String()), nil
+	}
+	return "`" + buf.String() + "`", nil
+}
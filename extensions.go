@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// SwaggerGoImport represents x-go-type-import, the package x-go-type needs. It is either an
+// object with the path and, optionally, the name to import it as or only the path.
+type SwaggerGoImport struct {
+	Path string `json:"path"`
+	Name string `json:"name,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler accepting the path alone too.
+func (gi *SwaggerGoImport) UnmarshalJSON(b []byte) error {
+	if trimmed := bytes.TrimSpace(b); bytes.HasPrefix(trimmed, []byte(`"`)) {
+		return withinPart(trimmed, json.Unmarshal(trimmed, &gi.Path))
+	}
+	type plain SwaggerGoImport
+	return withinPart(b, json.Unmarshal(b, (*plain)(gi)))
+}
+
+// spec returns the import as written in the heading, named ones quoted.
+func (gi *SwaggerGoImport) spec() string {
+	if gi == nil || gi.Path == "" {
+		return ""
+	}
+	if gi.Name != "" {
+		return gi.Name + " " + strconv.Quote(gi.Path)
+	}
+	return gi.Path
+}
+
+// goTypeOf returns the Go type x-go-type gives to a schema, along with the import it needs.
+// Without x-go-type-import a fully qualified type is split like the ones of --scalar-map, any
+// other type is written as is.
+func goTypeOf(m MetaSwaggerProperty) (string, string) {
+	if m.GoType == "" {
+		return "", ""
+	}
+	if imp := m.GoTypeImport.spec(); imp != "" {
+		return m.GoType, imp
+	}
+	if !strings.Contains(m.GoType, "/") {
+		return m.GoType, ""
+	}
+	return qualifiedType(m.GoType)
+}
+
+// goNames returns the x-go-name of the schemas, by schema name.
+func goNames(schemas SwaggerSchemas) map[string]string {
+	names := map[string]string{}
+	for _, name := range schemas.Names {
+		if goName := schemas.ByName[name].GoName; goName != "" {
+			names[name] = goName
+		}
+	}
+	return names
+}
+
+// renameTypes gives the types named after a key of names, and the ones made up for their
+// properties, the name it maps to. The fields and unions referencing them follow.
+func renameTypes(types []*Type, names map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+	rename := func(name string) string {
+		if to, ok := names[name]; ok {
+			return to
+		}
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			if to, ok := names[name[:i]]; ok {
+				return to + name[i:]
+			}
+		}
+		return name
+	}
+	// the values of maps are referenced by their Go name.
+	goNames := make(map[string]string, len(names))
+	for name, to := range names {
+		goNames[capitalize(name)] = capitalize(to)
+	}
+	for _, t := range types {
+		if to := rename(t.Name); to != t.Name {
+			goNames[capitalize(t.Name)] = capitalize(to)
+		}
+	}
+	for _, t := range types {
+		t.Name = rename(t.Name)
+		for i := range t.Fields {
+			f := &t.Fields[i].Type
+			if end := strings.LastIndexByte(f.nameOftype, ']'); end > 0 && strings.HasPrefix(f.nameOftype, "map[") {
+				if to, ok := goNames[f.nameOftype[end+1:]]; ok {
+					f.nameOftype = f.nameOftype[:end+1] + to
+				}
+			} else {
+				f.nameOftype = rename(f.nameOftype)
+			}
+			for j, mt := range f.multiType {
+				f.multiType[j] = rename(mt)
+			}
+			// refs renamed to the same type are one variant.
+			f.multiType = uniqueVariants(f.multiType)
+		}
+		if t.union != nil {
+			for i := range t.union.cases {
+				t.union.cases[i].typeName = rename(t.union.cases[i].typeName)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markExtra flags the types that get a field for the unknown keys. Types embedded for anyOf,
+// oneOf and allOf are left out as their UnmarshalJSON would be promoted to the types embedding
+// them and called on a nil pointer, the types embedding them do not know the keys they embed.
+func markExtra(c *config, types []*Type) {
+	if !c.extra {
+		return
+	}
+	embedded := map[string]bool{}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			for _, mt := range fld.Type.multiType {
+				embedded[capitalize(mt)] = true
+			}
+		}
+	}
+	for _, t := range types {
+		t.extra = len(t.Enum) == 0 && !t.tuple && !embedded[capitalize(t.Name)] &&
+			!(len(t.Fields) > 0 && t.Fields[0].Name == "")
+	}
+}
+
+// extraFieldName returns the name of the field holding the unknown keys, Extra unless the
+// source already has a field by that name.
+func extraFieldName(t *Type) string {
+	taken := make(map[string]bool, len(t.Fields))
+	for _, fld := range t.Fields {
+		if fld.Name != "" {
+			taken[fld.GoName()] = true
+		}
+	}
+	n := "Extra"
+	for i := 2; taken[n]; i++ {
+		n = fmt.Sprintf("Extra%d", i)
+	}
+	return n
+}
+
+// writeExtraField writes the field holding the unknown keys, it is skipped by every encoder.
+func writeExtraField(w io.Writer, c *config, t *Type) {
+	tags := []string{`json:"-"`}
+	for _, tag := range c.extraTags {
+		tags = append(tags, tag+`:"-"`)
+	}
+	if t.xml && !hasTag(c.extraTags, "xml") {
+		tags = append(tags, `xml:"-"`)
+	}
+	if c.dbMode != "" {
+		tags = append(tags, `db:"-"`)
+	}
+	n := extraFieldName(t)
+	fmt.Fprintf(w, "\t// %s holds the keys of the source that are not fields of this type.\n", n)
+	fmt.Fprintf(w, "\t%s map[string]json.RawMessage `%s`\n", n, strings.Join(tags, " "))
+}
+
+// writeUnmarshalExtra writes an UnmarshalJSON that decodes the known fields as usual and keeps
+// whatever else is in the object in the extra field.
+func writeUnmarshalExtra(w io.Writer, t *Type, structName string) {
+	n := extraFieldName(t)
+	known := make([]string, 0, len(t.Fields))
+	for _, fld := range t.Fields {
+		known = append(known, fmt.Sprintf("%q", fld.Name))
+	}
+	fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, keys that are not fields of %s are kept in %s.\n", structName, n)
+	fmt.Fprintf(w, "func (t *%s) UnmarshalJSON(b []byte) error {\n", structName)
+	fmt.Fprintf(w, "\ttype plain %s\n", structName)
+	fmt.Fprint(w, "\tif err := json.Unmarshal(b, (*plain)(t)); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprint(w, "\tvar all map[string]json.RawMessage\n")
+	fmt.Fprint(w, "\tif err := json.Unmarshal(b, &all); err != nil {\n\t\treturn err\n\t}\n")
+	if len(known) > 0 {
+		fmt.Fprintf(w, "\tfor _, k := range []string{%s} {\n\t\tdelete(all, k)\n\t}\n", strings.Join(known, ", "))
+	}
+	fmt.Fprintf(w, "\tt.%s = nil\n", n)
+	fmt.Fprintf(w, "\tif len(all) > 0 {\n\t\tt.%s = all\n\t}\n", n)
+	fmt.Fprint(w, "\treturn nil\n}\n\n")
+}
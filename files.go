@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileSystem is where LAC reads the files it is given and writes the ones it generates, the
+// local disk unless the config sets another one, such as memory where there is no disk.
+type FileSystem interface {
+	// Open returns the contents of the named file, the caller must close it. Missing files fail
+	// with an error os.IsNotExist recognizes.
+	Open(name string) (io.ReadCloser, error)
+	// Create returns a writer replacing the named file, which is complete once closed.
+	Create(name string) (io.WriteCloser, error)
+	// MkdirAll creates dir and the missing directories above it.
+	MkdirAll(dir string) error
+	// Glob returns the names of the files matching pattern, in the syntax of filepath.Match.
+	Glob(pattern string) ([]string, error)
+}
+
+// files returns the FileSystem of the run.
+func (c *config) files() FileSystem {
+	if c.fileSystem == nil {
+		return diskFileSystem{}
+	}
+	return c.fileSystem
+}
+
+// readFile returns the contents of the named file of fsys.
+func readFile(fsys FileSystem, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// writeFile lets fn fill name, which is only replaced once fn succeeds so a failed or
+// interrupted run leaves the previous contents in place.
+func writeFile(c *config, name string, fn func(io.Writer) error) error {
+	f, err := c.files().Create(name)
+	if err != nil {
+		return fmt.Errorf("creating output file: %w", err)
+	}
+	if err := fn(f); err != nil {
+		if a, ok := f.(aborter); ok {
+			a.Abort()
+		}
+		return err
+	}
+	return f.Close()
+}
+
+// aborter is implemented by the files of Create that can be dropped instead of closed, leaving
+// the file they replace as it was.
+type aborter interface {
+	Abort()
+}
+
+// diskFileSystem is the FileSystem of the local disk.
+type diskFileSystem struct{}
+
+func (diskFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (diskFileSystem) Create(name string) (io.WriteCloser, error) {
+	// the file replaced keeps its permissions, new ones are readable by everyone.
+	mode := os.FileMode(0644)
+	if st, err := os.Stat(name); err == nil {
+		mode = st.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &diskFile{File: f, name: name}, nil
+}
+
+func (diskFileSystem) MkdirAll(dir string) error {
+	return os.MkdirAll(dir, 0755)
+}
+
+func (diskFileSystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+var _ FileSystem = diskFileSystem{}
+
+// diskFile is written to a temporary file next to the one it replaces, which is renamed over it
+// once closed so that readers never see it half written.
+type diskFile struct {
+	*os.File
+	name string
+}
+
+func (f *diskFile) Close() error {
+	if err := f.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.name); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort drops what was written, leaving the file as it was.
+func (f *diskFile) Abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+var _ aborter = &diskFile{}
+
+// memoryFileSystem is a FileSystem held in memory, keyed by slash separated names. Directories
+// are implied by the names of the files.
+type memoryFileSystem struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	// created has the names of the files written since it was made.
+	created map[string]bool
+}
+
+// newMemoryFileSystem returns a memoryFileSystem holding files.
+func newMemoryFileSystem(files map[string][]byte) *memoryFileSystem {
+	m := &memoryFileSystem{files: make(map[string][]byte, len(files)), created: map[string]bool{}}
+	for name, b := range files {
+		m.files[path.Clean(name)] = b
+	}
+	return m
+}
+
+func (m *memoryFileSystem) Open(name string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memoryFileSystem) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{fs: m, name: path.Clean(name)}, nil
+}
+
+func (m *memoryFileSystem) MkdirAll(dir string) error {
+	return nil
+}
+
+func (m *memoryFileSystem) Glob(pattern string) ([]string, error) {
+	// the pattern is checked even when there are no files, as filepath.Glob does.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.files {
+		if ok, _ := path.Match(path.Clean(pattern), name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// written returns the files written since m was made.
+func (m *memoryFileSystem) written() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[string][]byte, len(m.created))
+	for name := range m.created {
+		files[name] = m.files[name]
+	}
+	return files
+}
+
+var _ FileSystem = &memoryFileSystem{}
+
+// memoryFile buffers what is written to a file of a memoryFileSystem until it is closed.
+type memoryFile struct {
+	bytes.Buffer
+	fs   *memoryFileSystem
+	name string
+}
+
+func (f *memoryFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	f.fs.files[f.name] = f.Bytes()
+	f.fs.created[f.name] = true
+	return nil
+}
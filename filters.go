@@ -0,0 +1,146 @@
+package main
+
+import "strings"
+
+// wantsType returns true if the type or component called name should be generated according to
+// --only, names can be given as found in the source or as the generated Go name.
+func (c *config) wantsType(name string) bool {
+	if len(c.only) == 0 && len(c.roots) == 0 {
+		return true
+	}
+	return matchesName(c.only, name)
+}
+
+// matchesName returns true if name is in names either as is or once capitalized.
+func matchesName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name || capitalize(n) == capitalize(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTypes drops the types not asked for with --only or --root and the fields skipped with
+// --skip-field, then warns about the filters that matched nothing as those are likely typos.
+func filterTypes(c *config, types []*Type) []*Type {
+	if len(c.only) == 0 && len(c.roots) == 0 && len(c.skipFields) == 0 {
+		return types
+	}
+	// skipped fields go first so the types only they referenced are not reachable anymore.
+	matched := map[string]bool{}
+	for _, t := range types {
+		structName := capitalize(t.Name)
+		for _, sf := range c.skipFields {
+			for _, fld := range t.Fields {
+				if fld.Name == "" {
+					continue
+				}
+				// same as --typesforitems, Go names, but source names are also accepted.
+				if sf == structName+"."+fld.GoName() || sf == t.Name+"."+fld.Name {
+					t.RemoveField(fld.Name)
+					matched[sf] = true
+					break
+				}
+			}
+		}
+	}
+	reachable := closure(c, types)
+	kept := make([]*Type, 0, len(types))
+	generated := make(map[string]bool, len(types))
+	for _, t := range types {
+		if c.wantsType(t.Name) || reachable[capitalize(t.Name)] {
+			kept = append(kept, t)
+			generated[capitalize(t.Name)] = true
+		}
+	}
+	for _, o := range c.only {
+		if !generated[capitalize(o)] {
+			c.warn(o, "--only matched no type")
+		}
+	}
+	for _, r := range c.roots {
+		if !reachable[capitalize(r)] {
+			c.warn(r, "--root matched no type")
+		}
+	}
+	for _, sf := range c.skipFields {
+		if !matched[sf] {
+			c.warn(sf, "--skip-field matched no field")
+		}
+	}
+	// types left out are still referenced by name, they need to come from somewhere else.
+	for _, t := range kept {
+		for _, fld := range t.Fields {
+			for _, ref := range referencedTypes(&fld.Type) {
+				if !generated[ref] {
+					c.warn(t.Name, "field %s references %s which is not generated", fld.Name, ref)
+				}
+			}
+		}
+	}
+	return kept
+}
+
+// closure returns the Go names of the --root types and of every type they reference, directly or
+// through other types.
+func closure(c *config, types []*Type) map[string]bool {
+	reachable := map[string]bool{}
+	if len(c.roots) == 0 {
+		return reachable
+	}
+	byName := make(map[string]*Type, len(types))
+	pending := make([]*Type, 0, len(c.roots))
+	for _, t := range types {
+		byName[capitalize(t.Name)] = t
+		if matchesName(c.roots, t.Name) {
+			pending = append(pending, t)
+			reachable[capitalize(t.Name)] = true
+		}
+	}
+	for len(pending) > 0 {
+		t := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, fld := range t.Fields {
+			for _, ref := range referencedTypes(&fld.Type) {
+				if rt, ok := byName[ref]; ok && !reachable[ref] {
+					reachable[ref] = true
+					pending = append(pending, rt)
+				}
+			}
+		}
+	}
+	return reachable
+}
+
+// referencedTypes returns the Go names of the generated types f refers to.
+func referencedTypes(f *maybeType) []string {
+	if f.contentType != "" {
+		return []string{capitalize(f.contentType)}
+	}
+	if f.IsMultiple() {
+		refs := make([]string, 0, len(f.multiType))
+		for _, mt := range f.multiType {
+			refs = append(refs, capitalize(mt))
+		}
+		return refs
+	}
+	if f.typeOf != nil || f.nameOftype == "" {
+		return nil
+	}
+	n := baseType(f.nameOftype)
+	// builtins and the types of other packages are not generated, ie the string of a
+	// map[string]string or a time.Time.
+	if goBuiltins[n] || strings.Contains(n, ".") {
+		return nil
+	}
+	return []string{capitalize(n)}
+}
+
+// goBuiltins are the predeclared types of Go fields can hold.
+var goBuiltins = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true, "error": true, "interface{}": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true, "any": true,
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// Content types of form request bodies.
+const (
+	formURLEncoded = "application/x-www-form-urlencoded"
+	formMultipart  = "multipart/form-data"
+)
+
+// markForms flags the components used as form request bodies by the operations in paths, only
+// bodies referencing a component can be flagged as inline ones have no type.
+func markForms(c *config, paths map[string]SwaggerPathItem, types []*Type) {
+	if len(paths) == 0 {
+		return
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for _, p := range names {
+		for _, op := range paths[p].Operations() {
+			if op.RequestBody == nil {
+				continue
+			}
+			for ct, media := range op.RequestBody.Content {
+				mt, _, err := mime.ParseMediaType(ct)
+				if err != nil || (mt != formURLEncoded && mt != formMultipart) {
+					continue
+				}
+				if media.Schema.Ref == "" {
+					c.warn(p, "the %s body of %s is not a component, no form methods are generated for it", mt, op.OperationID)
+					continue
+				}
+				t, ok := byName[typeFromRef(media.Schema.Ref)]
+				if !ok || len(t.Enum) > 0 || t.tuple {
+					continue
+				}
+				if t.forms == nil {
+					t.forms = map[string]bool{}
+				}
+				t.forms[mt] = true
+			}
+		}
+	}
+}
+
+// markXML flags the components the client sends as XML request bodies, and the ones they hold,
+// so that their fields are named as the spec says in XML too. Only bodies referencing a component
+// are sent as XML.
+func markXML(c *config, paths map[string]SwaggerPathItem, types []*Type) {
+	if !c.client || len(paths) == 0 {
+		return
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[capitalize(t.Name)] = t
+	}
+	var pending []*Type
+	for _, p := range sortedKeys(paths) {
+		for _, op := range paths[p].Operations() {
+			if op.RequestBody == nil {
+				continue
+			}
+			for ct, media := range op.RequestBody.Content {
+				if !isXMLMedia(ct) || media.Schema.Ref == "" {
+					continue
+				}
+				if t, ok := byName[capitalize(typeFromRef(media.Schema.Ref))]; ok && !t.xml {
+					t.xml = true
+					pending = append(pending, t)
+				}
+			}
+		}
+	}
+	for len(pending) > 0 {
+		t := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, fld := range t.Fields {
+			for _, ref := range referencedTypes(&fld.Type) {
+				if rt, ok := byName[ref]; ok && !rt.xml {
+					rt.xml = true
+					pending = append(pending, rt)
+				}
+			}
+		}
+	}
+}
+
+// formField is a field of a form body.
+type formField struct {
+	key string
+	// value is the Go expression for the field as a string.
+	value string
+	// guard, if any, is the condition for the field to be sent.
+	guard string
+	// each is set for arrays, value is then the expression for each element, held in e.
+	each bool
+	// file is set for []byte fields, which multipart sends as files.
+	file bool
+	// of is the Go expression of the field, or of the array for each.
+	of string
+}
+
+// formFields returns the fields of t that can be sent in a form along with the imports they need
+// and the names of the ones that can not.
+func formFields(c *config, t *Type) ([]formField, []string, []string) {
+	structName := capitalize(t.Name)
+	var (
+		fields  []formField
+		imports []string
+		skipped []string
+	)
+	for _, fld := range t.Fields {
+		if fld.Name == "" {
+			continue
+		}
+		fn := fld.GoName()
+		tn, _ := fieldType(c, structName, fn, &fld.Type)
+		ff := formField{key: fld.Name, of: "t." + fn}
+		// nullable fields can be pointers, nil ones are not sent.
+		if strings.HasPrefix(tn, "*") {
+			ff.guard = ff.of + " != nil"
+			ff.of = "*" + ff.of
+			tn = tn[1:]
+		}
+		v := ff.of
+		if tn == "[]byte" || tn == base64Type {
+			ff.file = true
+			if ff.guard == "" {
+				ff.guard = "len(" + v + ") > 0"
+			}
+		} else if strings.HasPrefix(tn, "[]") {
+			ff.each = true
+			tn = tn[2:]
+			v = "e"
+		}
+		value, pkg, ok := formValue(tn, fld.Type.enum, v)
+		if !ok {
+			skipped = append(skipped, fn)
+			continue
+		}
+		ff.value = value
+		if pkg != "" {
+			imports = append(imports, pkg)
+		}
+		fields = append(fields, ff)
+	}
+	return fields, imports, skipped
+}
+
+// formValue returns the Go expression turning v, of type tn, into a string along with the
+// package it needs, if any. It returns false for types that have no form representation.
+func formValue(tn string, enum bool, v string) (string, string, bool) {
+	switch tn {
+	case "string":
+		return v, "", true
+	case "[]byte", base64Type:
+		return "string(" + v + ")", "", true
+	case "int64":
+		return "strconv.FormatInt(" + v + ", 10)", "strconv", true
+	case "int", "int32":
+		return "strconv.FormatInt(int64(" + v + "), 10)", "strconv", true
+	case "float64":
+		return "strconv.FormatFloat(" + v + ", 'g', -1, 64)", "strconv", true
+	case "float32":
+		return "strconv.FormatFloat(float64(" + v + "), 'g', -1, 32)", "strconv", true
+	case "bool":
+		return "strconv.FormatBool(" + v + ")", "strconv", true
+	}
+	if enum {
+		return "string(" + v + ")", "", true
+	}
+	if tn == netipAddr || tn == netipPrefix {
+		return v + ".String()", "", true
+	}
+	return "", "", false
+}
+
+// formImports returns the imports needed by the form methods of t.
+func formImports(c *config, t *Type) []string {
+	if len(t.forms) == 0 {
+		return nil
+	}
+	_, imports, _ := formFields(c, t)
+	if t.forms[formURLEncoded] {
+		imports = append(imports, "net/url")
+	}
+	if t.forms[formMultipart] {
+		imports = append(imports, "mime/multipart")
+	}
+	return imports
+}
+
+// writeFormMethods writes FormValues for types sent as urlencoded forms and WriteMultipart for
+// the ones sent as multipart forms. Fields that are not strings, numbers, booleans, enums or
+// arrays of them are left out.
+func writeFormMethods(w io.Writer, c *config, t *Type, structName string) {
+	if len(t.forms) == 0 {
+		return
+	}
+	fields, _, skipped := formFields(c, t)
+	for _, fn := range skipped {
+		c.warn(structName+"."+fn, "the field can not be sent in a form, it is left out of the form methods")
+	}
+	if t.forms[formURLEncoded] {
+		fmt.Fprintf(w, "// FormValues returns %s encoded as an %s body.\n", structName, formURLEncoded)
+		fmt.Fprintf(w, "func (t %s) FormValues() url.Values {\n", structName)
+		fmt.Fprint(w, "\tv := url.Values{}\n")
+		for _, ff := range fields {
+			writeGuarded(w, ff, func(indent string) {
+				if ff.each {
+					fmt.Fprintf(w, "%sfor _, e := range %s {\n%s\tv.Add(%q, %s)\n%s}\n", indent, ff.of, indent, ff.key, ff.value, indent)
+					return
+				}
+				fmt.Fprintf(w, "%sv.Set(%q, %s)\n", indent, ff.key, ff.value)
+			})
+		}
+		fmt.Fprint(w, "\treturn v\n}\n\n")
+	}
+	if t.forms[formMultipart] {
+		fmt.Fprintf(w, "// WriteMultipart writes %s as the parts of a %s body, []byte fields are sent as files.\n", structName, formMultipart)
+		fmt.Fprintf(w, "func (t %s) WriteMultipart(w *multipart.Writer) error {\n", structName)
+		for _, ff := range fields {
+			writeGuarded(w, ff, func(indent string) {
+				switch {
+				case ff.file:
+					fmt.Fprintf(w, "%sfw, err := w.CreateFormFile(%q, %q)\n", indent, ff.key, ff.key)
+					fmt.Fprintf(w, "%sif err != nil {\n%s\treturn err\n%s}\n", indent, indent, indent)
+					fmt.Fprintf(w, "%sif _, err := fw.Write(%s); err != nil {\n%s\treturn err\n%s}\n", indent, ff.of, indent, indent)
+				case ff.each:
+					fmt.Fprintf(w, "%sfor _, e := range %s {\n", indent, ff.of)
+					fmt.Fprintf(w, "%s\tif err := w.WriteField(%q, %s); err != nil {\n%s\t\treturn err\n%s\t}\n%s}\n", indent, ff.key, ff.value, indent, indent, indent)
+				default:
+					fmt.Fprintf(w, "%sif err := w.WriteField(%q, %s); err != nil {\n%s\treturn err\n%s}\n", indent, ff.key, ff.value, indent, indent)
+				}
+			})
+		}
+		fmt.Fprint(w, "\treturn nil\n}\n\n")
+	}
+}
+
+// writeGuarded calls write with the indentation for the statements of ff, inside an if when ff
+// has a guard.
+func writeGuarded(w io.Writer, ff formField, write func(indent string)) {
+	if ff.guard == "" {
+		write("\t")
+		return
+	}
+	fmt.Fprintf(w, "\tif %s {\n", ff.guard)
+	write("\t\t")
+	fmt.Fprint(w, "\t}\n")
+}
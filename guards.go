@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Defaults of the limits keeping documents made to exhaust memory from doing so, they are well
+// above what real sources and specs need.
+const (
+	defaultMaxBytes      = 512 << 20
+	defaultMaxDepth      = 1000
+	defaultMaxComponents = 100000
+)
+
+// readLimited reads the document name from r failing, instead of reading on, when it is larger
+// than --max-bytes.
+func readLimited(c *config, name string, r io.Reader) ([]byte, error) {
+	if c.maxBytes <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, c.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > c.maxBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes, see --max-bytes", name, c.maxBytes)
+	}
+	return b, nil
+}
+
+// checkDepth fails for the JSON documents nesting objects and arrays deeper than --max-depth, at
+// the first value past the limit. It runs before decoding, which would recurse that deep.
+func checkDepth(c *config, name string, b []byte) error {
+	if c.maxDepth <= 0 {
+		return nil
+	}
+	depth := 0
+	inString := false
+	for i := 0; i < len(b); i++ {
+		switch ch := b[i]; {
+		case inString:
+			switch ch {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '{' || ch == '[':
+			depth++
+			if depth > c.maxDepth {
+				err := fmt.Errorf("nested deeper than %d levels, see --max-depth", c.maxDepth)
+				return locateError(name, b, err, int64(i+1))
+			}
+		case ch == '}' || ch == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// checkComponents fails for specs declaring more than --max-components schemas.
+func checkComponents(c *config, n int) error {
+	if c.maxComponents > 0 && n > c.maxComponents {
+		return fmt.Errorf("%s has %d schemas, more than %d, see --max-components", c.swaggerFile, n, c.maxComponents)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// HARFile represents the parts of an HTTP Archive, as saved by the devtools of browsers and by
+// proxies, LAC uses: the responses to each request.
+type HARFile struct {
+	Log struct {
+		Entries []HAREntry `json:"entries"`
+	} `json:"log"`
+}
+
+// HAREntry represents a request of the capture along with its response.
+type HAREntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			// Encoding is base64 when Text is encoded, binary bodies are.
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// harParam matches the path segments that look like identifiers rather than names: numbers,
+// UUIDs and long hex strings such as hashes or object ids.
+var harParam = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// harEndpoint is a method and path pattern of the capture, with the samples of its JSON responses.
+type harEndpoint struct {
+	method  string
+	pattern string
+	// segments are the ones of the path, the parameters are empty.
+	segments []string
+	docs     []interface{}
+}
+
+// newHAREndpoint returns the endpoint of the requests with method to path.
+func newHAREndpoint(method, path string) *harEndpoint {
+	ep := &harEndpoint{method: strings.ToUpper(method)}
+	params := 0
+	var pattern []string
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if harParam.MatchString(segment) {
+			params++
+			param := "{id}"
+			if params > 1 {
+				param = fmt.Sprintf("{id%d}", params)
+			}
+			pattern = append(pattern, param)
+			ep.segments = append(ep.segments, "")
+			continue
+		}
+		pattern = append(pattern, segment)
+		ep.segments = append(ep.segments, segment)
+	}
+	ep.pattern = "/" + strings.Join(pattern, "/")
+	return ep
+}
+
+// names returns how many of the segments are names rather than parameters, and how many the path
+// starts with before the first parameter.
+func (ep *harEndpoint) names() (all, leading int) {
+	leading = -1
+	for i, segment := range ep.segments {
+		if segment != "" {
+			all++
+		} else if leading < 0 {
+			leading = i
+		}
+	}
+	if leading < 0 {
+		leading = len(ep.segments)
+	}
+	return all, leading
+}
+
+// typeName returns the name of the responses of the endpoint made of its method and its path
+// after the first skip segments, each parameter being by_id where it is: get_users_by_id for
+// GET /users/{id} and get_users_by_id_posts for GET /users/{id}/posts, which is not the type of
+// GET /users/posts.
+func (ep *harEndpoint) typeName(skip int) string {
+	words := []string{strings.ToLower(ep.method)}
+	named := false
+	for i, segment := range ep.segments[skip:] {
+		switch {
+		case segment != "":
+			words = append(words, strings.Trim(nonIdentifier.ReplaceAllString(segment, "_"), "_"))
+			named = true
+		case i > 0 && ep.segments[skip+i-1] == "":
+			words = append(words, "and_id")
+		default:
+			words = append(words, "by_id")
+		}
+	}
+	if !named {
+		words = append(words[:1], append([]string{"root"}, words[1:]...)...)
+	}
+	return strings.Join(words, "_")
+}
+
+// commonPrefix returns how many segments, like the ones of /api/v1, all the endpoints start
+// with, leaving each endpoint at least one name of its own.
+func commonPrefix(endpoints []*harEndpoint) int {
+	if len(endpoints) == 0 {
+		return 0
+	}
+	first := endpoints[0].segments
+	_, common := endpoints[0].names()
+	for _, ep := range endpoints {
+		all, leading := ep.names()
+		if leading < common {
+			common = leading
+		}
+		if all <= common {
+			common = all - 1
+		}
+		for i := 0; i < common; i++ {
+			if ep.segments[i] != first[i] {
+				common = i
+				break
+			}
+		}
+	}
+	if common < 0 {
+		return 0
+	}
+	return common
+}
+
+// readHARSources returns a source per endpoint of the --har captures, with the JSON bodies of the
+// 2xx responses to the requests with the same method and path pattern as samples. Segments that
+// look like identifiers are the parameters of the pattern, so /users/1 and /users/2 are the same
+// endpoint, and the types are named after the method and the rest of the path.
+func readHARSources(ctx context.Context, c *config) ([]jsonSource, error) {
+	var result []jsonSource
+	for _, pattern := range c.harFiles {
+		names, err := c.files().Glob(pattern)
+		if err != nil || len(names) == 0 {
+			names = []string{pattern}
+		}
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", name, err)
+			}
+			srcs, err := readHAR(c, name)
+			if err != nil && c.keepGoing {
+				c.failedSources = append(c.failedSources, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, srcs...)
+		}
+	}
+	return result, nil
+}
+
+// readHAR returns the sources of the endpoints of the capture in name, in the order they were
+// first requested.
+func readHAR(c *config, name string) ([]jsonSource, error) {
+	f, err := c.files().Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening har file: %w", err)
+	}
+	b, err := readLimited(c, name, f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading har file: %w", err)
+	}
+	if b, err = toUTF8(b); err != nil {
+		return nil, fmt.Errorf("reading har file: %w", err)
+	}
+	var har HARFile
+	if err := json.Unmarshal(b, &har); err != nil {
+		return nil, fmt.Errorf("decoding har file %s: %w", name, err)
+	}
+	var endpoints []*harEndpoint
+	byKey := map[string]*harEndpoint{}
+	for i, entry := range har.Log.Entries {
+		content := entry.Response.Content
+		// pages, scripts and images are most of a capture.
+		if !strings.Contains(content.MimeType, "json") {
+			continue
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			c.warn(name, "entry %d has an invalid url %q, it is skipped", i, entry.Request.URL)
+			continue
+		}
+		ep := newHAREndpoint(entry.Request.Method, u.Path)
+		key := ep.method + " " + ep.pattern
+		label := name + " " + key
+		if entry.Response.Status < 200 || entry.Response.Status > 299 {
+			c.debugf("%s: skipping the %d response of entry %d, only the 2xx ones are samples\n", label, entry.Response.Status, i)
+			continue
+		}
+		text := []byte(content.Text)
+		if content.Encoding == "base64" {
+			if text, err = base64.StdEncoding.DecodeString(content.Text); err != nil {
+				c.warn(label, "the body of entry %d is not valid base64, it is skipped", i)
+				continue
+			}
+		}
+		if len(bytes.TrimSpace(text)) == 0 {
+			c.debugf("%s: the body of entry %d was not captured\n", label, i)
+			continue
+		}
+		docs, err := decodeDocuments(c, label, text)
+		if err != nil {
+			c.warn(label, "the body of entry %d is skipped: %s", i, err)
+			continue
+		}
+		if byKey[key] == nil {
+			byKey[key] = ep
+			endpoints = append(endpoints, ep)
+		}
+		byKey[key].docs = append(byKey[key].docs, docs...)
+	}
+	if len(endpoints) == 0 {
+		c.warn(name, "the capture has no 2xx responses with a JSON body")
+	}
+	skip := commonPrefix(endpoints)
+	srcs := make([]jsonSource, 0, len(endpoints))
+	for _, ep := range endpoints {
+		c.debugf("%s: %s %s has %d samples\n", name, ep.method, ep.pattern, len(ep.docs))
+		srcs = append(srcs, jsonSource{name: name, docs: ep.docs, typeName: ep.typeName(skip)})
+	}
+	return srcs, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Field is a single member of a generated Type.
+type Field struct {
+	// Name is the name as found in the source, it is used for the tags and to derive the Go
+	// name. Empty names are embedded types, which happens for anyOf, oneOf and allOf.
+	Name string
+	// Type holds what we know about the type of the field.
+	Type maybeType
+
+	// goName is the Go name the source gives the field, from x-go-name.
+	goName string
+}
+
+// GoName returns the name of the field in the generated struct.
+func (f Field) GoName() string {
+	if f.goName != "" {
+		return f.goName
+	}
+	return fieldName(f.Name)
+}
+
+// smallType is the amount of fields under which looking for a field is done by scanning.
+const smallType = 16
+
+// Type is a single struct to be generated, its fields keep the order they had in the source.
+type Type struct {
+	// Name is the name the type was registered with, parented names are dot separated.
+	Name string
+	// Source is the file the type was generated from, to trace back generation errors.
+	Source string
+	// Description is added to the type comment when the source provides one.
+	Description string
+	Fields      []Field
+	// Enum holds the valid values when the type is a string enum instead of a struct.
+	Enum []string
+
+	// enumNames are the names of the constants of the Enum values when the source names them,
+	// see protoEnumNames.
+	enumNames []string
+	// extra is set when the type keeps the unknown keys, see markExtra.
+	extra bool
+	// tuple is set when the type is a JSON array, its fields are the positions of the array.
+	tuple bool
+	// union is set when the type is a oneOf with a discriminator, its field still embeds the
+	// variants so that it references them.
+	union *union
+	// param is set when the type is the JSON content of a parameter, see paramTypes.
+	param bool
+	// forms holds the content types the type is sent as when it is a form request body, see
+	// markForms.
+	forms map[string]bool
+	// xml is set when the client sends the type as XML, its fields get xml tags then, see markXML.
+	xml bool
+	// checks are the conditions Validate enforces, see markChecks.
+	checks []fieldCheck
+	// examples are the examples of the schema of the type, see markExamples.
+	examples []json.RawMessage
+	// original is the key of the samples the type was found under, see applyLock.
+	original string
+
+	index map[string]int
+	// shape is the structural hash of the first hashed fields, see Shape.
+	shape  uint64
+	hashed int
+}
+
+// Shape returns a structural hash of the field names and types, independent of field order. It
+// is the sum of the hashes of each field so adding fields only hashes the new ones.
+func (t *Type) Shape() uint64 {
+	for ; t.hashed < len(t.Fields); t.hashed++ {
+		f := &t.Fields[t.hashed]
+		h := fnvAdd(fnvOffset, f.Name)
+		h = fnvAdd(h, "\x00")
+		h = f.Type.hashSignature(h)
+		t.shape += h
+	}
+	return t.shape
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// fnvAdd adds s to the FNV-1a hash h, it is hash/fnv without the allocations.
+func fnvAdd(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return h
+}
+
+// Field returns the field with the given name or nil if there is none.
+func (t *Type) Field(name string) *Field {
+	// small types are cheaper to scan than to index.
+	if len(t.Fields) <= smallType {
+		for i := range t.Fields {
+			if t.Fields[i].Name == name {
+				return &t.Fields[i]
+			}
+		}
+		return nil
+	}
+	if len(t.index) != len(t.Fields) {
+		t.index = make(map[string]int, len(t.Fields))
+		for i, f := range t.Fields {
+			t.index[f.Name] = i
+		}
+	}
+	i, ok := t.index[name]
+	if !ok {
+		return nil
+	}
+	return &t.Fields[i]
+}
+
+// AddField appends a field to the type.
+func (t *Type) AddField(f Field) {
+	t.Fields = append(t.Fields, f)
+	if t.index != nil {
+		t.index[f.Name] = len(t.Fields) - 1
+	}
+}
+
+// RemoveField removes the field called name from the type, it returns false if there was none.
+func (t *Type) RemoveField(name string) bool {
+	for i := range t.Fields {
+		if t.Fields[i].Name != name {
+			continue
+		}
+		t.Fields = append(t.Fields[:i:i], t.Fields[i+1:]...)
+		// positions after i moved and the shape included the field, both are recomputed lazily.
+		t.index = nil
+		t.shape, t.hashed = 0, 0
+		return true
+	}
+	return false
+}
+
+// canonicalOrder sorts types by name, this is the order in which they are emitted so the same
+// input always yields the same output regardless of how it was processed.
+func canonicalOrder(types []*Type) []*Type {
+	sort.SliceStable(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
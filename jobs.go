@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// parallel calls fn for every index in [0, n) using at most jobs goroutines, callers write
+// results into index i of a preallocated slice so merging them stays deterministic. It stops
+// handing out work once ctx is done and returns the context error in that case.
+func parallel(ctx context.Context, jobs, n int, fn func(i int)) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+	work := make(chan int)
+	wg := &sync.WaitGroup{}
+	wg.Add(jobs)
+	for j := 0; j < jobs; j++ {
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				fn(i)
+			}
+		}()
+	}
+	var err error
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	return err
+}
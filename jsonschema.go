@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// JSONSchemaDocument represents a standalone JSON Schema, draft-07 keeps the schemas it
+// references under definitions and 2020-12 under $defs.
+type JSONSchemaDocument struct {
+	SwaggerSchema
+	Title       string         `json:"title,omitempty"`
+	Defs        SwaggerSchemas `json:"$defs,omitempty"`
+	Definitions SwaggerSchemas `json:"definitions,omitempty"`
+}
+
+// jsonSchemaIntoTypes returns the types of the schemas in $defs and definitions along with the one
+// of the root schema, named after its title or, lacking one, after the file.
+func jsonSchemaIntoTypes(ctx context.Context, c *config) ([]*Type, error) {
+	var doc JSONSchemaDocument
+	if err := decodeSpec(ctx, c, &doc); err != nil {
+		return nil, fmt.Errorf("decoding json schema: %w", err)
+	}
+	if err := checkComponents(c, len(doc.Defs.Names)+len(doc.Definitions.Names)); err != nil {
+		return nil, err
+	}
+	var result []*Type
+	for _, defs := range []SwaggerSchemas{doc.Defs, doc.Definitions} {
+		for _, name := range defs.Names {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("processing %s: %w", name, err)
+			}
+			result = append(result, processComponent(c, name, objectSchema(defs.ByName[name]))...)
+		}
+	}
+	names := goNames(doc.Defs)
+	for name, goName := range goNames(doc.Definitions) {
+		names[name] = goName
+	}
+	// examples are looked up by name, and refs only keep the last part of the path.
+	schemas := SwaggerSchemas{ByName: map[string]SwaggerSchema{}}
+	for _, defs := range []SwaggerSchemas{doc.Defs, doc.Definitions} {
+		for _, name := range defs.Names {
+			schemas.ByName[name] = defs.ByName[name]
+		}
+	}
+	root := objectSchema(doc.SwaggerSchema)
+	if root.Type != "" {
+		rootName := rootSchemaName(doc.Title, c.swaggerFile)
+		result = append(result, processComponent(c, rootName, root)...)
+		if root.GoName != "" {
+			names[rootName] = root.GoName
+		}
+		schemas.ByName[rootName] = root
+	} else {
+		c.debugf("the root of %s is not a type, only its definitions are generated\n", c.swaggerFile)
+	}
+	// only the fixtures use the examples.
+	if c.fixtures {
+		if err := markExamples(ctx, result, schemas); err != nil {
+			return nil, err
+		}
+	}
+	renameTypes(result, names)
+	markEnumRefs(result)
+	return canonicalOrder(splitReadOnly(c, result)), nil
+}
+
+// objectSchema returns s typed as an object when it has properties but no type, as JSON Schemas
+// often leave it implicit.
+func objectSchema(s SwaggerSchema) SwaggerSchema {
+	if s.Type == "" && (len(s.Properties.Names) > 0 || len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0) {
+		s.Type = STObject
+	}
+	return s
+}
+
+// rootSchemaName returns the name of the type of the root schema, its title without spaces or the
+// name of the file without extensions like outer types of samples.
+func rootSchemaName(title, location string) string {
+	if title != "" {
+		return strings.Join(strings.Fields(title), "_")
+	}
+	return strings.Split(path.Base(location), ".")[0]
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	k8sMetaImport    = `metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"`
+	k8sRuntimeImport = "k8s.io/apimachinery/pkg/runtime"
+)
+
+// isK8sRoot returns true for types that look like a kubernetes object, those get TypeMeta and
+// ObjectMeta instead of their apiVersion, kind and metadata fields plus DeepCopyObject and a
+// List type so they can be registered in a scheme.
+func isK8sRoot(t *Type) bool {
+	return len(t.Enum) == 0 && t.Field("apiVersion") != nil && t.Field("kind") != nil
+}
+
+// isK8sMetaField returns true for the fields of a root replaced by TypeMeta and ObjectMeta.
+func isK8sMetaField(fn string) bool {
+	return fn == "apiVersion" || fn == "kind" || fn == "metadata"
+}
+
+// k8sStructs returns the Go names of the types that will be generated as structs, only those
+// have DeepCopy methods.
+func k8sStructs(types []*Type) map[string]bool {
+	structs := make(map[string]bool, len(types))
+	for _, t := range types {
+		if len(t.Enum) == 0 {
+			structs[capitalize(t.Name)] = true
+		}
+	}
+	return structs
+}
+
+// writeK8sTypeMarkers writes the markers that go right above a type definition.
+func writeK8sTypeMarkers(w io.Writer, t *Type) {
+	if len(t.Enum) > 0 {
+		values := make([]interface{}, 0, len(t.Enum))
+		for _, v := range t.Enum {
+			values = append(values, v)
+		}
+		fmt.Fprintf(w, "// +kubebuilder:validation:Enum=%s\n", markerValues(values))
+		return
+	}
+	if isK8sRoot(t) {
+		fmt.Fprint(w, "// +kubebuilder:object:root=true\n")
+		if t.Field("status") != nil {
+			fmt.Fprint(w, "// +kubebuilder:subresource:status\n")
+		}
+	}
+}
+
+// writeK8sRootMeta writes the embedded TypeMeta and ObjectMeta of a root type.
+func writeK8sRootMeta(w io.Writer, t *Type) {
+	fmt.Fprint(w, "\tmetav1.TypeMeta `json:\",inline\"`\n")
+	if t.Field("metadata") != nil {
+		fmt.Fprint(w, "\tmetav1.ObjectMeta `json:\"metadata,omitempty\"`\n")
+	}
+}
+
+// writeK8sFieldMarkers writes the kubebuilder validation markers for the constraints of a field.
+func writeK8sFieldMarkers(w io.Writer, cs *constraints) {
+	if cs != nil && cs.required {
+		fmt.Fprint(w, "\t// +kubebuilder:validation:Required\n")
+	} else {
+		fmt.Fprint(w, "\t// +optional\n")
+	}
+	for _, m := range k8sMarkers(cs, "") {
+		fmt.Fprintf(w, "\t// +kubebuilder:validation:%s\n", m)
+	}
+}
+
+// k8sMarkers returns the validation markers for cs without the +kubebuilder:validation: prefix,
+// the ones for array items are prefixed with items:.
+func k8sMarkers(cs *constraints, prefix string) []string {
+	if cs == nil {
+		return nil
+	}
+	var markers []string
+	add := func(format string, args ...interface{}) {
+		markers = append(markers, prefix+fmt.Sprintf(format, args...))
+	}
+	if cs.minimum != nil {
+		add("Minimum=%s", strconv.FormatFloat(*cs.minimum, 'f', -1, 64))
+		if cs.exclusiveMinimum {
+			add("ExclusiveMinimum=true")
+		}
+	}
+	if cs.maximum != nil {
+		add("Maximum=%s", strconv.FormatFloat(*cs.maximum, 'f', -1, 64))
+		if cs.exclusiveMaximum {
+			add("ExclusiveMaximum=true")
+		}
+	}
+	if cs.minLength != nil {
+		add("MinLength=%d", *cs.minLength)
+	}
+	if cs.maxLength != nil {
+		add("MaxLength=%d", *cs.maxLength)
+	}
+	if cs.pattern != "" {
+		if strings.Contains(cs.pattern, "`") {
+			add("Pattern=%q", cs.pattern)
+		} else {
+			add("Pattern=`%s`", cs.pattern)
+		}
+	}
+	if cs.minItems != nil {
+		add("MinItems=%d", *cs.minItems)
+	}
+	if cs.maxItems != nil {
+		add("MaxItems=%d", *cs.maxItems)
+	}
+	if cs.minProperties != nil {
+		add("MinProperties=%d", *cs.minProperties)
+	}
+	if cs.maxProperties != nil {
+		add("MaxProperties=%d", *cs.maxProperties)
+	}
+	if len(cs.enum) > 0 {
+		add("Enum=%s", markerValues(cs.enum))
+	}
+	return append(markers, k8sMarkers(cs.items, "items:")...)
+}
+
+// markerValues formats values as the ; separated list markers expect, strings that would break
+// the list are quoted.
+func markerValues(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			parts = append(parts, fmt.Sprint(v))
+			continue
+		}
+		if s == "" || strings.ContainsAny(s, "; ,\"=`") {
+			s = strconv.Quote(s)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ";")
+}
+
+// writeK8sMethods writes the DeepCopy scaffolding of t and, for root types, DeepCopyObject and
+// the List type, the same that controller-gen would otherwise generate.
+func writeK8sMethods(w io.Writer, c *config, t *Type, structs map[string]bool) {
+	if len(t.Enum) > 0 {
+		return
+	}
+	structName := capitalize(t.Name)
+	root := isK8sRoot(t)
+
+	fmt.Fprintf(w, "// DeepCopyInto copies the receiver into out, in must be non-nil.\n")
+	fmt.Fprintf(w, "func (in *%s) DeepCopyInto(out *%s) {\n", structName, structName)
+	fmt.Fprint(w, "\t*out = *in\n")
+	if root && t.Field("metadata") != nil {
+		fmt.Fprint(w, "\tin.ObjectMeta.DeepCopyInto(&out.ObjectMeta)\n")
+	}
+	for _, fld := range t.Fields {
+		fn, f := fld.Name, fld.Type
+		if fn == "" && t.union != nil {
+			writeDeepCopyUnion(w, t, structs)
+			break
+		}
+		if fn == "" {
+			writeDeepCopyEmbedded(w, "", f.multiType)
+			continue
+		}
+		if root && isK8sMetaField(fn) {
+			continue
+		}
+		capitalizedFN := fld.GoName()
+		if f.IsMultiple() {
+			writeDeepCopyEmbedded(w, capitalizedFN+".", f.multiType)
+			continue
+		}
+		tn, _ := fieldType(c, structName, capitalizedFN, &f)
+		if tn == structName {
+			tn = "*" + tn
+		}
+		if strings.HasSuffix(tn, "interface{}") {
+			c.warn(t.Name, "field %s is %s, DeepCopy will share its value", fn, tn)
+		}
+		writeDeepCopyField(w, capitalizedFN, tn, structs)
+	}
+	if t.extra {
+		writeDeepCopyField(w, extraFieldName(t), "map[string]json.RawMessage", structs)
+	}
+	fmt.Fprint(w, "}\n\n")
+	writeDeepCopy(w, structName)
+
+	if !root {
+		return
+	}
+	writeDeepCopyObject(w, structName)
+
+	listName := structName + "List"
+	fmt.Fprintf(w, "// %s contains a list of %s.\n", listName, structName)
+	fmt.Fprint(w, "// +kubebuilder:object:root=true\n")
+	fmt.Fprintf(w, "type %s struct {\n", listName)
+	fmt.Fprint(w, "\tmetav1.TypeMeta `json:\",inline\"`\n")
+	fmt.Fprint(w, "\tmetav1.ListMeta `json:\"metadata,omitempty\"`\n")
+	fmt.Fprintf(w, "\tItems []%s `json:\"items\"`\n", structName)
+	fmt.Fprint(w, "}\n\n")
+	fmt.Fprintf(w, "// DeepCopyInto copies the receiver into out, in must be non-nil.\n")
+	fmt.Fprintf(w, "func (in *%s) DeepCopyInto(out *%s) {\n", listName, listName)
+	fmt.Fprint(w, "\t*out = *in\n")
+	fmt.Fprint(w, "\tin.ListMeta.DeepCopyInto(&out.ListMeta)\n")
+	writeDeepCopyField(w, "Items", "[]"+structName, structs)
+	fmt.Fprint(w, "}\n\n")
+	writeDeepCopy(w, listName)
+	writeDeepCopyObject(w, listName)
+}
+
+// writeDeepCopyField writes the statements that deep copy the field fn of type tn, values that
+// are not references are already copied by *out = *in so nothing is written for them.
+func writeDeepCopyField(w io.Writer, fn, tn string, structs map[string]bool) {
+	switch {
+	case strings.HasPrefix(tn, "[]"):
+		elem := tn[2:]
+		fmt.Fprintf(w, "\tif in.%s != nil {\n", fn)
+		fmt.Fprintf(w, "\t\tout.%s = make(%s, len(in.%s))\n", fn, tn, fn)
+		switch {
+		case structs[elem]:
+			fmt.Fprintf(w, "\t\tfor i := range in.%s {\n\t\t\tin.%s[i].DeepCopyInto(&out.%s[i])\n\t\t}\n", fn, fn, fn)
+		case strings.HasPrefix(elem, "*") && structs[elem[1:]]:
+			fmt.Fprintf(w, "\t\tfor i := range in.%s {\n\t\t\tout.%s[i] = in.%s[i].DeepCopy()\n\t\t}\n", fn, fn, fn)
+		default:
+			fmt.Fprintf(w, "\t\tcopy(out.%s, in.%s)\n", fn, fn)
+		}
+		fmt.Fprint(w, "\t}\n")
+	case strings.HasPrefix(tn, "map["):
+		value := tn[strings.Index(tn, "]")+1:]
+		fmt.Fprintf(w, "\tif in.%s != nil {\n", fn)
+		fmt.Fprintf(w, "\t\tout.%s = make(%s, len(in.%s))\n", fn, tn, fn)
+		fmt.Fprintf(w, "\t\tfor k, v := range in.%s {\n", fn)
+		if structs[value] {
+			fmt.Fprintf(w, "\t\t\tout.%s[k] = *v.DeepCopy()\n", fn)
+		} else {
+			fmt.Fprintf(w, "\t\t\tout.%s[k] = v\n", fn)
+		}
+		fmt.Fprint(w, "\t\t}\n\t}\n")
+	case strings.HasPrefix(tn, "*"):
+		elem := tn[1:]
+		fmt.Fprintf(w, "\tif in.%s != nil {\n", fn)
+		if structs[elem] {
+			fmt.Fprintf(w, "\t\tout.%s = in.%s.DeepCopy()\n", fn, fn)
+		} else {
+			fmt.Fprintf(w, "\t\tout.%s = new(%s)\n\t\t*out.%s = *in.%s\n", fn, elem, fn, fn)
+		}
+		fmt.Fprint(w, "\t}\n")
+	case structs[tn]:
+		fmt.Fprintf(w, "\tin.%s.DeepCopyInto(&out.%s)\n", fn, fn)
+	}
+}
+
+// writeDeepCopyEmbedded deep copies the pointers embedded for anyOf, oneOf and allOf, prefix is
+// the path to the struct holding them.
+func writeDeepCopyEmbedded(w io.Writer, prefix string, multiType []string) {
+	for _, mt := range multiType {
+		n := prefix + capitalize(mt)
+		fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tout.%s = in.%s.DeepCopy()\n\t}\n", n, n, n)
+	}
+}
+
+// writeDeepCopyUnion deep copies the variant held by the union t.
+func writeDeepCopyUnion(w io.Writer, t *Type, structs map[string]bool) {
+	fmt.Fprint(w, "\tswitch v := in.Value.(type) {\n")
+	for _, uc := range t.union.cases {
+		n := capitalize(uc.typeName)
+		fmt.Fprintf(w, "\tcase *%s:\n", n)
+		if structs[n] {
+			fmt.Fprint(w, "\t\tout.Value = v.DeepCopy()\n")
+			continue
+		}
+		fmt.Fprint(w, "\t\tcp := *v\n\t\tout.Value = &cp\n")
+	}
+	fmt.Fprint(w, "\t}\n")
+}
+
+// writeDeepCopy writes the DeepCopy method of typeName.
+func writeDeepCopy(w io.Writer, typeName string) {
+	fmt.Fprintf(w, "// DeepCopy returns a deep copy of the receiver.\n")
+	fmt.Fprintf(w, "func (in *%s) DeepCopy() *%s {\n", typeName, typeName)
+	fmt.Fprint(w, "\tif in == nil {\n\t\treturn nil\n\t}\n")
+	fmt.Fprintf(w, "\tout := new(%s)\n\tin.DeepCopyInto(out)\n\treturn out\n}\n\n", typeName)
+}
+
+// writeDeepCopyObject writes the DeepCopyObject method that makes typeName a runtime.Object.
+func writeDeepCopyObject(w io.Writer, typeName string) {
+	fmt.Fprintf(w, "// DeepCopyObject implements runtime.Object.\n")
+	fmt.Fprintf(w, "func (in *%s) DeepCopyObject() runtime.Object {\n", typeName)
+	fmt.Fprint(w, "\tif c := in.DeepCopy(); c != nil {\n\t\treturn c\n\t}\n\treturn nil\n}\n\n")
+}
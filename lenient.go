@@ -0,0 +1,67 @@
+package main
+
+// stripJSONC turns JSON with comments and trailing commas into plain JSON. Comments and the
+// commas are replaced by spaces, keeping the line breaks, so the offsets of decoding errors still
+// point at the original text.
+func stripJSONC(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	inString := false
+	for i := 0; i < len(out); i++ {
+		switch ch := out[i]; {
+		case inString:
+			switch ch {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case ch == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			for i += 2; i < len(out) && !(out[i] == '*' && i+1 < len(out) && out[i+1] == '/'); i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			if i < len(out) {
+				out[i], out[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+	// comments are gone so only spaces can be between a trailing comma and what it closes.
+	inString = false
+	for i := 0; i < len(out); i++ {
+		switch ch := out[i]; {
+		case inString:
+			switch ch {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == ',':
+			j := i + 1
+			for j < len(out) && isJSONSpace(out[j]) {
+				j++
+			}
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+// isJSONSpace returns true for the whitespace JSON allows between tokens.
+func isJSONSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetWidth is how much of the offending line parse errors show around the column.
+const snippetWidth = 60
+
+// parseError is an error decoding a document, located at the line and column where it happened.
+type parseError struct {
+	file    string
+	line    int
+	column  int
+	snippet string
+	caret   int
+	err     error
+}
+
+// Error implements error, the offending line is shown under the message with a caret at the
+// column.
+func (e *parseError) Error() string {
+	msg := fmt.Sprintf("%s:%d:%d: %v", e.file, e.line, e.column, e.err)
+	if e.snippet == "" {
+		return msg
+	}
+	return msg + "\n\t" + e.snippet + "\n\t" + strings.Repeat(" ", e.caret) + "^"
+}
+
+// Unwrap returns the decoding error.
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// partError is an error decoding a part of a document. encoding/json hands Unmarshalers a copy of
+// the part holding their value and the offsets of the errors decoding it are relative to that
+// part, so the part is kept to find where it is.
+type partError struct {
+	part []byte
+	// at is where the part starts in the enclosing one, -1 when that is not known and the part is
+	// looked for in it.
+	at  int
+	err error
+}
+
+// Error implements error.
+func (e *partError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the decoding error.
+func (e *partError) Unwrap() error {
+	return e.err
+}
+
+// withinPart returns err, from decoding b, along with b.
+func withinPart(b []byte, err error) error {
+	return atPart(b, -1, err)
+}
+
+// atPart returns err, from decoding b, along with b and where it starts in the enclosing part.
+func atPart(b []byte, at int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &partError{part: b, at: at, err: err}
+}
+
+// partStart returns where the innermost part err was decoding starts in b, the document.
+func partStart(b []byte, err error) (int, bool) {
+	start := 0
+	for ; err != nil; err = errors.Unwrap(err) {
+		part, ok := err.(*partError)
+		switch {
+		case !ok:
+		case part.at >= 0:
+			start += part.at
+		default:
+			i := bytes.Index(b[start:], part.part)
+			if i < 0 {
+				return 0, false
+			}
+			start += i
+		}
+	}
+	return start, true
+}
+
+// locateError returns err, from decoding b, located in file when it tells where it happened or
+// offset, the position of the decoder, otherwise. A negative offset leaves other errors as they are.
+func locateError(file string, b []byte, err error, offset int64) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case offset < 0:
+		return err
+	}
+	start, ok := partStart(b, err)
+	if !ok {
+		return err
+	}
+	offset += int64(start)
+	// the end of the document is past its last byte.
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	line, column, snippet, caret := position(b, int(offset))
+	return &parseError{file: file, line: line, column: column, snippet: snippet, caret: caret, err: err}
+}
+
+// position returns the line and column, both counting from 1, of the byte at offset in b along
+// with up to snippetWidth characters of its line around it and where it is in them. Offsets of
+// encoding/json point after the offending byte so the column is the one of the byte before.
+func position(b []byte, offset int) (int, int, string, int) {
+	if offset > 0 {
+		offset--
+	}
+	start := bytes.LastIndexByte(b[:offset], '\n') + 1
+	end := len(b)
+	if i := bytes.IndexByte(b[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	line := bytes.Count(b[:start], []byte("\n")) + 1
+	before := []rune(string(b[start:offset]))
+	column := len(before) + 1
+	text := []rune(strings.TrimRight(string(b[start:end]), "\r"))
+	from := 0
+	if len(before) > snippetWidth/2 {
+		from = len(before) - snippetWidth/2
+	}
+	to := from + snippetWidth
+	if to > len(text) {
+		to = len(text)
+	}
+	snippet := string(text[from:to])
+	// tabs are shown as spaces so the caret lines up.
+	snippet = strings.Replace(snippet, "\t", " ", -1)
+	if !utf8.ValidString(snippet) || strings.TrimSpace(snippet) == "" {
+		return line, column, "", 0
+	}
+	return line, column, snippet, len(before) - from
+}
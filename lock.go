@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLockFile is where --lock keeps the names when given without a file.
+const defaultLockFile = "lac.lock"
+
+// LockEntry records the name chosen for a type found under a key of the samples with a shape, so
+// the next generation gives the same type the same name even if the names it would choose change,
+// as when new samples bring a type with the same key that is found first.
+type LockEntry struct {
+	// Original is the key the type was found under, or the name of its file for the outer ones.
+	Original string `json:"original"`
+	// Shape is the hash of the keys of the type and the kind of their values.
+	Shape  string `json:"shape"`
+	Name   string `json:"name"`
+	GoName string `json:"goName"`
+}
+
+// lockFile is the contents of the --lock file.
+type lockFile struct {
+	Types []LockEntry `json:"types"`
+}
+
+// lockKey identifies a type across generations.
+type lockKey struct {
+	original, shape string
+}
+
+// lockShape returns the hash of the field names of t and the type of their values, the types
+// generated are described by their keys rather than by their names as those are what the lock
+// keeps. It does not depend on the order of the fields.
+func lockShape(t *Type, byName map[string]*Type) string {
+	if len(t.Enum) > 0 {
+		return strconv.FormatUint(fnvAdd(fnvOffset, enumSignature(t.Enum)), 16)
+	}
+	var sum uint64
+	describe := func(h uint64, name string) uint64 {
+		if end := strings.LastIndexByte(name, ']'); end > 0 && strings.HasPrefix(name, "map[") {
+			h = fnvAdd(h, name[:end+1])
+			name = name[end+1:]
+		}
+		if ref, ok := byName[name]; ok {
+			return fnvAdd(h, typeSignature(ref))
+		}
+		return fnvAdd(h, name)
+	}
+	for _, fld := range t.Fields {
+		f := fld.Type
+		h := fnvAdd(fnvOffset, fld.Name)
+		h = fnvAdd(h, "\x00")
+		if f.isArray {
+			h = fnvAdd(h, "[]")
+		}
+		switch {
+		case len(f.multiType) > 0:
+			for _, mt := range f.multiType {
+				h = describe(fnvAdd(h, "|"), mt)
+			}
+		case f.typeOf != nil:
+			h = fnvAdd(h, f.typeOf.PkgPath()+"."+f.typeOf.Name())
+		default:
+			h = describe(h, f.nameOftype)
+		}
+		sum += h
+	}
+	return strconv.FormatUint(sum, 16)
+}
+
+// applyLock gives the types found in the --lock file, by the key they were found under and
+// their shape, the name they had then. Types claiming the same name keep the one they have.
+func applyLock(c *config, types []*Type) ([]*Type, error) {
+	if c.lockFile == "" {
+		return types, nil
+	}
+	b, err := readFile(c.files(), c.lockFile)
+	if os.IsNotExist(err) {
+		return types, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lock: %w", err)
+	}
+	var lock lockFile
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("decoding lock %s: %w", c.lockFile, err)
+	}
+	locked := make(map[lockKey]string, len(lock.Types))
+	for _, e := range lock.Types {
+		locked[lockKey{e.Original, e.Shape}] = e.Name
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	proposed := make(map[*Type]string, len(types))
+	for _, t := range types {
+		proposed[t] = t.Name
+		if name, ok := locked[lockKey{t.original, lockShape(t, byName)}]; ok && t.original != "" {
+			proposed[t] = name
+		}
+	}
+	// types claiming a taken name give up theirs until no two have the same.
+	for clash := true; clash; {
+		clash = false
+		claims := map[string]int{}
+		for _, t := range types {
+			claims[proposed[t]]++
+		}
+		for _, t := range types {
+			if claims[proposed[t]] > 1 && proposed[t] != t.Name {
+				c.warn(t.Name, "the locked name %s is taken, the type keeps its name", proposed[t])
+				proposed[t] = t.Name
+				clash = true
+			}
+		}
+	}
+	names := map[string]string{}
+	renamed := false
+	for _, t := range types {
+		names[t.Name] = proposed[t]
+		if proposed[t] != t.Name {
+			c.debugf("%s is named %s as locked\n", t.Name, proposed[t])
+			renamed = true
+		}
+	}
+	if !renamed {
+		return types, nil
+	}
+	// every type is mapped, even to itself, so only exact names are renamed.
+	renameTypes(types, names)
+	return canonicalOrder(types), nil
+}
+
+// writeLock writes the --lock file with the names of the types generated.
+func writeLock(c *config, types []*Type) error {
+	if c.lockFile == "" {
+		return nil
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	lock := lockFile{Types: []LockEntry{}}
+	for _, t := range types {
+		if t.original == "" {
+			continue
+		}
+		lock.Types = append(lock.Types, LockEntry{
+			Original: t.original,
+			Shape:    lockShape(t, byName),
+			Name:     t.Name,
+			GoName:   capitalize(t.Name),
+		})
+	}
+	sort.Slice(lock.Types, func(i, j int) bool { return lock.Types[i].Name < lock.Types[j].Name })
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding lock: %w", err)
+	}
+	return writeFile(c, c.lockFile, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\n", b)
+		return err
+	})
+}
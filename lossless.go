@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// losslessImports are the packages needed by losslessHelpers.
+var losslessImports = []string{"bytes", "encoding/json", "reflect", "sort"}
+
+// losslessHelpers is written once per file when --lossless is used, it is shared by the
+// marshalers of every type.
+const losslessHelpers = `// lacRaw remembers how an object was received so it can be written back the same way.
+type lacRaw struct {
+	data   []byte
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// lacDecodeRaw splits the JSON object in b into its keys, in order, and their raw values.
+func lacDecodeRaw(b []byte) (*lacRaw, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	r := &lacRaw{data: append([]byte(nil), b...), values: map[string]json.RawMessage{}}
+	for dec.More() {
+		kt, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		k, _ := kt.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if _, dup := r.values[k]; !dup {
+			r.keys = append(r.keys, k)
+		}
+		r.values[k] = v
+	}
+	return r, nil
+}
+
+// unknown returns the values of the keys that are not in known.
+func (r *lacRaw) unknown(known ...string) map[string]json.RawMessage {
+	if r == nil {
+		return nil
+	}
+	var result map[string]json.RawMessage
+	for _, k := range r.keys {
+		isKnown := false
+		for _, kk := range known {
+			if k == kk {
+				isKnown = true
+				break
+			}
+		}
+		if isKnown {
+			continue
+		}
+		if result == nil {
+			result = map[string]json.RawMessage{}
+		}
+		result[k] = r.values[k]
+	}
+	return result
+}
+
+// lacEncode writes current keeping the key order of raw and, for the values that are the same
+// they were in orig when received, their original bytes. Fields that were not received are only
+// written if they changed and unknown keys added since are written last.
+func lacEncode(raw *lacRaw, orig, current interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := lacDecodeRaw(b)
+	if err != nil {
+		return nil, err
+	}
+	prev := &lacRaw{}
+	if raw != nil {
+		if b, err = json.Marshal(orig); err != nil {
+			return nil, err
+		}
+		if prev, err = lacDecodeRaw(b); err != nil {
+			return nil, err
+		}
+	}
+	buf := &bytes.Buffer{}
+	written := map[string]bool{}
+	write := func(k string, v []byte) error {
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		if len(written) > 0 {
+			buf.WriteByte(',')
+		}
+		written[k] = true
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(v)
+		return nil
+	}
+	buf.WriteByte('{')
+	if raw != nil {
+		for _, k := range raw.keys {
+			v, known := cur.values[k]
+			if !known {
+				if v, ok := extra[k]; ok {
+					if err := write(k, v); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+			if bytes.Equal(v, prev.values[k]) {
+				v = raw.values[k]
+			}
+			if err := write(k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, k := range cur.keys {
+		if written[k] || (raw != nil && bytes.Equal(cur.values[k], prev.values[k])) {
+			continue
+		}
+		if err := write(k, cur.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	added := make([]string, 0, len(extra))
+	for k := range extra {
+		if !written[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	for _, k := range added {
+		if err := write(k, extra[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// lacUnchanged returns true if current is what raw decodes into.
+func lacUnchanged(raw *lacRaw, orig, current interface{}) bool {
+	return raw != nil && reflect.DeepEqual(orig, current)
+}
+
+`
+
+// writeLosslessField writes the field remembering how the type was received.
+func writeLosslessField(w io.Writer) {
+	fmt.Fprint(w, "\tlacRaw *lacRaw\n")
+}
+
+// writeLosslessMethods writes the marshalers that, along with the extra field, let the type be
+// written back the way it was received. Unchanged objects are written back byte for byte, the
+// changed ones keep the order of the keys and the bytes of the values that did not change.
+func writeLosslessMethods(w io.Writer, t *Type, structName string) {
+	n := extraFieldName(t)
+	known := make([]string, 0, len(t.Fields))
+	for _, fld := range t.Fields {
+		known = append(known, fmt.Sprintf("%q", fld.Name))
+	}
+	fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, keys that are not fields of %s are kept in %s\n", structName, n)
+	fmt.Fprint(w, "// and the source is kept so it can be written back as it was.\n")
+	fmt.Fprintf(w, "func (t *%s) UnmarshalJSON(b []byte) error {\n", structName)
+	fmt.Fprintf(w, "\ttype plain %s\n", structName)
+	fmt.Fprint(w, "\tif err := json.Unmarshal(b, (*plain)(t)); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprint(w, "\traw, err := lacDecodeRaw(b)\n\tif err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprintf(w, "\tt.%s = raw.unknown(%s)\n", n, strings.Join(known, ", "))
+	fmt.Fprint(w, "\tt.lacRaw = raw\n\treturn nil\n}\n\n")
+
+	fmt.Fprintf(w, "// MarshalJSON implements json.Marshaler, writing %s back as it was received as much as possible.\n", structName)
+	fmt.Fprintf(w, "func (t %s) MarshalJSON() ([]byte, error) {\n", structName)
+	fmt.Fprintf(w, "\ttype plain %s\n", structName)
+	fmt.Fprintf(w, "\tvar orig %s\n", structName)
+	fmt.Fprint(w, "\tif t.lacRaw != nil {\n")
+	fmt.Fprint(w, "\t\tif err := json.Unmarshal(t.lacRaw.data, &orig); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	fmt.Fprint(w, "\t\tif lacUnchanged(t.lacRaw, orig, t) {\n\t\t\treturn t.lacRaw.data, nil\n\t\t}\n\t}\n")
+	fmt.Fprintf(w, "\treturn lacEncode(t.lacRaw, plain(orig), plain(t), t.%s)\n}\n\n", n)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
-	"os"
+	"runtime"
+	"sync"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -12,21 +14,165 @@ import (
 type config struct {
 	targetFile    string
 	sourceFiles   []string
+	providers     map[string]string
 	swaggerFile   string
+	swaggerFormat string
+	// jsonSchema is set when swaggerFile is a standalone JSON Schema from --jsonschema.
+	jsonSchema bool
+	// asyncAPI is set when swaggerFile is an AsyncAPI document from --asyncapi.
+	asyncAPI bool
+	// proto is set when swaggerFile is a .proto file from --proto.
+	proto         bool
+	versions      []specVersion
+	importPath    string
 	targetPackage string
 	fileTypeMap   map[string]string
 	imports       []string
 	replaceTypes  map[string]string
 	typesForItems map[string]string
+	// scalars are the rules read from --scalar-map.
+	scalars       []scalarRule
+	decimal       string
+	durations     bool
+	netip         bool
+	pointerAbove  int
+	assertRuns    int
+	lenientJSON   bool
+	lenientBase64 bool
+	keepGoing     bool
+	// failedSources are the errors of the sources left out with --keep-going.
+	failedSources []error
+	// generated are the types written by the run, described by the results of --rpc.
+	generated     []*Type
+	timeFormat    string
+	timeLayout    string
+	descriptions  map[string]string
+	refTimeout    time.Duration
+	refOffline    bool
+	refCache      string
+	only          []string
+	roots         []string
+	skipFields    []string
+	extraTags     []string
+	envTags       bool
+	dbMode        string
+	arrayStyle    string
+	optional      string
+	duplicateKeys string
+	validatorTags bool
+	validate      bool
+	maxBytes      int64
+	maxDepth      int
+	maxComponents int
+	// requestSuffix names the request types of --split-read-only, empty when not asked for.
+	requestSuffix string
+	// snippet leaves the package and imports out of the output, see runSnippet.
+	snippet bool
+	// fixtures writes the examples of the schemas next to the target, see writeFixtures.
+	fixtures bool
+	// constructors adds the New functions filling the defaults, see writeConstructor.
+	constructors bool
+	// reuse is the package of --reuse-package, nil when not given.
+	reuse *reusePackage
+	// client writes a Client for the operations of the paths next to the target, see writeClient,
+	// and contractTests the tests calling them on a live server, see writeContractTests.
+	client        bool
+	contractTests bool
+	// server is the router of the Server written next to the target, see writeServer, and
+	// serverInterfaces whether its methods are split in interfaces per tag, see serverGroups.
+	server           string
+	serverInterfaces string
+	// unionExamples writes the examples of the unions next to the target, see writeUnionExamples.
+	unionExamples bool
+	// decode is how the decode helpers are written next to the target, empty for none. They
+	// decode with useNumber and strictDecode, see writeDecode.
+	decode       string
+	useNumber    bool
+	strictDecode bool
+	// lockFile keeps the names of the inferred types across runs, see applyLock.
+	lockFile string
+	// harFiles are the captures whose responses are samples too, see readHARSources.
+	harFiles []string
+	// overlay is where runOverlay writes the generated files instead of writing them, - for
+	// stdout and empty to write them.
+	overlay string
+	// provenance is how the file each type was generated from is written on it, see writeType.
+	provenance string
+	// trimPrefix is the directory the files written by --provenance full are relative to.
+	trimPrefix string
+	// sourceRoot is the absolute directory of trimPrefix or, without one, of the module the
+	// target is in. Empty when there is neither, the files are written as given then.
+	sourceRoot string
+	// rpc is set to serve --rpc instead of generating.
+	rpc           bool
+	extra         bool
+	lossless      bool
+	aliases       bool
+	enumUnknown   string
+	serveSpec     bool
+	enums         bool
+	gqlgen        bool
+	k8s           bool
+	jobs          int
+	verbose       bool
+	commentWidth  int
+	stripMarkdown bool
+	noDescription bool
+	descRewrites  []descRewrite
+	reportFile    string
+	// report collects the naming decisions when a reportFile is requested, nil otherwise.
+	report *renameReport
+	// sqlNullsTaken are the null types of --db sql whose name a type takes, see markSQLNulls.
+	sqlNullsTaken map[string]bool
+	// warningHandler gets all non fatal issues found during generation, nil means print them to
+	// stderr.
+	warningHandler func(warning)
+	// warnMu serializes calls to warningHandler as warnings can be raised concurrently.
+	warnMu sync.Mutex
+	// fileSystem is where files are read and written, nil means the local disk.
+	fileSystem FileSystem
 }
 
+// Styles for --array-style.
+const (
+	arrayStyleValue   = "value"
+	arrayStylePointer = "pointer"
+)
+
+// Strategies for --optional.
+const (
+	optionalPlain     = "plain"
+	optionalOmitEmpty = "omitempty"
+	optionalPointer   = "pointer"
+)
+
+// Behaviors for --duplicate-keys.
+const (
+	duplicateKeysWarn  = "warn"
+	duplicateKeysError = "error"
+)
+
+// Behaviors for --enum-unknown.
+const (
+	enumUnknownError       = "error"
+	enumUnknownUnknown     = "unknown"
+	enumUnknownPassthrough = "passthrough"
+)
+
+// Ways --provenance writes the source of the types.
+const (
+	provenanceFull     = "full"
+	provenanceFilename = "filename"
+	provenanceNone     = "none"
+)
+
 // ErrBadUsage should be raised when flags were improperly ivoked
 type ErrBadUsage struct {
 	err error
 }
 
 func (err *ErrBadUsage) Error() string {
-	return err.Error()
+	return err.err.Error()
 }
 
 func (err *ErrBadUsage) Unwrap() error {
@@ -35,80 +181,419 @@ func (err *ErrBadUsage) Unwrap() error {
 
 var _ error = &ErrBadUsage{}
 
-func parseFlags() (*config, error) {
-	c := &config{}
+// parseFlags reads the config from the command line args with flags, which can hold up to
+// arguments arguments after the flags. The files it names are read from fsys, nil meaning the
+// local disk.
+func parseFlags(flags *flag.FlagSet, args []string, arguments int, fsys FileSystem) (*config, error) {
+	c := &config{fileSystem: fsys}
 
-	flag.CommandLine.StringVar(&c.targetFile, "target", "", "path to the go file where structs will be created. If none provided stdout will be used.")
-	flag.CommandLine.StringVar(&c.targetPackage, "package", "main", "the package of the module where the structs will live.")
-	flag.CommandLine.StringVar(&c.swaggerFile, "swaggerfile", "", "path to a file containing a swagger schema json.")
-	flag.CommandLine.StringSliceVar(&c.sourceFiles, "source", []string{}, "list of files to use as source, wildcards are valid (such as *.json) but need to be quote wrapped.")
-	flag.CommandLine.StringToStringVar(&c.fileTypeMap, "structnames", map[string]string{}, "alternative struct names for types, only full matches will be replaced use either comma separated match=replacement or pass this flag multiple times, the names before capitalization are considered for the match. ie `issuetype=someotherstructname`")
-	flag.CommandLine.StringSliceVar(&c.imports, "imports", []string{}, "imports to be added")
-	flag.CommandLine.StringToStringVar(&c.replaceTypes, "replacetypes", map[string]string{}, "replace basic types with your own, only full matching with the type name is done, remember to add them to imports if they depend on external packages. ie `float64=float32`")
-	flag.CommandLine.StringToStringVar(&c.typesForItems, "typesforitems", map[string]string{}, "replace types of struct members specifying the path. ie `StructName.Member=package.CustomType` ")
+	flags.StringVar(&c.targetFile, "target", "", "path to the go file where structs will be created. If none provided stdout will be used.")
+	flags.StringVar(&c.targetPackage, "package", "main", "the package of the module where the structs will live.")
+	swaggerFiles := []string{}
+	flags.StringArrayVar(&swaggerFiles, "swaggerfile", []string{}, "path or http(s) URL of a file containing a swagger schema json, pass it multiple times as `version=path` to generate a package per version under --target plus conversion functions between them.")
+	jsonSchemaFile := ""
+	asyncAPIFile := ""
+	flags.StringVar(&asyncAPIFile, "asyncapi", "", "path or http(s) URL of an AsyncAPI 2.x document, structs are made of its schemas and of the payloads of its messages.")
+	protoFile := ""
+	flags.StringVar(&protoFile, "proto", "", "path or http(s) URL of a .proto file, structs are made of its messages as the JSON mapping of proto3 writes them, without protoc.")
+	flags.StringVar(&jsonSchemaFile, "jsonschema", "", "path or http(s) URL of a standalone draft-07 or 2020-12 JSON Schema, structs are made of its $defs, definitions and root properties.")
+	flags.StringVar(&c.swaggerFormat, "swaggerformat", swaggerFormatAuto, "format of the --swaggerfile, either `auto` (yaml for .yaml and .yml files or contents not starting with {), json or yaml.")
+	flags.StringVar(&c.importPath, "importpath", "", "import path of the --target directory, used by the conversion functions to import the version packages.")
+	flags.StringSliceVar(&c.harFiles, "har", []string{}, "HTTP Archive files saved by the devtools of browsers or by proxies, the JSON bodies of the 2xx responses are samples of a type per method and path, with the segments looking like ids as parameters. Wildcards are valid but need to be quote wrapped. ie `capture.har`")
+	flags.StringSliceVar(&c.sourceFiles, "source", []string{}, "list of files or http(s) URLs to use as source, wildcards are valid for files (such as *.json) but need to be quote wrapped.")
+	flags.StringToStringVar(&c.providers, "provider", map[string]string{}, "command reading the sources, specs and files given as scheme://location, run as \"command list location\" to print the documents there one per line and as \"command open document\" to print one. ie `s3=lac-s3`")
+	flags.BoolVar(&c.lenientJSON, "lenient-json", false, "accept comments and trailing commas in the JSON sources and specs, they are dropped before decoding.")
+	flags.BoolVar(&c.lenientBase64, "lenient-base64", false, "make the strings holding base64 a Base64 type written along the structs that reads the URL safe alphabet and unpadded values too.")
+	flags.StringVar(&c.duplicateKeys, "duplicate-keys", duplicateKeysWarn, "what to do with the keys repeated in an object of the sources, either `warn` and keep the last value or error.")
+	flags.BoolVar(&c.validate, "validate", false, "generate Validate methods checking the minimum, maximum, length, pattern, enum, not, propertyNames, minProperties, maxProperties and required properties of the fields, and the Validate of the types they hold, with no dependencies.")
+	flags.BoolVar(&c.validatorTags, "validator-tags", false, "add go-playground/validator validate tags for the minimum, maximum, length, pattern and enum of the fields.")
+	flags.Int64Var(&c.maxBytes, "max-bytes", defaultMaxBytes, "fail for sources and specs larger than this many bytes, YAML ones once converted to JSON, 0 never does.")
+	flags.IntVar(&c.maxDepth, "max-depth", defaultMaxDepth, "fail for sources and specs nesting objects and arrays deeper than this, 0 never does.")
+	flags.IntVar(&c.maxComponents, "max-components", defaultMaxComponents, "fail for specs declaring more schemas than this, 0 never does.")
+	flags.BoolVar(&c.rpc, "rpc", false, "serve the generation as JSON-RPC 2.0 on stdin and stdout, with messages framed by a Content-Length header as in the Language Server Protocol, for editor integrations. The other flags are passed with each request.")
+	flags.StringVar(&c.requestSuffix, "split-read-only", "", "generate a request type without the readOnly properties for the schemas having them, and the ones referencing those, named after the schema with this suffix, given after an =. Without a suffix "+defaultRequestSuffix+" is used. ie --split-read-only=`Create`")
+	flags.Lookup("split-read-only").NoOptDefVal = defaultRequestSuffix
+	reusePackageDir := ""
+	flags.StringVar(&reusePackageDir, "reuse-package", "", "directory of a Go package whose structs and enums are referenced instead of generating the types with their shape, the json keys and field types or the enum values. The import path is found in the go.mod above it or given after an =. ie `./models`")
+	flags.BoolVar(&c.constructors, "constructors", false, "generate for the structs with defaults a NewType function taking the required fields, in order, and filling the rest of the fields with their defaults.")
+	flags.StringVar(&c.lockFile, "lock", "", "keep in this file the names given to the types inferred from the samples, by the key they were found under and their shape, so regenerating after the samples change gives them the same names, given after an =. Without a file lac.lock is used. ie --lock=`lac.lock`")
+	flags.Lookup("lock").NoOptDefVal = defaultLockFile
+	flags.BoolVar(&c.client, "client", false, "write next to the --target, in a file ending in _client.go, a Client with a method for each operation of the paths taking the generated request types and returning the response ones, sent to the first of the servers by default.")
+	flags.BoolVar(&c.contractTests, "contract-tests", false, "write next to the --target, in a file ending in _contract_test.go, a test calling each operation of the --client on the server at the URL in LAC_CONTRACT_URL and checking it answers with the statuses and bodies of the spec, the operations other than GET, HEAD and OPTIONS only with LAC_CONTRACT_UNSAFE set.")
+	flags.StringVar(&c.server, "server", "", "write next to the --target, in a file ending in _server.go, a Server interface with a method for each operation of the paths taking the generated request types and returning the response ones, and a RegisterHandlers function routing the requests to it with a http.ServeMux or the router of chi, echo or gin, given after an =. Without a router "+serverServeMux+" is used. ie --server=`gin`")
+	flags.Lookup("server").NoOptDefVal = serverServeMux
+	flags.StringVar(&c.serverInterfaces, "server-interfaces", serverInterfacesSingle, "how the methods of the --server are written, either single (a Server interface with all of them) or `per-tag` (an interface per tag of the operations, embedded by Server, with a function registering its handlers alone).")
+	flags.StringVar(&c.provenance, "provenance", provenanceFull, "how the file each type was generated from is written in its comment, either `full` (the path as given), filename (only the name of the file) or none, so paths to the home of whoever generated the code are not committed.")
+	flags.StringVar(&c.trimPrefix, "trim-prefix", "", "directory the files written by --provenance full are relative to, instead of the root of the module the --target is in, so the comments are the same on every machine. URLs have it removed when they start with it. ie `/home/me/api`")
+	flags.StringVar(&c.overlay, "overlay", "", "write nothing but a json object mapping the absolute paths of the files that would be generated to their contents to this file, the Overlay of golang.org/x/tools/go/packages, for tools type-checking against the code that would be generated, given after an =. Without a file it is written to stdout. ie --overlay=`overlay.json`")
+	flags.Lookup("overlay").NoOptDefVal = "-"
+	flags.BoolVar(&c.unionExamples, "union-examples", false, "write next to the --target, in a file ending in _example_test.go, an Example for each oneOf with a discriminator decoding it and switching on the variants it can hold.")
+	flags.StringVar(&c.decode, "decode", "", "write next to the --target, in a file ending in _decode.go, the functions every consumer of the package can decode its types with alike, either `generic` (a Decode[T any], which needs Go 1.18) or types (a DecodeType for each type), given after an =. Without a value "+decodeGeneric+" is used. ie --decode=types")
+	flags.Lookup("decode").NoOptDefVal = decodeGeneric
+	flags.BoolVar(&c.useNumber, "use-number", false, "make the --decode functions keep the numbers held by interface{} values as json.Number instead of float64.")
+	flags.BoolVar(&c.strictDecode, "strict-decode", false, "make the --decode functions fail for documents with keys the types do not know.")
+	flags.BoolVar(&c.fixtures, "fixtures", false, "write next to the --target, in a file ending in _fixtures.go, a function returning each type holding the example of its schema, made of the examples of its properties when it has none, for tests.")
+	flags.BoolVar(&c.keepGoing, "keep-going", false, "leave out the sources that can not be read, generating the rest, and list them at the end failing the run.")
+	flags.StringToStringVar(&c.fileTypeMap, "structnames", map[string]string{}, "alternative struct names for types, only full matches will be replaced use either comma separated match=replacement or pass this flag multiple times, the names before capitalization are considered for the match. ie `issuetype=someotherstructname`")
+	flags.StringSliceVar(&c.imports, "imports", []string{}, "imports to be added")
+	flags.StringToStringVar(&c.replaceTypes, "replacetypes", map[string]string{}, "replace basic types with your own, only full matching with the type name is done, remember to add them to imports if they depend on external packages. ie `float64=float32`")
+	flags.StringSliceVar(&c.extraTags, "tags", []string{}, "extra struct tags to add to every field using the same key as json, ie `mapstructure,koanf` for structs loaded with Viper or koanf.")
+	flags.StringVar(&c.dbMode, "db", "", "add db tags for sqlc/scany and map nullable fields to null types, either `sql` (database/sql) or pgx (pgx v5 pgtype).")
+	flags.StringVar(&c.arrayStyle, "array-style", arrayStyleValue, "how arrays of structs are generated, either `value` ([]T) or pointer ([]*T).")
+	flags.StringVar(&c.optional, "optional", optionalPlain, "how the fields of properties that are not required are generated, either `plain`, omitempty (tagged ,omitempty) or pointer (pointers tagged ,omitempty).")
+	flags.BoolVar(&c.extra, "extra", false, "generate UnmarshalJSON methods that keep the keys not known to the struct in an Extra map[string]json.RawMessage field.")
+	flags.BoolVar(&c.lossless, "lossless", false, "generate marshalers that write payloads back as they were received, keeping key order, unknown keys and the bytes of unchanged values, implies --extra.")
+	flags.BoolVar(&c.aliases, "aliases", false, "compare with the current --target and add deprecated aliases for the types that were renamed, they are dropped on the next generation.")
+	flags.BoolVar(&c.serveSpec, "serve-spec", false, "add the spec to the generated code along with an OpenAPIHandler serving it at /openapi.json and its documentation at /docs.")
+	flags.BoolVar(&c.enums, "enums", false, "generate string enum types with constants for schema enums instead of plain strings.")
+	flags.StringVar(&c.enumUnknown, "enum-unknown", "", "add an Unknown zero value to enums and decide what unmarshaling values that are not known does, either `error`, unknown (they become Unknown) or passthrough (they are kept), implies --enums.")
+	flags.BoolVar(&c.gqlgen, "gqlgen", false, "generate gqlgen compatible models, nullable fields become pointers and enums get MarshalGQL/UnmarshalGQL, implies --enums.")
+	flags.BoolVar(&c.k8s, "k8s", false, "generate kubebuilder validation markers from the schema constraints and DeepCopy methods, types with apiVersion and kind become runtime.Object with a List type.")
+	flags.StringVar(&c.decimal, "decimal", "", "type for monetary fields, the ones with format decimal or named like price, amount or total, instead of float64, given after an =. Without a type "+defaultDecimal+" is used. ie --decimal=`github.com/shopspring/decimal.Decimal`")
+	flags.Lookup("decimal").NoOptDefVal = defaultDecimal
+	flags.BoolVar(&c.durations, "durations", false, "turn strings with format duration, or holding durations like 1h15m in samples, into a Duration type written along the structs that converts to time.Duration and keeps the string form in JSON.")
+	flags.BoolVar(&c.netip, "netip", false, "turn strings with format ipv4, ipv6 or cidr, or holding addresses and prefixes in samples, into net/netip.Addr and netip.Prefix.")
+	flags.StringVar(&c.timeFormat, "timeformat", timeFormatRFC3339, "how strings with format date-time are read, `rfc3339` makes them time.Time, none keeps date and date-time as strings, and the name of a layout of the time package, ie RFC1123, or a layout makes them a Timestamp type written along the structs.")
+	flags.IntVar(&c.pointerAbove, "pointer-above-bytes", 0, "make struct fields pointers when their type is estimated to take more than this many bytes, 0 never does.")
+	flags.DurationVar(&c.refTimeout, "ref-timeout", 30*time.Second, "how long fetching each document referenced by URL can take, 0 waits forever.")
+	flags.BoolVar(&c.refOffline, "ref-offline", false, "read the documents referenced by URL from --ref-cache instead of fetching them, failing for the ones never fetched.")
+	flags.StringVar(&c.refCache, "ref-cache", "", "directory where the documents referenced by URL are cached, by default LAC/refs under the cache directory of the user.")
+	flags.BoolVar(&c.envTags, "envtags", false, "add caarlos0/env style env tags named after the field path, nested structs get an envPrefix.")
+	flags.IntVar(&c.jobs, "jobs", runtime.NumCPU(), "how many files or swagger components to process in parallel.")
+	flags.StringVar(&c.reportFile, "report", "", "path to a json file where the mapping of source names to Go identifiers, and the collisions found, will be written.")
+	flags.IntVar(&c.commentWidth, "comment-width", 100, "column at which description comments are wrapped, 0 disables wrapping.")
+	flags.BoolVar(&c.stripMarkdown, "strip-markdown", false, "turn markdown in descriptions into plain text for the comments.")
+	flags.BoolVar(&c.noDescription, "no-descriptions", false, "do not add the descriptions of the source as comments.")
+	linkPrefixes := []string{}
+	flags.StringArrayVar(&linkPrefixes, "link-prefix", []string{}, "rewrite links and paths in descriptions starting with old to start with new, can be passed multiple times. ie `/docs/=https://portal.example.com/docs/`")
+	rewrites := []string{}
+	flags.StringArrayVar(&rewrites, "desc-rewrite", []string{}, "sed like regex replacement applied to descriptions, the first character is the delimiter, can be passed multiple times. ie `|see (\\S+)|see https://portal.example.com$1|`")
+	flags.IntVar(&c.assertRuns, "assert-deterministic", 0, "generate this many times before writing the output and fail if any run differs, to catch nondeterministic naming.")
+	flags.BoolVar(&c.verbose, "verbose", false, "print progress information while generating.")
+	flags.StringSliceVar(&c.only, "only", []string{}, "only generate these types, by source or Go name, swagger components not listed are not even processed. ie `Component1,Component2`")
+	flags.StringSliceVar(&c.roots, "root", []string{}, "only generate these types and the ones they reference, recursively. ie `User`")
+	flags.StringSliceVar(&c.skipFields, "skip-field", []string{}, "struct members to leave out specifying the path, can be passed multiple times. ie `StructName.Member`")
+	flags.StringToStringVar(&c.typesForItems, "typesforitems", map[string]string{}, "replace types of struct members specifying the path, parts can use wildcards and arrays are followed with []. ie `StructName.Member=package.CustomType` ")
+	descriptionsFile := ""
+	flags.StringVar(&descriptionsFile, "descriptions", "", "path to a yaml file mapping type names, as in the source or in Go, to the description added to their comment, replacing the one of the source. ie `descriptions.yaml`")
+	scalarMapFile := ""
+	flags.StringVar(&scalarMapFile, "scalar-map", "", "path to a yaml file mapping schema types and formats, or field patterns, to fully qualified Go types, their packages are imported. ie `scalars.yaml`")
+	codes := map[string]string{}
+	flags.StringToStringVar(&codes, "codes", map[string]string{}, "fully qualified Go types for the strings with the formats of currency (ISO 4217), country (ISO 3166) or language (ISO 639, BCP 47) codes. ie `language=golang.org/x/text/language.Tag`")
 
-	if err := flag.CommandLine.Parse(os.Args); err != nil {
+	if err := flags.Parse(args); err != nil {
+		return nil, &ErrBadUsage{err: err}
+	}
+	// the arguments left are usually the values of flags with an optional one given as
+	// --server gin instead of --server=gin.
+	if flags.NArg() > arguments {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unexpected argument %q, flags with an optional value take it after an =, ie --server=gin", flags.Arg(arguments))}
+	}
+	if c.gqlgen || c.enumUnknown != "" {
+		c.enums = true
+	}
+	if c.lossless {
+		c.extra = true
+	}
+	if err := parseSwaggerFiles(c, swaggerFiles); err != nil {
 		return nil, &ErrBadUsage{err: err}
 	}
+	if jsonSchemaFile != "" {
+		if len(swaggerFiles) > 0 {
+			return nil, &ErrBadUsage{err: fmt.Errorf("--jsonschema and --swaggerfile can not be used together")}
+		}
+		// the schema is read like a swagger file, only the types are found elsewhere.
+		c.swaggerFile = jsonSchemaFile
+		c.jsonSchema = true
+	}
+	if asyncAPIFile != "" {
+		if len(swaggerFiles) > 0 || jsonSchemaFile != "" {
+			return nil, &ErrBadUsage{err: fmt.Errorf("--asyncapi can not be used with --swaggerfile or --jsonschema")}
+		}
+		// the document is read like a swagger file, only the types are found elsewhere.
+		c.swaggerFile = asyncAPIFile
+		c.asyncAPI = true
+	}
+	if protoFile != "" {
+		if len(swaggerFiles) > 0 || jsonSchemaFile != "" || asyncAPIFile != "" {
+			return nil, &ErrBadUsage{err: fmt.Errorf("--proto can not be used with --swaggerfile, --jsonschema or --asyncapi")}
+		}
+		// the file is read like a swagger file, only the types are found elsewhere.
+		c.swaggerFile = protoFile
+		c.proto = true
+	}
+	if len(c.harFiles) > 0 && (len(c.swaggerFile) != 0 || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--har makes types of samples, it can not be used with --swaggerfile, --jsonschema, --asyncapi or --proto")}
+	}
+	// prefixes are applied first so the regular expressions see the final links.
+	for _, lp := range linkPrefixes {
+		r, err := parseLinkPrefix(lp)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.descRewrites = append(c.descRewrites, r)
+	}
+	for _, rw := range rewrites {
+		r, err := parseDescRewrite(rw)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.descRewrites = append(c.descRewrites, r)
+	}
+	if c.timeFormat != timeFormatRFC3339 && c.timeFormat != timeFormatNone {
+		layout, err := timeLayout(c.timeFormat)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.timeLayout = layout
+	}
+	if descriptionsFile != "" {
+		descriptions, err := readDescriptions(c, descriptionsFile)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.descriptions = descriptions
+	}
+	if scalarMapFile != "" {
+		scalars, err := readScalarMap(c, scalarMapFile)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.scalars = scalars
+	}
+	// the scalar map is more precise so it goes first.
+	rules, err := codeRules(codes)
+	if err != nil {
+		return nil, &ErrBadUsage{err: err}
+	}
+	c.scalars = append(c.scalars, rules...)
+	if c.reportFile != "" {
+		c.report = newRenameReport()
+	}
+	if c.serveSpec && ((c.swaggerFile == "" && len(c.versions) == 0) || c.jsonSchema || c.asyncAPI || c.proto) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--serve-spec needs a --swaggerfile")}
+	}
+	if c.fixtures && (c.targetFile == "" || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--fixtures needs a --target file to write them next to")}
+	}
+	if c.unionExamples && (c.targetFile == "" || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--union-examples needs a --target file to write them next to")}
+	}
+	if c.decode != "" && c.decode != decodeGeneric && c.decode != decodeTypes {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown decode %q, either %s or %s", c.decode, decodeGeneric, decodeTypes)}
+	}
+	if c.decode != "" && (c.targetFile == "" || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--decode needs a --target file to write it next to")}
+	}
+	if (c.useNumber || c.strictDecode) && c.decode == "" {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--use-number and --strict-decode are settings of the --decode functions")}
+	}
+	if c.strictDecode && c.extra {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--strict-decode can not be used with --extra or --lossless, they keep the keys the types do not know")}
+	}
+	if c.client && (c.targetFile == "" || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--client needs a --target file to write it next to")}
+	}
+	if c.client && (len(c.swaggerFile) == 0 || c.jsonSchema || c.asyncAPI || c.proto) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--client needs the paths of a --swaggerfile")}
+	}
+	if c.contractTests && !c.client {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--contract-tests calls the operations with the --client")}
+	}
+	if _, ok := routerImports[c.server]; c.server != "" && c.server != serverServeMux && !ok {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown router %q, either %s, %s, %s or %s", c.server, serverServeMux, serverChi, serverEcho, serverGin)}
+	}
+	if c.serverInterfaces != serverInterfacesSingle && c.serverInterfaces != serverInterfacesPerTag {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown server interfaces %q, either %s or %s", c.serverInterfaces, serverInterfacesSingle, serverInterfacesPerTag)}
+	}
+	if c.serverInterfaces != serverInterfacesSingle && c.server == "" {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--server-interfaces is a setting of the --server")}
+	}
+	if c.server != "" && (c.targetFile == "" || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--server needs a --target file to write it next to")}
+	}
+	if c.server != "" && (len(c.swaggerFile) == 0 || c.jsonSchema || c.asyncAPI || c.proto) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--server needs the paths of a --swaggerfile")}
+	}
+	if c.overlay != "" && (c.targetFile == "" || c.rpc) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--overlay needs a --target, the files are keyed by where they would be written")}
+	}
+	if c.lockFile != "" && (len(c.swaggerFile) != 0 || len(c.versions) > 0) {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--lock only applies to --source, the names of specs are their own")}
+	}
+	if reusePackageDir != "" {
+		if len(c.versions) > 0 {
+			return nil, &ErrBadUsage{err: fmt.Errorf("--reuse-package can not be used with versions")}
+		}
+		rp, err := readReusePackage(c, reusePackageDir)
+		if err != nil {
+			return nil, &ErrBadUsage{err: err}
+		}
+		c.reuse = rp
+	}
+	if c.assertRuns > 0 && len(c.versions) > 0 {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--assert-deterministic can not be used with versions")}
+	}
+	if c.arrayStyle != arrayStyleValue && c.arrayStyle != arrayStylePointer {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown array style %q", c.arrayStyle)}
+	}
+	if c.optional != optionalPlain && c.optional != optionalOmitEmpty && c.optional != optionalPointer {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown optional strategy %q", c.optional)}
+	}
+	if c.duplicateKeys != duplicateKeysWarn && c.duplicateKeys != duplicateKeysError {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown duplicate keys behavior %q", c.duplicateKeys)}
+	}
+	switch c.swaggerFormat {
+	case swaggerFormatAuto, swaggerFormatJSON, swaggerFormatYAML:
+	default:
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown swagger format %q", c.swaggerFormat)}
+	}
+	switch c.provenance {
+	case provenanceFull, provenanceFilename, provenanceNone:
+	default:
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown provenance %q, either %s, %s or %s", c.provenance, provenanceFull, provenanceFilename, provenanceNone)}
+	}
+	if c.trimPrefix != "" && c.provenance != provenanceFull {
+		return nil, &ErrBadUsage{err: fmt.Errorf("--trim-prefix only applies to --provenance %s", provenanceFull)}
+	}
+	if c.provenance == provenanceFull {
+		c.sourceRoot = provenanceRoot(c)
+	}
+	switch c.enumUnknown {
+	case "", enumUnknownError, enumUnknownUnknown, enumUnknownPassthrough:
+	default:
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown enum behavior %q", c.enumUnknown)}
+	}
+	if _, ok := dbNullTypes[c.dbMode]; c.dbMode != "" && !ok {
+		return nil, &ErrBadUsage{err: fmt.Errorf("unknown db mode %q", c.dbMode)}
+	}
 	return c, nil
 }
 
-func main() {
-	if err := realMain(); err != nil {
-		fmt.Printf("FAILED: %v\n", err)
-		var badUsage *ErrBadUsage
-		if errors.As(err, &badUsage) {
-			os.Exit(2)
+// run generates what c asks for, writing the code to stdout when there is no --target.
+func run(ctx context.Context, c *config, stdout io.Writer) error {
+	if len(c.versions) > 0 {
+		// each version is generated into its own package, which also takes care of the output.
+		if err := generateVersions(ctx, c); err != nil {
+			return fmt.Errorf("generating versions: %w", err)
 		}
-		os.Exit(1)
+		return writeReport(c)
 	}
-}
-
-func realMain() error {
-	c, err := parseFlags()
+	if c.assertRuns > 0 {
+		if err := assertDeterministic(ctx, c, c.assertRuns); err != nil {
+			return err
+		}
+	}
+	types, err := readTypes(ctx, c)
 	if err != nil {
-		return fmt.Errorf("flags step: %w", err)
+		return err
 	}
-	// the type structure
-	var ts map[string]map[string]maybeType
-	// the outer type names
-	var tns = map[string]string{}
-	// extra comments to be added to the type definitions
-	var extraComments = map[string]string{}
+	types = reuseTypes(c, types)
+	c.report.finish(c, types)
+	// the previous generation has to be read before it is overwritten.
+	aliases := previousAliases(c, c.targetFile, types)
+	generate := func(out io.Writer) error {
+		if err := makeMeCode(ctx, c, types, out); err != nil {
+			return fmt.Errorf("generating code: %w", err)
+		}
+		c.generated = types
+		return writeAliases(out, aliases)
+	}
+	if c.targetFile != "" {
+		// the target is only replaced once the code is complete, see writeFile.
+		if err := writeFile(c, c.targetFile, generate); err != nil {
+			return err
+		}
+	} else if err := generate(stdout); err != nil {
+		return err
+	}
+	if c.fixtures {
+		if err := writeFixtures(c, types); err != nil {
+			return fmt.Errorf("generating fixtures: %w", err)
+		}
+	}
+	if c.decode != "" {
+		if err := writeDecode(c, types); err != nil {
+			return fmt.Errorf("generating decode: %w", err)
+		}
+	}
+	if c.unionExamples {
+		if err := writeUnionExamples(c, types); err != nil {
+			return fmt.Errorf("generating union examples: %w", err)
+		}
+	}
+	if c.client {
+		if err := writeClient(ctx, c, types); err != nil {
+			return fmt.Errorf("generating client: %w", err)
+		}
+	}
+	if c.server != "" {
+		if err := writeServer(ctx, c, types); err != nil {
+			return fmt.Errorf("generating server: %w", err)
+		}
+	}
+	if err := writeLock(c, types); err != nil {
+		return fmt.Errorf("writing lock: %w", err)
+	}
+	if err := writeReport(c); err != nil {
+		return err
+	}
+	return failedSourcesError(c)
+}
 
+// readTypes returns the types of the swagger file, JSON Schema or samples, filtered as asked.
+func readTypes(ctx context.Context, c *config) ([]*Type, error) {
+	var (
+		types []*Type
+		err   error
+	)
 	if len(c.swaggerFile) != 0 {
 		// swagger files, at least the ones I tried, return types with sane names to avoid needing
 		// outer name correction but also return comments from their types description.
-		// Schemas can be converted straight into the rendereable map since there is no guessing
+		// Schemas can be converted straight into types since there is no guessing
 		// happening so no intermediat format needed.
-		ts, extraComments, err = schemaIntoMap(c)
+		switch {
+		case c.jsonSchema:
+			types, err = jsonSchemaIntoTypes(ctx, c)
+		case c.asyncAPI:
+			types, err = asyncAPIIntoTypes(ctx, c)
+		case c.proto:
+			types, err = protoIntoTypes(ctx, c)
+		default:
+			types, err = schemaIntoTypes(ctx, c)
+		}
 		if err != nil {
-			return fmt.Errorf("reading swagger file into maps: %w", err)
+			return nil, fmt.Errorf("reading swagger file into types: %w", err)
 		}
 	} else {
-		// JSON will need the extra tns map that contains outer names, these are used to name
-		// the outer most types basede on input file names.
-		// jsonIntoMap creates an intermediat format from the .json files so we can then
+		// JSON types are named after their input files when they are the outer most ones.
+		// readJSONSources creates an intermediat format from the .json files so we can then
 		// resolve the types from it.
-		m, err := jsonIntoMap(c)
+		srcs, err := readJSONSources(ctx, c)
 		if err != nil {
-			return fmt.Errorf("reading files into maps: %w", err)
+			return nil, fmt.Errorf("reading files: %w", err)
 		}
-		ts, tns, err = typesFromMap(c, m)
+		captured, err := readHARSources(ctx, c)
 		if err != nil {
-			return fmt.Errorf("crafting types: %w", err)
+			return nil, fmt.Errorf("reading captures: %w", err)
 		}
-	}
-	var out io.Writer
-	if c.targetFile != "" {
-		f, err := os.Create(c.targetFile)
+		srcs = append(srcs, captured...)
+		types, err = typesFromMap(ctx, c, srcs)
 		if err != nil {
-			return fmt.Errorf("creating output file: %w", err)
+			return nil, fmt.Errorf("crafting types: %w", err)
 		}
-		out = f
-	} else {
-		out = os.Stdout
+		if types, err = applyLock(c, types); err != nil {
+			return nil, err
+		}
+	}
+	applyDescriptions(c, types)
+	return filterTypes(c, types), nil
+}
+
+// writeReport writes the rename report if one was asked for.
+func writeReport(c *config) error {
+	if c.report == nil {
+		return nil
 	}
-	makeMeCode(c, ts, tns, extraComments, out)
-	return nil
+	return c.report.write(c, c.reportFile)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"syscall/js"
+)
+
+// main makes lacGenerate available to the page and waits for it to be called, in the browser
+// there is no command line nor disk so both are passed to it.
+func main() {
+	js.Global().Set("lacGenerate", js.FuncOf(generateJS))
+	select {}
+}
+
+// generateJS runs LAC with the arguments in the array of strings passed first over the files of
+// the object passed second, which maps names to contents. It returns a promise of the
+// GenerateResult of the run, as --rpc answers it.
+func generateJS(this js.Value, args []js.Value) interface{} {
+	var (
+		argv  []string
+		files = map[string]string{}
+	)
+	if len(args) > 0 && args[0].Truthy() {
+		for i := 0; i < args[0].Length(); i++ {
+			argv = append(argv, args[0].Index(i).String())
+		}
+	}
+	if len(args) > 1 && args[1].Truthy() {
+		names := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < names.Length(); i++ {
+			name := names.Index(i).String()
+			files[name] = args[1].Get(name).String()
+		}
+	}
+	var executor js.Func
+	executor = js.FuncOf(func(this js.Value, p []js.Value) interface{} {
+		resolve, reject := p[0], p[1]
+		// fetching remote documents blocks, which can not be done in the calling goroutine.
+		go func() {
+			defer executor.Release()
+			result, err := generateInMemory(context.Background(), argv, files)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			b, err := json.Marshal(result)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(js.Global().Get("JSON").Call("parse", string(b)))
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
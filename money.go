@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultDecimal is the type of monetary fields when --decimal is passed without one.
+const defaultDecimal = "github.com/shopspring/decimal.Decimal"
+
+// moneyWords are the words in field names that make them monetary, total only counts as the last
+// word since total_count and the like are not money.
+var moneyWords = map[string]bool{
+	"price":    true,
+	"amount":   true,
+	"cost":     true,
+	"fee":      true,
+	"balance":  true,
+	"subtotal": true,
+	"tax":      true,
+}
+
+// decimalString matches the strings samples use for decimals, ie "19.99".
+var decimalString = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// isMoneyName returns true for field names like price, unitPrice or order_total.
+func isMoneyName(name string) bool {
+	words := strings.FieldsFunc(normalizeNames(name, ""), func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 {
+		return false
+	}
+	// plurals count too, fees are money.
+	for _, w := range words {
+		if moneyWords[w] || moneyWords[strings.TrimSuffix(w, "s")] {
+			return true
+		}
+	}
+	return strings.TrimSuffix(words[len(words)-1], "s") == "total"
+}
+
+// isSchemaDecimal returns true for the schemas of monetary fields, the ones with format decimal
+// and the numbers with a monetary name.
+func isSchemaDecimal(name string, t SwaggerType, format string) bool {
+	if format == "decimal" {
+		return t == STString || t == STNumber
+	}
+	return t == STNumber && isMoneyName(name)
+}
+
+// isSampleDecimal returns true for the values of monetary fields in samples, numbers and strings
+// holding decimals under a monetary name.
+func isSampleDecimal(name string, v interface{}) bool {
+	if !isMoneyName(name) {
+		return false
+	}
+	switch s := v.(type) {
+	case float64:
+		return true
+	case string:
+		return decimalString.MatchString(s)
+	}
+	return false
+}
+
+// decimalFor returns the type and import of a monetary field, from --decimal.
+func decimalFor(c *config, f *maybeType) (string, string, bool) {
+	if c.decimal == "" || !f.decimal {
+		return "", "", false
+	}
+	tn, pkg := qualifiedType(c.decimal)
+	if f.isArray {
+		tn = "[]" + tn
+	}
+	return tn, pkg, true
+}
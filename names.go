@@ -0,0 +1,43 @@
+package main
+
+import "sync"
+
+// memoLimit is how many results a memo holds before it is emptied, the memos live as long as the
+// process and --rpc or wasm generate many times over in one, so they can not grow forever.
+const memoLimit = 1 << 16
+
+// memo caches the results of a pure string transformation, names are transformed over and over
+// on big specs so this saves most of the string churn. It is safe for concurrent use and holds
+// up to memoLimit results.
+type memo struct {
+	mu sync.RWMutex
+	m  map[string]string
+}
+
+func newMemo() *memo {
+	return &memo{m: map[string]string{}}
+}
+
+// get returns the cached value for key, calling compute to fill it the first time.
+func (m *memo) get(key string, compute func() string) string {
+	m.mu.RLock()
+	v, ok := m.m[key]
+	m.mu.RUnlock()
+	if ok {
+		return v
+	}
+	v = compute()
+	m.mu.Lock()
+	if len(m.m) >= memoLimit {
+		// the results are cheap to compute again, the ones still in use will soon be back.
+		m.m = map[string]string{}
+	}
+	m.m[key] = v
+	m.mu.Unlock()
+	return v
+}
+
+var (
+	capitalizeMemo = newMemo()
+	normalizeMemo  = newMemo()
+)
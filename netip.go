@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net"
+)
+
+// netipImport is the package of the types --netip maps addresses to, they implement
+// encoding.TextMarshaler so they are read and written as strings.
+const netipImport = "net/netip"
+
+// Types of netipImport.
+const (
+	netipAddr   = "netip.Addr"
+	netipPrefix = "netip.Prefix"
+)
+
+// schemaNetIP returns the netip type for strings with an IP or CIDR format, or "" for the rest.
+func schemaNetIP(t SwaggerType, format string) string {
+	if t != STString {
+		return ""
+	}
+	switch format {
+	case "ipv4", "ipv6", "ip":
+		return netipAddr
+	case "cidr", "ipv4-cidr", "ipv6-cidr":
+		return netipPrefix
+	}
+	return ""
+}
+
+// sampleNetIP returns the netip type for sample values holding an IP or a CIDR, or "" for the
+// rest.
+func sampleNetIP(v interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		return ""
+	}
+	if net.ParseIP(s) != nil {
+		return netipAddr
+	}
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return netipPrefix
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// normalizeTypeArrays rewrites the JSON Schema form of nullable types, type: [string, null], into
+// the OpenAPI one, type: string and nullable: true, which is what the schemas are decoded as.
+// Types listing several other types can not be held by one Go type so they are dropped.
+func normalizeTypeArrays(ctx context.Context, c *config, spec []byte) ([]byte, error) {
+	if !bytes.Contains(spec, []byte(`"type":[`)) && !bytes.Contains(spec, []byte(`"type": [`)) {
+		return spec, nil
+	}
+	doc, err := decodeDocument(spec)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", c.swaggerFile, err)
+	}
+	if !normalizeTypes(c, doc, "#") {
+		return spec, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeDocument(buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeTypes rewrites the type arrays found in v, at pointer, and returns true if any was.
+func normalizeTypes(c *config, v interface{}, pointer string) bool {
+	changed := false
+	switch n := v.(type) {
+	case *jsonObject:
+		if types, ok := n.get("type").([]interface{}); ok {
+			normalizeType(c, n, types, pointer)
+			changed = true
+		}
+		for i, k := range n.keys {
+			changed = normalizeTypes(c, n.values[i], pointer+"/"+k) || changed
+		}
+	case []interface{}:
+		for i, e := range n {
+			changed = normalizeTypes(c, e, fmt.Sprintf("%s/%d", pointer, i)) || changed
+		}
+	}
+	return changed
+}
+
+// normalizeType replaces the type array types of the schema o.
+func normalizeType(c *config, o *jsonObject, types []interface{}, pointer string) {
+	var others []string
+	nullable := false
+	for _, t := range types {
+		s, _ := t.(string)
+		if s == "null" {
+			nullable = true
+			continue
+		}
+		others = append(others, s)
+	}
+	if nullable {
+		o.set("nullable", true)
+	}
+	if len(others) == 1 {
+		o.set("type", others[0])
+		return
+	}
+	if len(others) > 1 {
+		c.warn(pointer, "the schema can be any of %s, it is held in an interface{}", strings.Join(others, ", "))
+	}
+	o.remove("type")
+}
+
+// canBeNil returns true for the Go types that have nil as a value.
+func canBeNil(tn string) bool {
+	return strings.HasPrefix(tn, "[]") || strings.HasPrefix(tn, "map[") || tn == "interface{}" ||
+		tn == "json.RawMessage"
+}
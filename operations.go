@@ -0,0 +1,650 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// apiParam is a parameter of an operation as the client sends it and the server reads it.
+type apiParam struct {
+	// name is the one of the parameter in the spec, goName the one it has in the method, or in
+	// the Params struct for the query and header ones.
+	name, goName string
+	in           string
+	goType       string
+	required     bool
+	// constraints are the validation keywords of the schema of the parameter, which the server
+	// checks.
+	constraints *constraints
+	// style and explode say how query parameters holding arrays and objects are written, object
+	// is set for the latter.
+	style   string
+	explode bool
+	object  bool
+	// example is the JSON of the example of the parameter, or of its default, which the contract
+	// tests send.
+	example json.RawMessage
+}
+
+// repeated says whether the query parameter p is written as its key repeated for each value, as
+// scalars and the arrays of the form style exploded are, rather than as its style says.
+func (p apiParam) repeated() bool {
+	return !p.object && ((p.style == "form" && p.explode) || !strings.HasPrefix(p.goType, "[]"))
+}
+
+// apiOperation is a method of the client and of the Server interface.
+type apiOperation struct {
+	name, method, path, summary string
+	pathParams, params          []apiParam
+	// tag is the first of the tags of the operation, which --server-interfaces=per-tag groups it
+	// by.
+	tag string
+	// statuses are the ones of all its responses, in order.
+	statuses []string
+	// body and result are the Go types of the request and response bodies, empty when there
+	// are none, and status the one of the response.
+	body, result string
+	status       int
+	// bodies are the content types the client sends the request body as, the first one is the
+	// one of body and is sent by the method named after the operation. Read for the client alone.
+	bodies []apiBody
+	// errors are the responses outside 2xx with a JSON body, read for the client alone.
+	errors []apiError
+	// responses are all the responses, read for the server alone.
+	responses []apiResponse
+}
+
+// apiResponse is a response of an operation the server can answer with.
+type apiResponse struct {
+	// status is the one of the spec, a code, a range such as 4XX or default.
+	status string
+	// body is the Go type of the body, empty when it has none or it is not JSON, and mediaType
+	// the one it is written with.
+	body, mediaType string
+}
+
+// apiBody is a content type the client sends the request body of an operation as.
+type apiBody struct {
+	// mediaType is the one of the spec, goType the Go type of the body sent as it and suffix the
+	// one of the name of the method sending it, empty for the first one.
+	mediaType, goType, suffix string
+}
+
+// apiError is a response of an operation outside 2xx with a JSON body, which the client returns
+// as an error holding it.
+type apiError struct {
+	// status is the one of the spec, a code, a range such as 4XX or default.
+	status string
+	// body is the Go type of the body, typeName the one of the error holding it.
+	body, typeName string
+}
+
+// pathTemplate matches the parameters of a path.
+var pathTemplate = regexp.MustCompile(`\{([^}]+)\}`)
+
+// nonIdentifier matches what can not be part of a method name made up of a path.
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// operationReader holds what the operations of the spec are read with.
+type operationReader struct {
+	c *config
+	// what is the client or the server, in the warnings about what they leave out.
+	what string
+	spec *SwaggerSimplification
+	// types holds the Go names of the generated types, objects the ones of the structs among
+	// them, and names the Go names of the schemas renamed by x-go-name.
+	types, objects map[string]bool
+	names          map[string]string
+	// forms holds the media types of the form methods of the generated types, by Go name.
+	forms map[string]map[string]bool
+	// errors says whether the responses outside 2xx are read, only the client returns them, and
+	// responses whether all of them are, for the helpers of the server writing them. bodies says
+	// whether the request bodies that are not JSON are read, only the client sends them.
+	errors, responses, bodies bool
+}
+
+// readOperations returns the operations of the paths of the spec, in the order of their paths
+// and methods, leaving out the ones whose names are taken. reserved are the names what is written
+// with, the generated types can not take them.
+func readOperations(ctx context.Context, c *config, types []*Type, what string, reserved []string) (*SwaggerSimplification, []apiOperation, error) {
+	var spec SwaggerSimplification
+	if err := decodeSpec(ctx, c, &spec); err != nil {
+		return nil, nil, fmt.Errorf("decoding file contents: %w", err)
+	}
+	r := &operationReader{c: c, what: what, spec: &spec, types: make(map[string]bool, len(types)), objects: map[string]bool{}, names: goNames(spec.Schemas()), forms: map[string]map[string]bool{}, errors: what == "client", responses: what == "server", bodies: what == "client"}
+	for _, t := range types {
+		r.types[capitalize(t.Name)] = true
+		if len(t.forms) > 0 {
+			r.forms[capitalize(t.Name)] = t.forms
+		}
+		if len(t.Enum) == 0 && !t.tuple && t.union == nil {
+			r.objects[capitalize(t.Name)] = true
+		}
+	}
+	for _, n := range reserved {
+		if r.types[n] {
+			return nil, nil, fmt.Errorf("the type %s of the spec takes a name the %s needs", n, what)
+		}
+	}
+	paths := sortedKeys(spec.Paths)
+	if len(paths) == 0 {
+		c.warn(c.swaggerFile, "no paths found, the %s has no operations", what)
+	}
+	var ops []apiOperation
+	taken := map[string]bool{}
+	for _, p := range paths {
+		item := spec.Paths[p]
+		for _, mo := range item.MethodOperations() {
+			op, ok := r.operation(p, item, mo)
+			if !ok {
+				continue
+			}
+			if taken[op.name] || (len(op.params) > 0 && r.types[op.name+"Params"]) {
+				c.warn(p, "%s %s is left out of the %s as the name %s is taken", mo.Method, p, what, op.name)
+				continue
+			}
+			taken[op.name] = true
+			op.bodies = r.untakenBodies(p, op, taken)
+			ops = append(ops, op)
+		}
+	}
+	return &spec, ops, nil
+}
+
+// operation returns the method for the operation mo of the path p, false when it can not be
+// sent as JSON.
+func (r *operationReader) operation(p string, item SwaggerPathItem, mo MethodOperation) (apiOperation, bool) {
+	c, op := r.c, mo.Operation
+	result := apiOperation{method: mo.Method, path: p, summary: op.Summary}
+	if len(op.Tags) > 0 {
+		result.tag = op.Tags[0]
+	}
+	result.statuses = sortedKeys(op.Responses)
+	where := mo.Method + " " + p
+	if op.OperationID != "" {
+		result.name = fieldName(op.OperationID)
+	} else {
+		result.name = fieldName(strings.ToLower(mo.Method) + "_" + nonIdentifier.ReplaceAllString(p, "_"))
+	}
+	// the parameters of the operation replace the ones of the path with the same name and place.
+	var params []SwaggerParameter
+	index := map[string]int{}
+	for _, declared := range append(append([]SwaggerParameter(nil), item.Parameters...), op.Parameters...) {
+		param, ok := r.parameter(declared)
+		if !ok {
+			c.warn(where, "the parameter %s is not found, the operation is left out of the %s", declared.Ref, r.what)
+			return result, false
+		}
+		key := param.In + " " + param.Name
+		if i, dup := index[key]; dup {
+			params[i] = param
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+	// the names of the method parameters can not be the ones the client and server are written
+	// with.
+	used := map[string]bool{"c": true, "ctx": true, "params": true, "body": true, "result": true, "query": true, "header": true, "err": true, "v": true, "w": true, "r": true, "s": true, "e": true, "g": true, "i": true, "pathValue": true, "t": true, "all": true}
+	fields := map[string]bool{}
+	for _, param := range params {
+		switch param.In {
+		case "path":
+			cp := apiParam{name: param.Name, in: param.In, goType: r.paramType(where, op, param), required: true, constraints: propertyConstraints(paramSchema(param)), example: paramExample(param)}
+			cp.goName = paramName(fieldName(param.Name))
+			for used[cp.goName] {
+				cp.goName += "Param"
+			}
+			used[cp.goName] = true
+			result.pathParams = append(result.pathParams, cp)
+		case "query", "header":
+			cp := apiParam{name: param.Name, in: param.In, goType: r.paramType(where, op, param), required: param.Required, constraints: propertyConstraints(paramSchema(param)), example: paramExample(param)}
+			cp.goName = fieldName(param.Name)
+			if param.In == "query" {
+				cp.style, cp.explode = r.queryStyle(where, param)
+				cp.object = r.objects[cp.goType]
+			}
+			if fields[cp.goName] {
+				c.warn(where, "the %s parameter %s is left out of the %s as %s is taken", param.In, param.Name, r.what, cp.goName)
+				continue
+			}
+			fields[cp.goName] = true
+			result.params = append(result.params, cp)
+		case "body":
+			if param.Schema == nil {
+				continue
+			}
+			tn, ok := r.schemaType(*param.Schema)
+			if !ok {
+				tn = "json.RawMessage"
+			}
+			result.body, result.bodies = tn, []apiBody{{mediaType: "application/json", goType: tn}}
+		case "formData":
+			c.warn(where, "the form parameter %s is not JSON, the operation is left out of the %s", param.Name, r.what)
+			return result, false
+		default:
+			c.warn(where, "the %s parameter %s is left out of the %s", param.In, param.Name, r.what)
+		}
+	}
+	for _, m := range pathTemplate.FindAllStringSubmatch(p, -1) {
+		if _, ok := index["path "+m[1]]; !ok {
+			c.warn(where, "the path parameter %s is not declared, the operation is left out of the %s", m[1], r.what)
+			return result, false
+		}
+	}
+	if op.RequestBody != nil {
+		bodies, ok := r.requestBody(where, op)
+		if !ok {
+			return result, false
+		}
+		result.body, result.bodies = bodies[0].goType, bodies
+	}
+	tn, status, ok := r.response(where, op)
+	if !ok {
+		return result, false
+	}
+	result.result, result.status = tn, status
+	if r.errors {
+		result.errors = r.errorResponses(where, op)
+	}
+	if r.responses {
+		result.responses = r.allResponses(where, op)
+	}
+	return result, true
+}
+
+// parameter returns param, the one it references when it is a reference, false when that is not
+// found.
+func (r *operationReader) parameter(param SwaggerParameter) (SwaggerParameter, bool) {
+	if param.Ref == "" {
+		return param, true
+	}
+	name := typeFromRef(param.Ref)
+	if found, ok := r.spec.Components.Parameters[name]; ok {
+		return found, true
+	}
+	found, ok := r.spec.Parameters[name]
+	return found, ok
+}
+
+// queryStyle returns the style of a query parameter and whether it is exploded, form and exploded
+// by default. Swagger 2.0 arrays take them from their collectionFormat, csv by default. Styles
+// query parameters can not have are warned about and form is used instead.
+func (r *operationReader) queryStyle(where string, param SwaggerParameter) (string, bool) {
+	if param.Schema == nil && param.Type != "" {
+		switch param.CollectionFormat {
+		case "", "csv":
+			return "form", false
+		case "ssv":
+			return "spaceDelimited", false
+		case "pipes":
+			return "pipeDelimited", false
+		case "multi":
+			return "form", true
+		}
+		r.c.warn(where, "the collectionFormat %s of the query parameter %s is not supported, it is written as csv", param.CollectionFormat, param.Name)
+		return "form", false
+	}
+	style := param.Style
+	switch style {
+	case "":
+		style = "form"
+	case "form", "spaceDelimited", "pipeDelimited", "deepObject":
+	default:
+		r.c.warn(where, "the query parameter %s can not have the style %s, it is written as form", param.Name, style)
+		style = "form"
+	}
+	explode := style == "form"
+	if param.Explode != nil {
+		explode = *param.Explode
+	}
+	return style, explode
+}
+
+// paramSchema returns the schema of a parameter, the one Swagger 2.0 declares in place is made
+// into one.
+func paramSchema(param SwaggerParameter) SwaggerProperty {
+	if param.Schema != nil {
+		return *param.Schema
+	}
+	return SwaggerProperty{MetaSwaggerProperty: MetaSwaggerProperty{
+		Type:             param.Type,
+		Format:           param.Format,
+		Enum:             param.Enum,
+		Minimum:          param.Minimum,
+		Maximum:          param.Maximum,
+		ExclusiveMinimum: param.ExclusiveMinimum,
+		ExclusiveMaximum: param.ExclusiveMaximum,
+		MinLength:        param.MinLength,
+		MaxLength:        param.MaxLength,
+		MinItems:         param.MinItems,
+		MaxItems:         param.MaxItems,
+		Pattern:          param.Pattern,
+		Default:          param.Default,
+	}, Items: param.Items}
+}
+
+// paramExample returns the example of a parameter, the one of its schema or, failing those, its
+// default. It is nil when there is none.
+func paramExample(param SwaggerParameter) json.RawMessage {
+	schema := paramSchema(param)
+	for _, example := range []json.RawMessage{param.Example, schema.Example, schema.Default} {
+		if len(example) > 0 {
+			return example
+		}
+	}
+	return nil
+}
+
+// paramType returns the Go type of a path, query or header parameter, strings when it has no
+// type that can be written in a URL or header. Query objects declared inline are the types
+// queryObjectType made for them.
+func (r *operationReader) paramType(where string, op *SwaggerOperation, param SwaggerParameter) string {
+	if param.In == "query" && isQueryObject(param.Schema) {
+		if tn := capitalize(queryObjectName(op, param)); tn != "" && r.types[tn] {
+			return tn
+		}
+	}
+	tn, ok := r.schemaType(paramSchema(param))
+	if !ok || strings.HasPrefix(tn, "[][]") || (strings.HasPrefix(tn, "[]") && param.In == "path") {
+		r.c.warn(where, "the %s parameter %s is a string in the %s", param.In, param.Name, r.what)
+		return "string"
+	}
+	return tn
+}
+
+// schemaType returns the Go type of a schema of a parameter or body, false when it is neither a
+// generated type nor a scalar or an array of them.
+func (r *operationReader) schemaType(schema SwaggerProperty) (string, bool) {
+	if schema.Ref != "" {
+		name := typeFromRef(schema.Ref)
+		if to, ok := r.names[name]; ok {
+			name = to
+		}
+		tn := capitalize(name)
+		return tn, r.types[tn]
+	}
+	switch schema.Type {
+	case STString:
+		return "string", true
+	case STBoolean:
+		return "bool", true
+	case STInteger, STNumber:
+		return numberType(schema.Type, schema.Format).String(), true
+	case STArray:
+		tn, ok := r.schemaType(SwaggerProperty{MetaSwaggerProperty: schema.Items.MetaSwaggerProperty})
+		return "[]" + tn, ok
+	}
+	return "", false
+}
+
+// contentType returns the Go type of the JSON content of a body, the type generated for it when
+// it is declared inline with the name inline and json.RawMessage when it has none. It is false
+// when the body is not JSON.
+func (r *operationReader) contentType(content map[string]SwaggerMediaType, inline string) (string, bool) {
+	for _, ct := range sortedKeys(content) {
+		if !isJSONMedia(ct) {
+			continue
+		}
+		schema := content[ct].Schema
+		if schema.Ref == "" && r.types[capitalize(inline)] {
+			return capitalize(inline), true
+		}
+		tn, ok := r.schemaType(SwaggerProperty{
+			MetaSwaggerProperty: MetaSwaggerProperty{Type: schema.Type, Ref: schema.Ref},
+			Items:               schema.Items,
+		})
+		if !ok {
+			return "json.RawMessage", true
+		}
+		return tn, true
+	}
+	return "", false
+}
+
+// requestBody returns the content types of the request body of op, JSON first when it is among
+// them. Only the client reads the ones that are not JSON, XML and form bodies, it is false when
+// none can be read.
+func (r *operationReader) requestBody(where string, op *SwaggerOperation) ([]apiBody, bool) {
+	body, inline := *op.RequestBody, op.OperationID+".request"
+	if body.Ref != "" {
+		name := typeFromRef(body.Ref)
+		found, ok := r.spec.Components.RequestBodies[name]
+		if !ok {
+			r.c.warn(where, "the request body %s is not found, the operation is left out of the %s", body.Ref, r.what)
+			return nil, false
+		}
+		body, inline = found, name+".body"
+	}
+	var bodies []apiBody
+	if tn, ok := r.contentType(body.Content, inline); ok {
+		bodies = append(bodies, apiBody{mediaType: "application/json", goType: tn})
+	}
+	if r.bodies {
+		suffixes := map[string]bool{}
+		for _, ct := range sortedKeys(body.Content) {
+			b, ok := r.otherBody(where, ct, body.Content[ct].Schema)
+			if !ok || suffixes[b.suffix] {
+				continue
+			}
+			suffixes[b.suffix] = true
+			bodies = append(bodies, b)
+		}
+	}
+	if len(bodies) == 0 {
+		r.c.warn(where, "the request body is not JSON, the operation is left out of the %s", r.what)
+		return nil, false
+	}
+	// the first content type is sent by the method named after the operation.
+	bodies[0].suffix = ""
+	return bodies, true
+}
+
+// otherBody returns the content type ct of a request body when it is XML or a form, false for
+// the rest. XML bodies have to be generated structs and form ones structs with the form methods
+// of ct, the ones that are not are warned about.
+func (r *operationReader) otherBody(where, ct string, schema SwaggerSchema) (apiBody, bool) {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil || isJSONMedia(mt) {
+		return apiBody{}, false
+	}
+	var suffix string
+	switch {
+	case isXMLMedia(mt):
+		suffix = "XML"
+	case mt == formURLEncoded:
+		suffix = "Form"
+	case mt == formMultipart:
+		suffix = "Multipart"
+	default:
+		r.c.warn(where, "the %s request body is not sent by the %s", mt, r.what)
+		return apiBody{}, false
+	}
+	tn, ok := "", false
+	if schema.Ref != "" {
+		tn, ok = r.schemaType(SwaggerProperty{MetaSwaggerProperty: MetaSwaggerProperty{Ref: schema.Ref}})
+	}
+	if !ok || !r.objects[tn] || (suffix != "XML" && !r.forms[tn][mt]) {
+		r.c.warn(where, "the %s request body is not a component, it is not sent by the %s", mt, r.what)
+		return apiBody{}, false
+	}
+	return apiBody{mediaType: mt, goType: tn, suffix: suffix}, true
+}
+
+// untakenBodies returns the bodies of op leaving out the ones whose method name, the name of op
+// with their suffix, is taken, which it takes.
+func (r *operationReader) untakenBodies(where string, op apiOperation, taken map[string]bool) []apiBody {
+	var result []apiBody
+	for _, b := range op.bodies {
+		if b.suffix != "" {
+			name := op.name + b.suffix
+			if taken[name] || r.types[name] {
+				r.c.warn(where, "the %s request body is not sent by the %s as the name %s is taken", b.mediaType, r.what, name)
+				continue
+			}
+			taken[name] = true
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// response returns the Go type of the body of the first 2xx response of op, empty when it has
+// none, along with its status. It is false when the body is not JSON.
+func (r *operationReader) response(where string, op *SwaggerOperation) (string, int, bool) {
+	for _, status := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		// ranges such as 2XX are answered with 200.
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			code = http.StatusOK
+		}
+		tn, _, err := r.responseBody(op, status)
+		if err != nil {
+			r.c.warn(where, "%s, the operation is left out of the %s", err, r.what)
+			return "", 0, false
+		}
+		return tn, code, true
+	}
+	return "", http.StatusOK, true
+}
+
+// errorResponses returns the responses of op outside 2xx with a JSON body, in the order of their
+// statuses, the ones without a body or with one that is not JSON are left to the ClientError.
+func (r *operationReader) errorResponses(where string, op *SwaggerOperation) []apiError {
+	var result []apiError
+	for _, status := range sortedKeys(op.Responses) {
+		if strings.HasPrefix(status, "2") {
+			continue
+		}
+		tn, _, err := r.responseBody(op, status)
+		if err != nil {
+			r.c.warn(where, "%s, it is returned as a *ClientError", err)
+			continue
+		}
+		if tn == "" {
+			continue
+		}
+		result = append(result, apiError{status: status, body: tn})
+	}
+	return result
+}
+
+// allResponses returns the responses of op, in the order of their statuses, the ones whose body
+// can not be read are warned about and kept without one.
+func (r *operationReader) allResponses(where string, op *SwaggerOperation) []apiResponse {
+	result := make([]apiResponse, 0, len(op.Responses))
+	for _, status := range sortedKeys(op.Responses) {
+		tn, mediaType, err := r.responseBody(op, status)
+		if err != nil {
+			r.c.warn(where, "%s, it gets no helper writing it", err)
+		}
+		result = append(result, apiResponse{status: status, body: tn, mediaType: mediaType})
+	}
+	return result
+}
+
+// responseBody returns the Go type of the body of the response of op with status, empty when it
+// has none, along with its JSON media type. It fails when the response is not found or its body
+// is not JSON.
+func (r *operationReader) responseBody(op *SwaggerOperation, status string) (string, string, error) {
+	resp, inline := op.Responses[status], op.OperationID+"."+status+".response"
+	if resp.Ref != "" {
+		name := typeFromRef(resp.Ref)
+		found, ok := r.spec.Components.Responses[name]
+		if !ok {
+			found, ok = r.spec.Responses[name]
+		}
+		if !ok {
+			return "", "", fmt.Errorf("the response %s is not found", resp.Ref)
+		}
+		resp, inline = found, name+".response"
+	}
+	if resp.Schema != nil {
+		tn, ok := r.schemaType(*resp.Schema)
+		if !ok {
+			return "json.RawMessage", "application/json", nil
+		}
+		return tn, "application/json", nil
+	}
+	if len(resp.Content) == 0 {
+		return "", "", nil
+	}
+	tn, ok := r.contentType(resp.Content, inline)
+	if !ok {
+		return "", "", fmt.Errorf("the %s response is not JSON", status)
+	}
+	// contentType took the first JSON media type.
+	mediaType := ""
+	for _, ct := range sortedKeys(resp.Content) {
+		if isJSONMedia(ct) {
+			mediaType = ct
+			break
+		}
+	}
+	return tn, mediaType, nil
+}
+
+// operationSignature returns the name, parameters and results of the method of op, as in the
+// client and the Server interface.
+func operationSignature(op apiOperation) string {
+	return methodSignature(op.name, op.body, op)
+}
+
+// methodSignature returns the signature of the method name sending op with a body of type body.
+func methodSignature(name, body string, op apiOperation) string {
+	args := []string{"ctx context.Context"}
+	for _, p := range op.pathParams {
+		args = append(args, p.goName+" "+p.goType)
+	}
+	if len(op.params) > 0 {
+		args = append(args, "params "+op.name+"Params")
+	}
+	if body != "" {
+		args = append(args, "body "+body)
+	}
+	if op.result == "" {
+		return name + "(" + strings.Join(args, ", ") + ") error"
+	}
+	return name + "(" + strings.Join(args, ", ") + ") (" + op.result + ", error)"
+}
+
+// writeSummary writes the summary of op as comment lines starting with indent.
+func writeSummary(w *bufio.Writer, indent string, op apiOperation) {
+	if op.summary == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(op.summary), "\n") {
+		fmt.Fprintf(w, "%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+// writeOperationParams writes the struct holding the query and header parameters of op, the ones
+// not required are pointers left out when nil.
+func writeOperationParams(w *bufio.Writer, op apiOperation) {
+	fmt.Fprintf(w, "\n// %sParams holds the query and header parameters of %s.\n", op.name, op.name)
+	fmt.Fprintf(w, "type %sParams struct {\n", op.name)
+	width := 0
+	for _, p := range op.params {
+		if len(p.goName) > width {
+			width = len(p.goName)
+		}
+	}
+	for _, p := range op.params {
+		tn := p.goType
+		if !p.required && !strings.HasPrefix(tn, "[]") {
+			tn = "*" + tn
+		}
+		fmt.Fprintf(w, "\t%-*s %s\n", width, p.goName, tn)
+	}
+	fmt.Fprint(w, "}\n")
+}
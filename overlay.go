@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// overlayFileSystem reads the files of base, or the ones written to it during the run, and keeps
+// what is written in memory so nothing is written to base.
+type overlayFileSystem struct {
+	base FileSystem
+	*memoryFileSystem
+}
+
+// newOverlayFileSystem returns an overlayFileSystem over base with nothing written yet.
+func newOverlayFileSystem(base FileSystem) *overlayFileSystem {
+	return &overlayFileSystem{base: base, memoryFileSystem: newMemoryFileSystem(nil)}
+}
+
+func (o *overlayFileSystem) Open(name string) (io.ReadCloser, error) {
+	if f, err := o.memoryFileSystem.Open(name); err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+func (o *overlayFileSystem) Glob(pattern string) ([]string, error) {
+	return o.base.Glob(pattern)
+}
+
+var _ FileSystem = &overlayFileSystem{}
+
+// runOverlay runs c without writing the files it generates, they are written instead as a JSON
+// object mapping their absolute paths to their contents to the --overlay file, stdout for -
+// leaving it to the overlay alone. It
+// is the shape of the Overlay of golang.org/x/tools/go/packages once the contents are []byte, so
+// tools can type-check against the code that would be generated.
+func runOverlay(ctx context.Context, c *config, stdout io.Writer) error {
+	base := c.fileSystem
+	fsys := newOverlayFileSystem(c.files())
+	c.fileSystem = fsys
+	err := run(ctx, c, stdout)
+	c.fileSystem = base
+	if err != nil {
+		return err
+	}
+	overlay := map[string]string{}
+	for name, b := range fsys.written() {
+		abs, err := filepath.Abs(filepath.FromSlash(name))
+		if err != nil {
+			return fmt.Errorf("finding the path of %s: %w", name, err)
+		}
+		overlay[abs] = string(b)
+	}
+	b, err := json.MarshalIndent(overlay, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding overlay: %w", err)
+	}
+	if c.overlay == "-" {
+		_, err := fmt.Fprintf(stdout, "%s\n", b)
+		return err
+	}
+	// the overlay itself is the one file written.
+	return writeFile(c, c.overlay, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\n", b)
+		return err
+	})
+}
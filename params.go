@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// paramTypes flags the components holding the JSON content of parameters and returns the types
+// made up for the contents declared inline, named after the operation and the parameter.
+func paramTypes(c *config, paths map[string]SwaggerPathItem, types []*Type) []*Type {
+	if len(paths) == 0 {
+		return nil
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	var result []*Type
+	for _, p := range names {
+		item := paths[p]
+		for _, op := range item.Operations() {
+			params := append(append([]SwaggerParameter(nil), item.Parameters...), op.Parameters...)
+			for _, param := range params {
+				if t := queryObjectType(c, p, op, param); t != nil {
+					result = append(result, t...)
+					continue
+				}
+				for ct, media := range param.Content {
+					if !isJSONMedia(ct) {
+						continue
+					}
+					if media.Schema.Ref != "" {
+						if t, ok := byName[typeFromRef(media.Schema.Ref)]; ok && len(t.Enum) == 0 && !t.tuple {
+							t.param = true
+						}
+						continue
+					}
+					if media.Schema.Type != STObject {
+						c.debugf("the content of the %s parameter %s is not an object, no type needed\n", param.In, param.Name)
+						continue
+					}
+					if op.OperationID == "" {
+						c.warn(p, "the content of the %s parameter %s is only given a type in operations with an operationId", param.In, param.Name)
+						continue
+					}
+					ts := processComponent(c, op.OperationID+"."+param.Name, media.Schema)
+					if len(ts) == 0 {
+						continue
+					}
+					ts[0].param = true
+					result = append(result, ts...)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// queryObjectType returns the types of a query parameter whose schema is an object declared
+// inline, named after the operation and the parameter like the contents, so that its fields are
+// written as its style says instead of as a string.
+func queryObjectType(c *config, p string, op *SwaggerOperation, param SwaggerParameter) []*Type {
+	if param.In != "query" || !isQueryObject(param.Schema) {
+		return nil
+	}
+	if op.OperationID == "" {
+		c.warn(p, "the query parameter %s is only given a type in operations with an operationId", param.Name)
+		return nil
+	}
+	name := queryObjectName(op, param)
+	fields, extra := processProperty(c, name, param.Schema.Properties, param.Schema.Required)
+	t := &Type{Name: name, Source: c.swaggerFile, Description: param.Schema.Description, Fields: fields}
+	return append([]*Type{t}, extra...)
+}
+
+// isQueryObject returns true if schema is an object with properties declared inline.
+func isQueryObject(schema *SwaggerProperty) bool {
+	if schema == nil || schema.Ref != "" || len(schema.Properties.Names) == 0 {
+		return false
+	}
+	return (schema.Type == STObject || schema.Type == "") && len(schema.AllOf)+len(schema.AnyOf)+len(schema.OneOf) == 0
+}
+
+// queryObjectName returns the name of the type of an inline object query parameter, empty when
+// the operation has no operationId to name it after.
+func queryObjectName(op *SwaggerOperation, param SwaggerParameter) string {
+	if op.OperationID == "" {
+		return ""
+	}
+	return op.OperationID + "." + param.Name
+}
+
+// writeParamMethods writes the helpers encoding a type into the content of a parameter and
+// decoding it back.
+func writeParamMethods(w io.Writer, t *Type, structName string) {
+	if !t.param {
+		return
+	}
+	fmt.Fprintf(w, "// EncodeParam returns %s as the JSON content of a parameter, it still needs to be escaped\n", structName)
+	fmt.Fprint(w, "// for the query, header, path or cookie holding it.\n")
+	fmt.Fprintf(w, "func (t %s) EncodeParam() (string, error) {\n", structName)
+	fmt.Fprint(w, "\tb, err := json.Marshal(t)\n\treturn string(b), err\n}\n\n")
+
+	fmt.Fprintf(w, "// Decode%sParam decodes %s from the JSON content of a parameter, once unescaped.\n", structName, structName)
+	fmt.Fprintf(w, "func Decode%sParam(s string) (%s, error) {\n", structName, structName)
+	fmt.Fprintf(w, "\tvar t %s\n", structName)
+	fmt.Fprint(w, "\terr := json.Unmarshal([]byte(s), &t)\n\treturn t, err\n}\n\n")
+}
@@ -1,206 +1,414 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"unicode"
 )
 
-func jsonIntoMap(c *config) (map[string][]interface{}, error) {
-	expanded := make([]string, 0, len(c.sourceFiles))
+// jsonSource holds the documents decoded from a single source file.
+type jsonSource struct {
+	name string
+	docs []interface{}
+	// typeName names the outer types, they are named after the file when empty.
+	typeName string
+	// annotations holds what the sidecar annotations file says about the types, by name.
+	annotations map[string]typeAnnotation
+}
+
+// jsonObject is a decoded JSON object that, unlike map[string]interface{}, remembers the order
+// in which its keys appeared, values[i] is the value of keys[i].
+type jsonObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// set adds or replaces the value of key, duplicated keys keep their first position and their
+// last value like encoding/json does.
+func (o *jsonObject) set(key string, v interface{}) {
+	for i, k := range o.keys {
+		if k == key {
+			o.values[i] = v
+			return
+		}
+	}
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, v)
+}
+
+// has returns true if the object has key, whatever its value.
+func (o *jsonObject) has(key string) bool {
+	for _, k := range o.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// remove drops key from the object, if it has it.
+func (o *jsonObject) remove(key string) {
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			o.values = append(o.values[:i], o.values[i+1:]...)
+			return
+		}
+	}
+}
+
+// get returns the value of key, or nil if the object does not have it.
+func (o *jsonObject) get(key string) interface{} {
+	for i, k := range o.keys {
+		if k == key {
+			return o.values[i]
+		}
+	}
+	return nil
+}
+
+// readJSONSources decodes the sources, with --keep-going the ones that fail are left out and
+// kept in c.failedSources to be reported once the rest are generated.
+func readJSONSources(ctx context.Context, c *config) ([]jsonSource, error) {
+	type found struct {
+		p   sourceProvider
+		ref sourceRef
+	}
+	refs := []found{}
+	c.failedSources = nil
 	for _, sf := range c.sourceFiles {
-		g, err := filepath.Glob(sf)
-		if err != nil {
-			expanded = append(expanded, sf)
+		p, err := providerFor(ctx, c, sf)
+		if err != nil && c.keepGoing {
+			c.failedSources = append(c.failedSources, fmt.Errorf("finding source %s: %w", sf, err))
 			continue
 		}
-		expanded = append(expanded, g...)
-		for _, e := range g {
-			fmt.Printf("Found file: %s\n", e)
+		if err != nil {
+			return nil, fmt.Errorf("finding source %s: %w", sf, err)
+		}
+		for _, ref := range p.List() {
+			c.debugf("Found file: %s\n", ref)
+			refs = append(refs, found{p: p, ref: ref})
 		}
 	}
 
-	result := map[string][]interface{}{}
-	for _, f := range expanded {
-		var tgt interface{}
-		fp, err := os.Open(f)
-		if err != nil {
-			return nil, fmt.Errorf("opening json file: %w", err)
+	// files are decoded concurrently but kept in the order they were found.
+	result := make([]jsonSource, len(refs))
+	errs := make([]error, len(refs))
+	err := parallel(ctx, c.jobs, len(refs), func(i int) {
+		result[i], errs[i] = decodeSource(ctx, c, refs[i].p, refs[i].ref)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading sources: %w", err)
+	}
+	kept := result[:0]
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			kept = append(kept, result[i])
+		case c.keepGoing:
+			c.failedSources = append(c.failedSources, fmt.Errorf("%s: %w", refs[i].ref, err))
+		default:
+			return nil, err
 		}
-		if err := json.NewDecoder(fp).Decode(&tgt); err != nil {
+	}
+	return kept, nil
+}
+
+// failedSourcesError returns an error listing the sources --keep-going left out, nil if none.
+func failedSourcesError(c *config) error {
+	if len(c.failedSources) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(c.failedSources))
+	for _, err := range c.failedSources {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("%d sources could not be read and were left out:\n\t%s", len(msgs), strings.Join(msgs, "\n\t"))
+}
+
+// decodeSource reads the JSON documents of a source from the provider, files holding documents
+// back to back, like logs, give a sample per document. With --lenient-json comments and trailing
+// commas are dropped first.
+func decodeSource(ctx context.Context, c *config, p sourceProvider, ref sourceRef) (jsonSource, error) {
+	src := jsonSource{name: string(ref)}
+	if err := ctx.Err(); err != nil {
+		return src, fmt.Errorf("reading %s: %w", ref, err)
+	}
+	fp, err := p.Open(ref)
+	if err != nil {
+		return src, fmt.Errorf("opening json file: %w", err)
+	}
+	// the contents are read whole to tell where decoding errors happened.
+	b, err := readLimited(c, src.name, fp)
+	fp.Close()
+	if err != nil {
+		return src, fmt.Errorf("reading json file: %w", err)
+	}
+	b, err = toUTF8(b)
+	if err != nil {
+		return src, fmt.Errorf("reading json file: %w", err)
+	}
+	if src.docs, err = decodeDocuments(c, src.name, b); err != nil {
+		return src, err
+	}
+	src.annotations, err = readAnnotations(c, src.name)
+	if err != nil {
+		return src, err
+	}
+	return src, nil
+}
+
+// decodeDocuments decodes the JSON documents in b, read from name, the elements of top level
+// arrays are documents of their own.
+func decodeDocuments(c *config, name string, b []byte) ([]interface{}, error) {
+	if c.lenientJSON {
+		b = stripJSONC(b)
+	}
+	if err := checkDepth(c, name, b); err != nil {
+		return nil, fmt.Errorf("reading json file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// exports repeating keys are usually broken, encoding/json would silently keep the last value.
+	duplicate := func(path string) error {
+		if c.duplicateKeys == duplicateKeysError {
+			return fmt.Errorf("the key %s is repeated", path)
+		}
+		line, column, _, _ := position(b, int(dec.InputOffset()))
+		c.warn(fmt.Sprintf("%s:%d:%d", name, line, column), "the key %s is repeated, the last value is kept", path)
+		return nil
+	}
+	var docs []interface{}
+	for n := 1; ; n++ {
+		tgt, err := decodeOrdered(dec, "", duplicate)
+		// the first document is required, the end of the file only ends the ones after it.
+		if err == io.EOF && n > 1 {
+			break
+		}
+		if err != nil {
+			err = locateError(name, b, err, dec.InputOffset())
+			if n > 1 {
+				return nil, fmt.Errorf("decoding document %d of the file: %w", n, err)
+			}
 			return nil, fmt.Errorf("decoding file contents: %w", err)
 		}
 		switch t := tgt.(type) {
-		case map[string]interface{}:
-			result[f] = []interface{}{t}
+		case *jsonObject:
+			docs = append(docs, t)
 		case []interface{}:
-			result[f] = t
+			docs = append(docs, t...)
 		case string: // yeah, valid but cmoon
-			result[f] = []interface{}{t}
+			docs = append(docs, t)
 		default:
 			return nil, fmt.Errorf("the json is %T and I have no clue what to do with it", t)
 		}
 	}
-	return result, nil
+	return docs, nil
 }
 
-func typesFromMap(c *config, m map[string][]interface{}) (map[string]map[string]maybeType, map[string]string, error) {
-	types := map[string]map[string]maybeType{}
-	outerTypes := map[string]string{}
-	for tn, t := range m {
-		for _, tf := range t {
-			switch field := tf.(type) {
-			case map[string]interface{}:
-				fileName := filepath.Base(tn)
-				parts := strings.Split(fileName, ".")
-				name := parts[0]
-				t, err := unWrapMap(c, field, name, types, outerTypes, tn)
+// decodeOrdered decodes the next value from dec, like Decode into an interface{} would, except
+// objects are returned as *jsonObject. The keys found twice in an object are passed, dot separated
+// from the ones of the objects holding it starting at path, to duplicate, when not nil, and decoding
+// stops if it returns an error. Otherwise the last value is kept.
+func decodeOrdered(dec *json.Decoder, path string, duplicate func(path string) error) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch d {
+	case '{':
+		obj := &jsonObject{}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := kt.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if duplicate != nil && obj.has(key) {
+				if err := duplicate(keyPath); err != nil {
+					return nil, err
+				}
+			}
+			v, err := decodeOrdered(dec, keyPath, duplicate)
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s: %w", key, err)
+			}
+			obj.set(key, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrdered(dec, path, duplicate)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", d)
+}
+
+func typesFromMap(ctx context.Context, c *config, sources []jsonSource) ([]*Type, error) {
+	inf := &inference{types: newTypeRegistry()}
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("inferring types from %s: %w", src.name, err)
+		}
+		inf.annotations = src.annotations
+		inf.annotated = map[string]bool{}
+		for _, doc := range src.docs {
+			switch obj := doc.(type) {
+			case *jsonObject:
+				name := src.typeName
+				if name == "" {
+					fileName := filepath.Base(src.name)
+					parts := strings.Split(fileName, ".")
+					name = parts[0]
+				}
+				t, err := unWrapMap(ctx, c, obj, name, inf, src.name, 0)
 				if err != nil {
-					return nil, nil, fmt.Errorf("unwrapping json types: %w", err)
+					return nil, fmt.Errorf("unwrapping json types: %w", err)
 				}
-				finalTname, _ := typeExists(name, "topLevel", c, t, types)
-				outerTypes[finalTname] = tn
+				inf.types.Resolve(name, "topLevel", c, t)
 			default:
 				// not sure what to do here
-				fmt.Printf("type of field (%T) %v\n", tf, tf)
+				c.warn(src.name, "skipping top level %T value %v, only objects can become types", doc, doc)
 			}
 		}
+		unusedAnnotations(c, src.name, src.annotations, inf.annotated)
 	}
-	return types, outerTypes, nil
+	return inf.types.Types(), nil
 }
 
-func unWrapMap(c *config, m map[string]interface{}, name string,
-	typeMap map[string]map[string]maybeType,
-	outerTypes map[string]string,
-	fileName string) (map[string]maybeType, error) {
-	aType := map[string]maybeType{}
-	for fn, f := range m {
-		var it = maybeType{
-			originalFileName: fileName,
-		}
+// inference holds the state shared by the recursive unWrapMap calls.
+type inference struct {
+	types *TypeRegistry
+	// scratch holds a reusable field buffer per nesting depth, most inferred types end up merged
+	// into existing ones so they never need fields of their own, the registry copies the fields
+	// of the ones it keeps.
+	scratch [][]Field
+	// annotations are the ones of the source being inferred, annotated holds the names of its
+	// types seen so far.
+	annotations map[string]typeAnnotation
+	annotated   map[string]bool
+}
+
+// fields returns an empty buffer for a type at depth with room for size fields.
+func (inf *inference) fields(depth, size int) []Field {
+	for len(inf.scratch) <= depth {
+		inf.scratch = append(inf.scratch, nil)
+	}
+	if cap(inf.scratch[depth]) < size {
+		inf.scratch[depth] = make([]Field, 0, size)
+	}
+	return inf.scratch[depth][:0]
+}
+
+func unWrapMap(ctx context.Context, c *config, m *jsonObject, name string,
+	inf *inference,
+	fileName string, depth int) (*Type, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	aType := &Type{
+		Source: fileName,
+		Fields: inf.fields(depth, len(m.keys)),
+	}
+	for i, fn := range m.keys {
+		f := m.values[i]
+		aType.Fields = append(aType.Fields, Field{Name: fn})
+		it := &aType.Fields[len(aType.Fields)-1].Type
 		switch field := f.(type) {
-		case map[string][]interface{}:
-			// TODO handle this type (it is rather uncommon)
-			continue
 		case []interface{}:
 			// Have no clue what this is
 			it.isArray = true
 			if len(field) == 0 {
+				c.warn(fileName, "field %s.%s is an empty array, assuming []interface{}", name, fn)
 				it.nameOftype = "interface{}"
 				break
 			}
 			switch innerField := field[0].(type) {
-			case map[string]interface{}:
-				uit, err := unWrapMap(c, innerField, fn, typeMap, outerTypes, fileName)
+			case *jsonObject:
+				uit, err := unWrapMap(ctx, c, innerField, fn, inf, fileName, depth+1)
 				if err != nil {
 					return nil, fmt.Errorf("unwrapping type %s: %w", fn, err)
 				}
 
-				tName, _ := typeExists(fn, name, c, uit, typeMap)
-				outerTypes[tName] = fileName
+				tName, _ := inf.types.Resolve(fn, name, c, uit)
 				it.nameOftype = tName
 			default:
 				it.typeOf = reflect.TypeOf(innerField)
+				it.decimal = isSampleDecimal(fn, innerField)
+				it.duration = isSampleDuration(innerField)
+				it.netip = sampleNetIP(innerField)
 			}
 
-		case map[string]interface{}:
-			uit, err := unWrapMap(c, field, fn, typeMap, outerTypes, fileName)
+		case *jsonObject:
+			uit, err := unWrapMap(ctx, c, field, fn, inf, fileName, depth+1)
 			if err != nil {
 				return nil, fmt.Errorf("unwrapping type %s: %w", fn, err)
 			}
-			tName, _ := typeExists(fn, name, c, uit, typeMap)
-			outerTypes[tName] = fileName
+			tName, _ := inf.types.Resolve(fn, name, c, uit)
 			it.nameOftype = tName
+		case nil:
+			if inf.annotations[name].Fields[fn].Type == "" {
+				c.warn(fileName, "field %s.%s is null, assuming interface{}", name, fn)
+			}
 		default:
 			it.typeOf = reflect.TypeOf(f)
+			it.decimal = isSampleDecimal(fn, f)
+			it.duration = isSampleDuration(f)
+			it.netip = sampleNetIP(f)
 		}
-		aType[fn] = it
 	}
+	inf.scratch[depth] = aType.Fields
+	inf.annotated[name] = true
+	annotate(aType, name, inf.annotations)
 	return aType, nil
 }
 
 func normalizeNames(name, pkgName string) string {
-	newName := make([]rune, 0, len(name)*2) // worse case scenario there are all capitals
+	return normalizeMemo.get(name+"\x00"+pkgName, func() string { return normalizeNamesUncached(name, pkgName) })
+}
+
+// normalizeNamesUncached turns camelCase into snake_case and removes the package name prefix.
+func normalizeNamesUncached(name, pkgName string) string {
+	b := &strings.Builder{}
+	b.Grow(len(name) * 2) // worse case scenario there are all capitals
 	for i, r := range name {
-		rr := rune(r)
-		if unicode.IsUpper(rr) {
-			rr = unicode.ToLower(rr)
+		if unicode.IsUpper(r) {
+			r = unicode.ToLower(r)
 			if i > 0 { // first can be safely lowercased without prepending _
-				newName = append(newName, '_')
+				b.WriteByte('_')
 			}
 		}
-		newName = append(newName, rr)
-
+		b.WriteRune(r)
 	}
-	normalized := string(newName)
+	normalized := b.String()
 	// prevent go lint stuttering type name warning
-	if strings.HasPrefix(strings.ToLower(name), strings.ToLower(pkgName)) && len(name) != len(pkgName) {
+	if len(name) != len(pkgName) && strings.HasPrefix(strings.ToLower(name), strings.ToLower(pkgName)) {
 		normalized = normalized[len(pkgName):]
 	}
 	return normalized
 }
-
-func typeExists(name, parent string, c *config, ours map[string]maybeType, typeMap map[string]map[string]maybeType) (string, bool) {
-	foundName := name
-	fmt.Printf("looking for type: %s\n", foundName)
-	newName, ok := c.fileTypeMap[foundName]
-	if ok {
-		foundName = newName
-		fmt.Printf("renamed to: %s\n", foundName)
-	}
-	foundName = normalizeNames(foundName, c.targetPackage)
-	fmt.Printf("normalized to: %s\n", foundName)
-	existing, exists := typeMap[foundName]
-	if !exists {
-		for k := range typeMap {
-			parts := strings.Split(k, ".")
-			if parts[len(parts)-1] == foundName {
-				existing = typeMap[k]
-				foundName = k
-				fmt.Printf("it exists parented: %s\n", foundName)
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			fmt.Println("it's new")
-			typeMap[foundName] = ours
-			return foundName, false
-		}
-	}
-
-	missing := map[string]maybeType{}
-	for k, v := range existing {
-		vo, ok := ours[k]
-		if !ok {
-			continue
-		}
-		if !v.Equals(&vo) {
-			newName := fmt.Sprintf("%s.%s", parent, foundName)
-			typeMap[newName] = ours
-			return newName, false
-		}
-	}
-
-	for k, v := range ours {
-		vo, ok := existing[k]
-		if !ok {
-			missing[k] = ours[k]
-			continue
-		}
-		if !v.Equals(&vo) {
-			newName := fmt.Sprintf("%s.%s", parent, foundName)
-			typeMap[newName] = ours
-			return newName, false
-		}
-	}
-	for k := range missing {
-		existing[k] = missing[k]
-	}
-	typeMap[foundName] = existing
-	return foundName, true
-}
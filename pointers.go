@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// markPointers turns the struct fields whose type is estimated to take more than
+// --pointer-above-bytes into pointers, so copying the structs holding them stays cheap. Nested
+// structs that become pointers count as one in the size of their parents.
+func markPointers(ctx context.Context, c *config, types []*Type) error {
+	if c.pointerAbove <= 0 {
+		return nil
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[capitalize(t.Name)] = t
+	}
+	sizes := map[string]int{}
+	for _, t := range types {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("sizing %s: %w", t.Name, err)
+		}
+		structName := capitalize(t.Name)
+		for i := range t.Fields {
+			f := &t.Fields[i].Type
+			if t.Fields[i].Name == "" || f.IsMultiple() || f.isArray {
+				continue
+			}
+			tn, _ := fieldType(c, structName, t.Fields[i].GoName(), f)
+			if sub, ok := byName[tn]; ok && len(sub.Enum) == 0 && tn != structName &&
+				structSize(c, sub, byName, sizes) > c.pointerAbove {
+				c.debugf("%s.%s is estimated to take more than %d bytes, it becomes a pointer\n", structName, t.Fields[i].GoName(), c.pointerAbove)
+				f.pointer = true
+			}
+		}
+	}
+	return nil
+}
+
+// structSize estimates the bytes taken by a value of t, as its fields are aligned at most at 8
+// bytes every field takes at least that. Sizes are memoized in sizes, where types being measured
+// are 0 so recursive ones count as pointers.
+func structSize(c *config, t *Type, byName map[string]*Type, sizes map[string]int) int {
+	structName := capitalize(t.Name)
+	if size, ok := sizes[structName]; ok {
+		if size == 0 {
+			return 8
+		}
+		return size
+	}
+	sizes[structName] = 0
+	size := 0
+	for i := range t.Fields {
+		f := &t.Fields[i].Type
+		if f.IsMultiple() {
+			size += 8 * len(f.multiType)
+			continue
+		}
+		tn, _ := fieldType(c, structName, t.Fields[i].GoName(), f)
+		fieldSize := typeSize(tn)
+		if sub, ok := byName[tn]; ok && len(sub.Enum) == 0 {
+			fieldSize = structSize(c, sub, byName, sizes)
+			if fieldSize > c.pointerAbove {
+				fieldSize = 8
+			}
+		}
+		size += fieldSize
+	}
+	if t.extra {
+		size += 8
+	}
+	sizes[structName] = size
+	return size
+}
+
+// typeSize estimates the bytes taken by a value of tn when it is not one of the generated structs,
+// types from other packages are assumed to be small.
+func typeSize(tn string) int {
+	switch {
+	case strings.HasPrefix(tn, "[]"):
+		return 24
+	case strings.HasPrefix(tn, "*"), strings.HasPrefix(tn, "map["):
+		return 8
+	}
+	switch tn {
+	case "string", "interface{}", netipAddr:
+		return 16
+	case netipPrefix:
+		return 32
+	}
+	return 8
+}
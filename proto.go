@@ -0,0 +1,771 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// protoToken is a token of a .proto file along with the comments around it, doc holds the ones
+// on the lines right above and trailing the one after it on the same line.
+type protoToken struct {
+	text     string
+	str      bool
+	line     int
+	doc      string
+	trailing string
+}
+
+// protoMessage is a message of a .proto file, named after the messages holding it dot separated.
+type protoMessage struct {
+	name    string
+	comment string
+	fields  []protoField
+}
+
+// protoField is a field of a message, keyType is set for maps and oneof for the members of one.
+type protoField struct {
+	name     string
+	jsonName string
+	typeName string
+	keyType  string
+	label    string
+	oneof    string
+	comment  string
+}
+
+// protoEnum is an enum of a .proto file, named like messages are.
+type protoEnum struct {
+	name    string
+	comment string
+	values  []string
+}
+
+// protoParser reads the messages and enums of a .proto file, the services, extensions and options
+// are skipped.
+type protoParser struct {
+	c        *config
+	file     string
+	toks     []protoToken
+	pos      int
+	pkg      string
+	imports  []string
+	messages []*protoMessage
+	enums    []*protoEnum
+}
+
+// protoScalars are the schemas of the scalar types of protobuf as the JSON mapping of proto3
+// writes them. 64 bit integers are written as strings, json.Number reads both forms.
+var protoScalars = map[string]MetaSwaggerProperty{
+	"double":   {Type: STNumber, Format: "double"},
+	"float":    {Type: STNumber, Format: "float"},
+	"int32":    {Type: STInteger, Format: "int32"},
+	"sint32":   {Type: STInteger, Format: "int32"},
+	"sfixed32": {Type: STInteger, Format: "int32"},
+	"uint32":   {Type: STInteger, GoType: "uint32"},
+	"fixed32":  {Type: STInteger, GoType: "uint32"},
+	"int64":    {Type: STString, GoType: "encoding/json.Number"},
+	"sint64":   {Type: STString, GoType: "encoding/json.Number"},
+	"sfixed64": {Type: STString, GoType: "encoding/json.Number"},
+	"uint64":   {Type: STString, GoType: "encoding/json.Number"},
+	"fixed64":  {Type: STString, GoType: "encoding/json.Number"},
+	"bool":     {Type: STBoolean},
+	"string":   {Type: STString},
+	"bytes":    {Type: STString, Format: "byte"},
+}
+
+// protoWellKnown are the schemas of the well known types of google/protobuf as the JSON mapping
+// writes them, the wrappers are the nullable scalars they wrap.
+var protoWellKnown = map[string]MetaSwaggerProperty{
+	"google.protobuf.Timestamp":   {Type: STString, Format: "date-time"},
+	"google.protobuf.Duration":    {Type: STString, Format: "duration"},
+	"google.protobuf.FieldMask":   {Type: STString},
+	"google.protobuf.Struct":      {GoType: "map[string]interface{}"},
+	"google.protobuf.Any":         {GoType: "map[string]interface{}"},
+	"google.protobuf.Empty":       {GoType: "struct{}"},
+	"google.protobuf.Value":       {GoType: "interface{}"},
+	"google.protobuf.ListValue":   {GoType: "[]interface{}"},
+	"google.protobuf.DoubleValue": {Type: STNumber, Format: "double", Nullable: true},
+	"google.protobuf.FloatValue":  {Type: STNumber, Format: "float", Nullable: true},
+	"google.protobuf.Int32Value":  {Type: STInteger, Format: "int32", Nullable: true},
+	"google.protobuf.UInt32Value": {Type: STInteger, GoType: "*uint32"},
+	"google.protobuf.Int64Value":  {Type: STString, GoType: "*encoding/json.Number"},
+	"google.protobuf.UInt64Value": {Type: STString, GoType: "*encoding/json.Number"},
+	"google.protobuf.BoolValue":   {Type: STBoolean, Nullable: true},
+	"google.protobuf.StringValue": {Type: STString, Nullable: true},
+	"google.protobuf.BytesValue":  {Type: STString, Format: "byte", Nullable: true},
+}
+
+// protoIntoTypes returns the types of the messages of a .proto file, the ones of the JSON mapping
+// of proto3: fields are named in lowerCamelCase unless they have a json_name and enums are their
+// value names. Nested messages and enums are named after the ones holding them.
+func protoIntoTypes(ctx context.Context, c *config) ([]*Type, error) {
+	b, err := readProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	toks, err := lexProto(c.swaggerFile, b)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{c: c, file: c.swaggerFile, toks: toks}
+	if err := p.parseFile(); err != nil {
+		return nil, err
+	}
+	schemas := p.schemas()
+	if err := checkComponents(c, len(schemas.Names)); err != nil {
+		return nil, err
+	}
+	var result []*Type
+	for _, name := range schemas.Names {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("processing %s: %w", name, err)
+		}
+		result = append(result, processComponent(c, name, schemas.ByName[name])...)
+	}
+	markEnumRefs(result)
+	return canonicalOrder(splitReadOnly(c, result)), nil
+}
+
+// readProto returns the contents of the .proto file.
+func readProto(ctx context.Context, c *config) ([]byte, error) {
+	p, err := providerFor(ctx, c, c.swaggerFile)
+	if err != nil {
+		return nil, fmt.Errorf("finding %s: %w", c.swaggerFile, err)
+	}
+	fp, err := p.Open(sourceRef(c.swaggerFile))
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", c.swaggerFile, err)
+	}
+	defer fp.Close()
+	b, err := readLimited(c, c.swaggerFile, fp)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", c.swaggerFile, err)
+	}
+	return toUTF8(b)
+}
+
+// lexProto splits b into the tokens of a .proto file, identifiers keep their dots so qualified
+// names are a single token.
+func lexProto(file string, b []byte) ([]protoToken, error) {
+	s := string(b)
+	var (
+		toks []protoToken
+		doc  []string
+		// docLine is the line the comments in doc end on, they document the next token only when
+		// it is right below them.
+		docLine int
+	)
+	line := 1
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case strings.HasPrefix(s[i:], "//") || strings.HasPrefix(s[i:], "/*"):
+			var text string
+			start := line
+			if s[i+1] == '/' {
+				end := strings.IndexByte(s[i:], '\n')
+				if end < 0 {
+					end = len(s) - i
+				}
+				text = strings.TrimSpace(s[i+2 : i+end])
+				i += end
+			} else {
+				end := strings.Index(s[i+2:], "*/")
+				if end < 0 {
+					return nil, fmt.Errorf("%s:%d: the comment is never closed", file, line)
+				}
+				text = cleanBlockComment(s[i+2 : i+2+end])
+				line += strings.Count(s[i:i+2+end], "\n")
+				i += end + 4
+			}
+			if n := len(toks); n > 0 && toks[n-1].line == start && len(doc) == 0 {
+				toks[n-1].trailing = strings.TrimSpace(toks[n-1].trailing + " " + text)
+				continue
+			}
+			if docLine != 0 && start > docLine+1 {
+				doc = nil
+			}
+			doc = append(doc, text)
+			docLine = line
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(s) && rune(s[j]) != r {
+				if s[j] == '\\' {
+					j++
+				}
+				if j < len(s) && s[j] == '\n' {
+					return nil, fmt.Errorf("%s:%d: the string is never closed", file, line)
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("%s:%d: the string is never closed", file, line)
+			}
+			toks = append(toks, protoToken{text: s[i+1 : j], str: true, line: line, doc: protoDoc(doc, docLine, line)})
+			doc = nil
+			i = j + 1
+		case r == '_' || r == '.' || r == '-' || r == '+' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, protoToken{text: s[i:j], line: line, doc: protoDoc(doc, docLine, line)})
+			doc = nil
+			i = j
+		default:
+			toks = append(toks, protoToken{text: s[i : i+1], line: line, doc: protoDoc(doc, docLine, line)})
+			doc = nil
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// protoDoc returns the comments in doc when they end right above line.
+func protoDoc(doc []string, docLine, line int) string {
+	if len(doc) == 0 || line > docLine+1 {
+		return ""
+	}
+	return strings.Join(doc, "\n")
+}
+
+// cleanBlockComment returns the text of a /* */ comment without the stars starting its lines.
+func cleanBlockComment(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimSpace(l), "*")
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// peek returns the text of the next token, empty at the end of the file.
+func (p *protoParser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos].text
+}
+
+// next returns the next token, failing at the end of the file.
+func (p *protoParser) next() (protoToken, error) {
+	if p.pos >= len(p.toks) {
+		return protoToken{}, fmt.Errorf("%s: unexpected end of the file", p.file)
+	}
+	p.pos++
+	return p.toks[p.pos-1], nil
+}
+
+// expect consumes the next token, failing when it is not text.
+func (p *protoParser) expect(text string) (protoToken, error) {
+	tok, err := p.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.text != text || tok.str {
+		return tok, fmt.Errorf("%s:%d: expected %q, found %q", p.file, tok.line, text, tok.text)
+	}
+	return tok, nil
+}
+
+// skipStatement consumes the tokens up to the semicolon ending the statement, the ones of the
+// aggregate values of options included.
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.str:
+		case tok.text == "{" || tok.text == "[" || tok.text == "<":
+			depth++
+		case tok.text == "}" || tok.text == "]" || tok.text == ">":
+			depth--
+		case tok.text == ";" && depth == 0:
+			return nil
+		}
+	}
+}
+
+// skipBlock consumes the tokens up to the brace closing the block that follows, as the ones of
+// services and extensions.
+func (p *protoParser) skipBlock() error {
+	for p.peek() != "{" {
+		if _, err := p.next(); err != nil {
+			return err
+		}
+	}
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.str:
+		case tok.text == "{":
+			depth++
+		case tok.text == "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// parseFile reads the top level statements of the file.
+func (p *protoParser) parseFile() error {
+	for p.pos < len(p.toks) {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case ";":
+		case "syntax", "edition":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "package":
+			name, err := p.next()
+			if err != nil {
+				return err
+			}
+			p.pkg = name.text
+			if _, err := p.expect(";"); err != nil {
+				return err
+			}
+		case "import":
+			if p.peek() == "public" || p.peek() == "weak" {
+				p.pos++
+			}
+			name, err := p.next()
+			if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(name.text, "google/protobuf/") {
+				p.imports = append(p.imports, name.text)
+			}
+			if _, err := p.expect(";"); err != nil {
+				return err
+			}
+		case "option":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage("", tok); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum("", tok); err != nil {
+				return err
+			}
+		case "service", "extend":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s:%d: unexpected %q", p.file, tok.line, tok.text)
+		}
+	}
+	if len(p.imports) > 0 {
+		p.c.warn(p.file, "the imports %s are not read, fields of their types are left as interface{}", strings.Join(p.imports, ", "))
+	}
+	return nil
+}
+
+// parseMessage reads a message, the keyword is start, along with the messages and enums it holds.
+func (p *protoParser) parseMessage(scope string, start protoToken) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	m := &protoMessage{name: qualify(scope, name.text), comment: start.doc}
+	p.messages = append(p.messages, m)
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+	return p.parseBody(m, "")
+}
+
+// parseBody reads the body of m, or of its oneof when given, up to the closing brace.
+func (p *protoParser) parseBody(m *protoMessage, oneof string) error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case "}":
+			return nil
+		case ";":
+		case "option", "reserved", "extensions":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "extend":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage(m.name, tok); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(m.name, tok); err != nil {
+				return err
+			}
+		case "oneof":
+			group, err := p.next()
+			if err != nil {
+				return err
+			}
+			if _, err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseBody(m, group.text); err != nil {
+				return err
+			}
+		default:
+			p.pos--
+			if err := p.parseField(m, oneof); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseField reads a field of m, maps included.
+func (p *protoParser) parseField(m *protoMessage, oneof string) error {
+	first := p.toks[p.pos]
+	f := protoField{oneof: oneof, comment: first.doc}
+	switch p.peek() {
+	case "repeated", "optional", "required":
+		f.label = p.peek()
+		p.pos++
+	}
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	switch {
+	case tok.text == "group":
+		p.c.warn(m.name, "groups are not read, the group at line %d is left out", tok.line)
+		return p.skipBlock()
+	case tok.text == "map" && p.peek() == "<":
+		p.pos++
+		key, err := p.next()
+		if err != nil {
+			return err
+		}
+		if _, err := p.expect(","); err != nil {
+			return err
+		}
+		value, err := p.next()
+		if err != nil {
+			return err
+		}
+		if _, err := p.expect(">"); err != nil {
+			return err
+		}
+		f.keyType, f.typeName = key.text, value.text
+	default:
+		f.typeName = tok.text
+	}
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	f.name = name.text
+	if _, err := p.expect("="); err != nil {
+		return err
+	}
+	if _, err := p.next(); err != nil {
+		return err
+	}
+	if p.peek() == "[" {
+		p.pos++
+		if err := p.parseFieldOptions(&f); err != nil {
+			return err
+		}
+	}
+	end, err := p.expect(";")
+	if err != nil {
+		return err
+	}
+	if f.comment == "" {
+		f.comment = end.trailing
+	}
+	if f.jsonName == "" {
+		f.jsonName = protoJSONName(f.name)
+	}
+	m.fields = append(m.fields, f)
+	return nil
+}
+
+// parseFieldOptions reads the options of a field up to the closing bracket, json_name is the only
+// one kept.
+func (p *protoParser) parseFieldOptions(f *protoField) error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tok.str:
+		case tok.text == "json_name" && depth == 0 && p.peek() == "=":
+			p.pos++
+			value, err := p.next()
+			if err != nil {
+				return err
+			}
+			f.jsonName = value.text
+		case tok.text == "{" || tok.text == "[":
+			depth++
+		case tok.text == "}":
+			depth--
+		case tok.text == "]":
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+// parseEnum reads an enum, the keyword is start.
+func (p *protoParser) parseEnum(scope string, start protoToken) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	e := &protoEnum{name: qualify(scope, name.text), comment: start.doc}
+	p.enums = append(p.enums, e)
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case "}":
+			return nil
+		case ";":
+		case "option", "reserved":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			e.values = append(e.values, tok.text)
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// qualify returns name within scope, dot separated.
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// protoJSONName returns the lowerCamelCase name the JSON mapping gives a field.
+func protoJSONName(name string) string {
+	b := &strings.Builder{}
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// protoSchemaName returns the name of the schema of a message or enum, the nested ones are
+// joined by an underscore so they are capitalized as a single name.
+func protoSchemaName(name string) string {
+	return strings.Replace(name, ".", "_", -1)
+}
+
+// resolve returns the message or enum named ref from within scope, as protobuf looks names up
+// from the innermost scope out.
+func (p *protoParser) resolve(scope, ref string) (*protoMessage, *protoEnum) {
+	find := func(name string) (*protoMessage, *protoEnum) {
+		for _, m := range p.messages {
+			if m.name == name {
+				return m, nil
+			}
+		}
+		for _, e := range p.enums {
+			if e.name == name {
+				return nil, e
+			}
+		}
+		return nil, nil
+	}
+	if strings.HasPrefix(ref, ".") {
+		ref = strings.TrimPrefix(ref[1:], p.pkg+".")
+		return find(ref)
+	}
+	if p.pkg != "" && strings.HasPrefix(ref, p.pkg+".") {
+		if m, e := find(strings.TrimPrefix(ref, p.pkg+".")); m != nil || e != nil {
+			return m, e
+		}
+	}
+	for {
+		if m, e := find(qualify(scope, ref)); m != nil || e != nil || scope == "" {
+			return m, e
+		}
+		if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// fieldSchema returns the schema of the values of f, a field of the message in scope.
+func (p *protoParser) fieldSchema(scope string, f protoField) MetaSwaggerProperty {
+	if s, ok := protoScalars[f.typeName]; ok {
+		return s
+	}
+	if s, ok := protoWellKnown[strings.TrimPrefix(f.typeName, ".")]; ok {
+		return s
+	}
+	m, e := p.resolve(scope, f.typeName)
+	switch {
+	case m != nil:
+		return MetaSwaggerProperty{Ref: "#/components/schemas/" + protoSchemaName(m.name)}
+	case e != nil && p.c.enums:
+		return MetaSwaggerProperty{Ref: "#/components/schemas/" + protoSchemaName(e.name)}
+	case e != nil:
+		values := make([]interface{}, 0, len(e.values))
+		for _, v := range e.values {
+			values = append(values, v)
+		}
+		return MetaSwaggerProperty{Type: STString, Enum: values}
+	}
+	p.c.warn(scope, "the type %s of the field %s is not declared in the file, it is left as interface{}", f.typeName, f.name)
+	return MetaSwaggerProperty{GoType: "interface{}"}
+}
+
+// protoEnumNames returns the names of the constants of the values of an enum, the way
+// protoc-gen-go names them: the UPPER_SNAKE name of the enum is the usual prefix of its values so
+// it is left out, STATUS_OPEN of Status is StatusOpen. Values that are not UPPER_SNAKE are named
+// like the values of any enum.
+func protoEnumNames(e *protoEnum) []string {
+	name := e.name[strings.LastIndex(e.name, ".")+1:]
+	prefix := envName(name) + "_"
+	names := make([]string, len(e.values))
+	for i, v := range e.values {
+		if v != strings.ToUpper(v) {
+			continue
+		}
+		n := strings.TrimPrefix(v, prefix)
+		if n == "" || (n[0] >= '0' && n[0] <= '9') {
+			n = v
+		}
+		names[i] = capitalize(strings.ToLower(n))
+	}
+	return names
+}
+
+// schemas returns the schemas of the messages and, with --enums, of the enums of the file.
+func (p *protoParser) schemas() SwaggerSchemas {
+	schemas := SwaggerSchemas{ByName: map[string]SwaggerSchema{}}
+	add := func(name string, s SwaggerSchema) {
+		name = protoSchemaName(name)
+		if _, dup := schemas.ByName[name]; dup {
+			p.c.warn(p.file, "%s is declared twice, the last one is kept", name)
+		} else {
+			schemas.Names = append(schemas.Names, name)
+		}
+		schemas.ByName[name] = s
+	}
+	for _, m := range p.messages {
+		s := SwaggerSchema{Type: STObject, Description: m.comment, Properties: SwaggerProperties{ByName: map[string]SwaggerProperty{}}}
+		oneofs := map[string][]string{}
+		for _, f := range m.fields {
+			if f.oneof != "" {
+				oneofs[f.oneof] = append(oneofs[f.oneof], f.jsonName)
+			}
+		}
+		for _, f := range m.fields {
+			values := p.fieldSchema(m.name, f)
+			var prop SwaggerProperty
+			switch {
+			case f.keyType != "":
+				prop.Type = STObject
+				prop.AdditionalProperties = &SwaggerProperty{MetaSwaggerProperty: values}
+				// the values of maps are made of their schema alone, the Go type is the map's.
+				switch {
+				case values.GoType != "":
+					prop.GoType = "map[string]" + strings.TrimPrefix(values.GoType, "*")
+				case values.Format == "date-time":
+					prop.GoType = "map[string]time.Time"
+					prop.GoTypeImport = &SwaggerGoImport{Path: "time"}
+				}
+			case f.label == "repeated":
+				prop.Type = STArray
+				prop.Items = SwaggerItems{MetaSwaggerProperty: values}
+			default:
+				prop.MetaSwaggerProperty = values
+			}
+			prop.Description = f.comment
+			if f.oneof != "" {
+				members := joinNames(oneofs[f.oneof])
+				if f.comment == "" {
+					prop.Description = fmt.Sprintf("member of the oneof %s, only one of %s is set.", f.oneof, members)
+				} else {
+					prop.Description = fmt.Sprintf("%s\n\nIt is a member of the oneof %s, only one of %s is set.", f.comment, f.oneof, members)
+				}
+			}
+			// optional fields and the members of oneofs tell a field left out from one holding
+			// its zero value.
+			if (f.label == "optional" || f.oneof != "") && values.GoType == "" {
+				prop.Nullable = true
+			}
+			if f.label == "required" {
+				s.Required.Names = append(s.Required.Names, f.jsonName)
+			}
+			if _, dup := s.Properties.ByName[f.jsonName]; !dup {
+				s.Properties.Names = append(s.Properties.Names, f.jsonName)
+			}
+			s.Properties.ByName[f.jsonName] = prop
+		}
+		add(m.name, s)
+	}
+	if p.c.enums {
+		for _, e := range p.enums {
+			values := make([]interface{}, 0, len(e.values))
+			for _, v := range e.values {
+				values = append(values, v)
+			}
+			add(e.name, SwaggerSchema{Type: STString, Description: e.comment, Enum: values, enumNames: protoEnumNames(e)})
+		}
+	}
+	return schemas
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProtoSchemas(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		src   string
+		enums bool
+		// names are the schemas in the order they are made.
+		names []string
+		// props are the properties expected, keyed by schema.property.
+		props map[string]SwaggerProperty
+		// required are the required properties expected, keyed by schema.
+		required map[string][]string
+		// enumNames are the constant names expected, keyed by schema.
+		enumNames map[string][]string
+		err       string
+	}{
+		{
+			name: "message",
+			src: `syntax = "proto3";
+package demo;
+option go_package = "demo";
+
+// A user.
+message User {
+  string user_name = 1; // how it is called.
+  int64 id = 2 [json_name = "key"];
+  repeated string tags = 3;
+  optional bool active = 4;
+}`,
+			names: []string{"User"},
+			props: map[string]SwaggerProperty{
+				"User.userName": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString, Description: "how it is called."}},
+				"User.key":      {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString, GoType: "encoding/json.Number"}},
+				"User.tags":     {MetaSwaggerProperty: MetaSwaggerProperty{Type: STArray}, Items: SwaggerItems{MetaSwaggerProperty: MetaSwaggerProperty{Type: STString}}},
+				"User.active":   {MetaSwaggerProperty: MetaSwaggerProperty{Type: STBoolean, Nullable: true}},
+			},
+		},
+		{
+			name: "proto2 required",
+			src: `syntax = "proto2";
+message Pet {
+  required string name = 1;
+  optional int32 age = 2;
+}`,
+			names: []string{"Pet"},
+			props: map[string]SwaggerProperty{
+				"Pet.name": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString}},
+				"Pet.age":  {MetaSwaggerProperty: MetaSwaggerProperty{Type: STInteger, Format: "int32", Nullable: true}},
+			},
+			required: map[string][]string{"Pet": {"name"}},
+		},
+		{
+			name: "nested types",
+			src: `syntax = "proto3";
+package demo;
+message Order {
+  message Item {
+    string sku = 1;
+  }
+  repeated Item items = 1;
+  Order.Item first = 2;
+  .demo.Order.Item last = 3;
+}`,
+			names: []string{"Order", "Order_Item"},
+			props: map[string]SwaggerProperty{
+				"Order.items":    {MetaSwaggerProperty: MetaSwaggerProperty{Type: STArray}, Items: SwaggerItems{MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/Order_Item"}}},
+				"Order.first":    {MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/Order_Item"}},
+				"Order.last":     {MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/Order_Item"}},
+				"Order_Item.sku": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString}},
+			},
+		},
+		{
+			name: "enums",
+			src: `syntax = "proto3";
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_DARK_RED = 1;
+  BLUE = 2;
+  COLOR_2D = 3;
+  Green = 4;
+}
+message PhoneNumber {
+  enum PhoneType {
+    PHONE_TYPE_MOBILE = 0;
+    PHONE_TYPE_HOME = 1 [deprecated = true];
+  }
+  PhoneType type = 1;
+  Color color = 2;
+}`,
+			enums: true,
+			names: []string{"PhoneNumber", "Color", "PhoneNumber_PhoneType"},
+			props: map[string]SwaggerProperty{
+				"PhoneNumber.type":  {MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/PhoneNumber_PhoneType"}},
+				"PhoneNumber.color": {MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/Color"}},
+			},
+			enumNames: map[string][]string{
+				"Color":                 {"Unspecified", "DarkRed", "Blue", "Color2d", ""},
+				"PhoneNumber_PhoneType": {"Mobile", "Home"},
+			},
+		},
+		{
+			name: "enums inlined",
+			src: `syntax = "proto3";
+enum Size {
+  SIZE_SMALL = 0;
+  SIZE_LARGE = 1;
+}
+message Shirt {
+  Size size = 1;
+}`,
+			names: []string{"Shirt"},
+			props: map[string]SwaggerProperty{
+				"Shirt.size": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString, Enum: []interface{}{"SIZE_SMALL", "SIZE_LARGE"}}},
+			},
+		},
+		{
+			name: "oneof",
+			src: `syntax = "proto3";
+message Payment {
+  oneof method {
+    // the card number.
+    string card = 1;
+    int32 cash_cents = 2;
+  }
+}`,
+			names: []string{"Payment"},
+			props: map[string]SwaggerProperty{
+				"Payment.card":      {MetaSwaggerProperty: MetaSwaggerProperty{Type: STString, Nullable: true, Description: "the card number.\n\nIt is a member of the oneof method, only one of card or cashCents is set."}},
+				"Payment.cashCents": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STInteger, Format: "int32", Nullable: true, Description: "member of the oneof method, only one of card or cashCents is set."}},
+			},
+		},
+		{
+			name: "maps",
+			src: `syntax = "proto3";
+message Inventory {
+  message Item {
+    string sku = 1;
+  }
+  map<string, int32> counts = 1;
+  map<int64, Item> items = 2;
+  map<string, int64> totals = 3;
+}`,
+			names: []string{"Inventory", "Inventory_Item"},
+			props: map[string]SwaggerProperty{
+				"Inventory.counts": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STObject}, AdditionalProperties: &SwaggerProperty{MetaSwaggerProperty: MetaSwaggerProperty{Type: STInteger, Format: "int32"}}},
+				"Inventory.items":  {MetaSwaggerProperty: MetaSwaggerProperty{Type: STObject}, AdditionalProperties: &SwaggerProperty{MetaSwaggerProperty: MetaSwaggerProperty{Ref: "#/components/schemas/Inventory_Item"}}},
+				"Inventory.totals": {MetaSwaggerProperty: MetaSwaggerProperty{Type: STObject, GoType: "map[string]encoding/json.Number"}, AdditionalProperties: &SwaggerProperty{MetaSwaggerProperty: MetaSwaggerProperty{Type: STString, GoType: "encoding/json.Number"}}},
+			},
+		},
+		{
+			name: "services are skipped",
+			src: `syntax = "proto3";
+message Empty {}
+service Greeter {
+  rpc Hello (Empty) returns (Empty) {
+    option deprecated = true;
+  }
+}`,
+			names: []string{"Empty"},
+		},
+		{
+			name: "unexpected token",
+			src:  `syntax = "proto3"; mesage Foo {}`,
+			err:  `a.proto:1: unexpected "mesage"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &config{enums: tc.enums, warningHandler: func(warning) {}}
+			toks, err := lexProto("a.proto", []byte(tc.src))
+			if err != nil {
+				t.Fatalf("lexing: %v", err)
+			}
+			p := &protoParser{c: c, file: "a.proto", toks: toks}
+			err = p.parseFile()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			schemas := p.schemas()
+			if !reflect.DeepEqual(schemas.Names, tc.names) {
+				t.Errorf("got schemas %v, want %v", schemas.Names, tc.names)
+			}
+			for key, want := range tc.props {
+				i := strings.IndexByte(key, '.')
+				got, ok := schemas.ByName[key[:i]].Properties.ByName[key[i+1:]]
+				if !ok {
+					t.Errorf("%s is missing", key)
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s is %+v, want %+v", key, got, want)
+				}
+			}
+			for name, want := range tc.required {
+				if got := schemas.ByName[name].Required.Names; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s requires %v, want %v", name, got, want)
+				}
+			}
+			for name, want := range tc.enumNames {
+				if got := schemas.ByName[name].enumNames; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s names its values %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProtoJSONName(t *testing.T) {
+	for in, want := range map[string]string{
+		"name":          "name",
+		"user_name":     "userName",
+		"user_id_2":     "userId2",
+		"already_Camel": "alreadyCamel",
+	} {
+		if got := protoJSONName(in); got != want {
+			t.Errorf("protoJSONName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+// defaultRequestSuffix is appended to the names of the request types when --split-read-only is
+// passed without a suffix.
+const defaultRequestSuffix = "Create"
+
+// splitReadOnly adds, for every struct with readOnly fields, a request type named after it with
+// the --split-read-only suffix that leaves them out, servers rejecting the fields they set
+// themselves refuse the full one. Structs referencing those get a request type too, whose fields
+// reference the request types instead.
+func splitReadOnly(c *config, types []*Type) []*Type {
+	if c.requestSuffix == "" {
+		return types
+	}
+	taken := make(map[string]bool, len(types))
+	for _, t := range types {
+		taken[capitalize(t.Name)] = true
+	}
+	// requests maps the names of the types to the ones of their request types, the ones that
+	// can not have one because the name is taken are visited but left out.
+	requests := map[string]string{}
+	visited := map[string]bool{}
+	need := func(t *Type) {
+		visited[t.Name] = true
+		name := t.Name + c.requestSuffix
+		if taken[capitalize(name)] {
+			c.warn(t.Name, "no request type is generated as %s is taken", capitalize(name))
+			return
+		}
+		taken[capitalize(name)] = true
+		requests[t.Name] = name
+	}
+	for _, t := range types {
+		if splittable(t) && hasReadOnly(t) {
+			need(t)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, t := range types {
+			if splittable(t) && !visited[t.Name] && referencesAny(t, requests) {
+				need(t)
+				changed = true
+			}
+		}
+	}
+	var split []*Type
+	for _, t := range types {
+		name, ok := requests[t.Name]
+		if !ok {
+			continue
+		}
+		request := &Type{
+			Name:        name,
+			Source:      t.Source,
+			Description: t.Description,
+		}
+		for _, fld := range t.Fields {
+			if !fld.Type.readOnly {
+				request.Fields = append(request.Fields, fld)
+			}
+		}
+		split = append(split, request)
+	}
+	for _, t := range split {
+		for i := range t.Fields {
+			f := &t.Fields[i].Type
+			if name, ok := requests[f.nameOftype]; ok {
+				f.nameOftype = name
+			}
+			if len(f.multiType) == 0 {
+				continue
+			}
+			multi := make([]string, len(f.multiType))
+			for j, mt := range f.multiType {
+				multi[j] = mt
+				if name, ok := requests[mt]; ok {
+					multi[j] = name
+				}
+			}
+			f.multiType = multi
+		}
+	}
+	return append(types, split...)
+}
+
+// splittable returns true for the types that can have a request type, structs.
+func splittable(t *Type) bool {
+	return len(t.Enum) == 0 && t.union == nil && !t.tuple
+}
+
+// hasReadOnly returns true if any of the fields of t is readOnly.
+func hasReadOnly(t *Type) bool {
+	for _, fld := range t.Fields {
+		if fld.Type.readOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesAny returns true if a field of t references, or embeds, one of names.
+func referencesAny(t *Type, names map[string]string) bool {
+	for _, fld := range t.Fields {
+		if _, ok := names[fld.Type.nameOftype]; ok {
+			return true
+		}
+		for _, mt := range fld.Type.multiType {
+			if _, ok := names[mt]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// refResolver folds the schemas referenced in other documents into the spec, each document is
+// loaded once and each schema folded once however many times it is referenced.
+type refResolver struct {
+	ctx context.Context
+	c   *config
+	// main is the location of the spec, cleaned up like the ones of the documents it references.
+	main string
+	// docs caches the documents by location.
+	docs map[string]interface{}
+	// folded holds the names given to the schemas already folded, by location#pointer.
+	folded map[string]string
+	// taken holds the names of the schemas of the spec, folded ones included.
+	taken map[string]bool
+	// prefix is the pointer to the schemas of the spec, ie #/components/schemas/.
+	prefix string
+	// names and schemas are the folded schemas in the order they were found.
+	names   []string
+	schemas []interface{}
+}
+
+// resolveExternalRefs returns spec with the schemas it references in other documents, by path
+// relative to the spec or URL, added to its own and the refs pointing to them instead.
+func resolveExternalRefs(ctx context.Context, c *config, spec []byte) ([]byte, error) {
+	if !bytes.Contains(spec, []byte(`"$ref"`)) {
+		return spec, nil
+	}
+	doc, err := decodeDocument(spec)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", c.swaggerFile, err)
+	}
+	root, ok := doc.(*jsonObject)
+	if !ok {
+		return spec, nil
+	}
+	container := schemasPath(c, root)
+	main := relativeLocation(c.swaggerFile, path.Base(c.swaggerFile))
+	r := &refResolver{
+		ctx:    ctx,
+		c:      c,
+		main:   main,
+		docs:   map[string]interface{}{main: doc},
+		folded: map[string]string{},
+		taken:  map[string]bool{},
+		prefix: "#/" + strings.Join(container, "/") + "/",
+	}
+	if schemas, ok := lookupPointer(root, container).(*jsonObject); ok {
+		for _, k := range schemas.keys {
+			r.taken[k] = true
+		}
+	}
+	if err := r.walk(doc, main); err != nil {
+		return nil, err
+	}
+	if len(r.names) == 0 {
+		return spec, nil
+	}
+	schemas := ensureObject(root, container)
+	for i, n := range r.names {
+		schemas.set(n, r.schemas[i])
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeDocument(buf, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// schemasPath returns the keys leading to the schemas of the spec, which depend on its kind.
+func schemasPath(c *config, root *jsonObject) []string {
+	if c.jsonSchema {
+		return []string{"$defs"}
+	}
+	if v, _ := root.get("swagger").(string); strings.HasPrefix(v, "2") {
+		return []string{"definitions"}
+	}
+	return []string{"components", "schemas"}
+}
+
+// walk replaces the refs found in v, which comes from the document at base, with refs to the
+// schemas of the spec.
+func (r *refResolver) walk(v interface{}, base string) error {
+	switch n := v.(type) {
+	case *jsonObject:
+		for i, k := range n.keys {
+			if ref, ok := n.values[i].(string); ok && k == "$ref" {
+				resolved, err := r.resolve(ref, base)
+				if err != nil {
+					return err
+				}
+				n.values[i] = resolved
+				continue
+			}
+			if err := r.walk(n.values[i], base); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, e := range n {
+			if err := r.walk(e, base); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the ref of the spec for ref, as found in the document at base, folding the
+// schema it points to if it is in another document.
+func (r *refResolver) resolve(ref, base string) (string, error) {
+	file, pointer := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		file, pointer = ref[:i], ref[i+1:]
+	}
+	location := base
+	if file != "" {
+		location = relativeLocation(base, file)
+	}
+	// refs within the spec, including the ones other documents make back to it, stay.
+	if location == r.main {
+		return "#" + pointer, nil
+	}
+	key := location + "#" + pointer
+	if name, ok := r.folded[key]; ok {
+		return r.prefix + name, nil
+	}
+	if err := r.ctx.Err(); err != nil {
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	doc, ok := r.docs[location]
+	if !ok {
+		r.c.debugf("loading %s for %s\n", location, ref)
+		var (
+			b   []byte
+			err error
+		)
+		if isRemote(location) {
+			b, err = fetchRef(r.ctx, r.c, location)
+		} else {
+			b, err = readDocument(r.ctx, r.c, location, swaggerFormatAuto)
+		}
+		if err != nil {
+			return "", fmt.Errorf("resolving %s: %w", ref, err)
+		}
+		doc, err = decodeDocument(b)
+		if err != nil {
+			return "", fmt.Errorf("decoding %s: %w", location, locateError(location, b, err, -1))
+		}
+		r.docs[location] = doc
+	}
+	parts := pointerParts(pointer)
+	schema := lookupPointer(doc, parts)
+	if schema == nil {
+		return "", fmt.Errorf("resolving %s: %s has nothing at #%s", ref, location, pointer)
+	}
+	name := strings.Split(path.Base(location), ".")[0]
+	if len(parts) > 0 {
+		name = parts[len(parts)-1]
+	}
+	if r.taken[name] {
+		unique := name
+		for i := 2; r.taken[unique]; i++ {
+			unique = name + strconv.Itoa(i)
+		}
+		r.c.warn(ref, "the schema %s is taken, the one referenced is named %s", name, unique)
+		name = unique
+	}
+	r.taken[name] = true
+	r.folded[key] = name
+	r.names = append(r.names, name)
+	r.schemas = append(r.schemas, schema)
+	// the refs of the folded schema are relative to its own document.
+	if err := r.walk(schema, location); err != nil {
+		return "", err
+	}
+	return r.prefix + name, nil
+}
+
+// relativeLocation returns the location of file, a path or URL, relative to the document at base.
+func relativeLocation(base, file string) string {
+	if strings.Contains(file, "://") || filepath.IsAbs(file) {
+		return file
+	}
+	if strings.Contains(base, "://") {
+		if u, err := url.Parse(base); err == nil {
+			if ref, err := url.Parse(file); err == nil {
+				return u.ResolveReference(ref).String()
+			}
+		}
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(file))
+}
+
+// pointerParts splits a JSON pointer into its unescaped keys.
+func pointerParts(pointer string) []string {
+	pointer = strings.Trim(pointer, "/")
+	if pointer == "" {
+		return nil
+	}
+	if unescaped, err := url.PathUnescape(pointer); err == nil {
+		pointer = unescaped
+	}
+	parts := strings.Split(pointer, "/")
+	for i, p := range parts {
+		parts[i] = strings.Replace(strings.Replace(p, "~1", "/", -1), "~0", "~", -1)
+	}
+	return parts
+}
+
+// lookupPointer returns the value at the keys in parts of v, nil if there is none.
+func lookupPointer(v interface{}, parts []string) interface{} {
+	for _, p := range parts {
+		switch n := v.(type) {
+		case *jsonObject:
+			v = n.get(p)
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			v = n[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// ensureObject returns the object at the keys in parts of root, creating the missing ones.
+func ensureObject(root *jsonObject, parts []string) *jsonObject {
+	o := root
+	for _, p := range parts {
+		next, ok := o.get(p).(*jsonObject)
+		if !ok {
+			next = &jsonObject{}
+			o.set(p, next)
+		}
+		o = next
+	}
+	return o
+}
+
+// decodeDocument decodes b keeping the order of keys and the exact numbers.
+func decodeDocument(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeOrdered(dec, "", nil)
+}
+
+// encodeDocument writes v, as decoded by decodeDocument, as JSON into buf.
+func encodeDocument(buf *bytes.Buffer, v interface{}) error {
+	switch n := v.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, k := range n.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			kb, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			buf.Write(kb)
+			buf.WriteByte(':')
+			if err := encodeDocument(buf, n.values[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range n {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeDocument(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
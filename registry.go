@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeRegistry holds the types inferred so far, indexed so finding an existing type with the
+// same name or the same shape does not require scanning every known type.
+type TypeRegistry struct {
+	types  []*Type
+	byName map[string]*Type
+	// bySuffix maps the last segment of a type name (the name in parent.name) to every type
+	// ending in it, in registration order, so resolution is stable across runs.
+	bySuffix map[string][]*Type
+	// byShape maps the structural hash of a type to the types with that hash.
+	byShape map[uint64][]*Type
+	// shapes remembers the hash each type was indexed with, so it can be reindexed on merge.
+	shapes map[*Type]uint64
+}
+
+func newTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byName:   map[string]*Type{},
+		bySuffix: map[string][]*Type{},
+		byShape:  map[uint64][]*Type{},
+		shapes:   map[*Type]uint64{},
+	}
+}
+
+// Types returns the registered types in canonical order.
+func (r *TypeRegistry) Types() []*Type {
+	types := make([]*Type, len(r.types))
+	copy(types, r.types)
+	return canonicalOrder(types)
+}
+
+// suffix returns the last segment of a possibly parented type name.
+func suffix(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name
+	}
+	return name[i+1:]
+}
+
+// hashSignature adds a canonical description of the field type to the FNV-1a hash h, used for
+// shape hashing.
+func (m *maybeType) hashSignature(h uint64) uint64 {
+	if m.isArray {
+		h = fnvAdd(h, "[]")
+	}
+	switch {
+	case len(m.multiType) > 0:
+		for _, mt := range m.multiType {
+			h = fnvAdd(h, "|")
+			h = fnvAdd(h, mt)
+		}
+	case m.typeOf != nil:
+		h = fnvAdd(h, m.typeOf.PkgPath())
+		h = fnvAdd(h, ".")
+		h = fnvAdd(h, m.typeOf.Name())
+	default:
+		h = fnvAdd(h, m.nameOftype)
+	}
+	return h
+}
+
+// add registers a new type under name and indexes it, the fields are copied as callers might be
+// building types on reused buffers.
+func (r *TypeRegistry) add(name string, t *Type) {
+	t.Name = name
+	t.Fields = append(make([]Field, 0, len(t.Fields)), t.Fields...)
+	t.index = nil
+	r.types = append(r.types, t)
+	r.byName[name] = t
+	s := suffix(name)
+	r.bySuffix[s] = append(r.bySuffix[s], t)
+	r.reindex(t)
+}
+
+// reindex updates the shape index of t after its fields changed.
+func (r *TypeRegistry) reindex(t *Type) {
+	if old, ok := r.shapes[t]; ok {
+		types := r.byShape[old]
+		for i, o := range types {
+			if o == t {
+				r.byShape[old] = append(types[:i:i], types[i+1:]...)
+				break
+			}
+		}
+	}
+	h := t.Shape()
+	r.shapes[t] = h
+	r.byShape[h] = append(r.byShape[h], t)
+}
+
+// compatible returns false if any field present in both types has a different type.
+func compatible(existing, ours *Type) bool {
+	for _, f := range ours.Fields {
+		ef := existing.Field(f.Name)
+		if ef == nil {
+			continue
+		}
+		if !f.Type.Equals(&ef.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve finds a type matching name whose fields are compatible with ours and merges ours into
+// it, otherwise it registers ours, prefixing the name with parent if it is already taken. It
+// returns the final name of the type and whether it already existed.
+func (r *TypeRegistry) Resolve(name, parent string, c *config, ours *Type) (string, bool) {
+	foundName := name
+	ours.original = name
+	c.debugf("looking for type: %s\n", foundName)
+	newName, ok := c.fileTypeMap[foundName]
+	if ok {
+		foundName = newName
+		c.debugf("renamed to: %s\n", foundName)
+	}
+	decision := TypeRename{Source: ours.Source, Original: name, Parent: parent, StructNames: ok}
+	foundName = normalizeNames(foundName, c.targetPackage)
+	c.debugf("normalized to: %s\n", foundName)
+
+	// a type with the exact same shape and name is the cheapest match.
+	for _, t := range r.byShape[ours.Shape()] {
+		if suffix(t.Name) == foundName && len(t.Fields) == len(ours.Fields) && compatible(t, ours) {
+			decision.Name, decision.Decision = t.Name, DecisionMerged
+			c.report.typeResolved(decision)
+			return t.Name, true
+		}
+	}
+
+	candidates := r.bySuffix[foundName]
+	if len(candidates) == 0 {
+		c.debugf("it's new\n")
+		r.add(foundName, ours)
+		decision.Name, decision.Decision = foundName, DecisionNew
+		c.report.typeResolved(decision)
+		return foundName, false
+	}
+
+	// the unparented name is preferred, then parented ones in the order they were found.
+	ordered := make([]*Type, 0, len(candidates))
+	if t, exists := r.byName[foundName]; exists {
+		ordered = append(ordered, t)
+	}
+	for _, t := range candidates {
+		if t.Name != foundName {
+			ordered = append(ordered, t)
+		}
+	}
+	for _, existing := range ordered {
+		if !compatible(existing, ours) {
+			continue
+		}
+		if existing.Name != foundName {
+			c.debugf("it exists parented: %s\n", existing.Name)
+		}
+		for _, f := range ours.Fields {
+			if existing.Field(f.Name) == nil {
+				existing.AddField(f)
+			}
+		}
+		r.reindex(existing)
+		decision.Name, decision.Decision = existing.Name, DecisionMerged
+		c.report.typeResolved(decision)
+		return existing.Name, true
+	}
+
+	decision.Decision = DecisionParented
+	newName = fmt.Sprintf("%s.%s", parent, foundName)
+	for i := 2; ; i++ {
+		if _, taken := r.byName[newName]; !taken {
+			break
+		}
+		decision.Decision = DecisionNumbered
+		newName = fmt.Sprintf("%s.%s%d", parent, foundName, i)
+	}
+	r.add(newName, ours)
+	decision.Name = newName
+	c.report.typeResolved(decision)
+	if c.report != nil {
+		names := make([]string, 0, len(ordered)+1)
+		for _, t := range ordered {
+			names = append(names, t.Name)
+		}
+		c.report.collision(Collision{
+			Kind:       "type",
+			GoName:     capitalize(foundName),
+			Names:      append(names, newName),
+			Resolution: fmt.Sprintf("incompatible types, %s %s as %s", name, decision.Decision, newName),
+		})
+	}
+	return newName, false
+}
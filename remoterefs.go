@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isRemote returns true for locations fetched over the network.
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// refCacheDir returns the directory where remote documents are cached, --ref-cache or the cache
+// directory of the user.
+func refCacheDir(c *config) (string, error) {
+	if c.refCache != "" {
+		return c.refCache, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the cache directory, pass --ref-cache: %w", err)
+	}
+	return filepath.Join(dir, "LAC", "refs"), nil
+}
+
+// refCachePath returns the file holding the cached document at location, as JSON.
+func refCachePath(c *config, location string) (string, error) {
+	dir, err := refCacheDir(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// fetchRef returns the document referenced at a remote location as JSON. It is fetched within
+// --ref-timeout and cached so that --ref-offline can generate without the network.
+func fetchRef(ctx context.Context, c *config, location string) ([]byte, error) {
+	cached, err := refCachePath(c, location)
+	if err != nil {
+		return nil, err
+	}
+	if c.refOffline {
+		b, err := readFile(c.files(), cached)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s is not cached, run once without --ref-offline", location)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the cached %s: %w", location, err)
+		}
+		c.debugf("using the cached %s\n", location)
+		return b, nil
+	}
+	if c.refTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.refTimeout)
+		defer cancel()
+	}
+	b, err := readDocument(ctx, c, location, swaggerFormatAuto)
+	if err != nil {
+		return nil, err
+	}
+	// a cache that can not be written only costs fetching again.
+	if err := c.files().MkdirAll(filepath.Dir(cached)); err != nil {
+		c.warn(location, "the document can not be cached: %v", err)
+		return b, nil
+	}
+	if err := writeFile(c, cached, func(out io.Writer) error {
+		_, err := out.Write(b)
+		return err
+	}); err != nil {
+		c.warn(location, "the document can not be cached: %v", err)
+	}
+	return b, nil
+}
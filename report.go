@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Naming decisions taken when registering a type.
+const (
+	// DecisionNew is a type registered under its own name.
+	DecisionNew = "new"
+	// DecisionMerged is a type folded into an existing compatible one.
+	DecisionMerged = "merged"
+	// DecisionParented is a type that clashed with an incompatible one and got its parent name
+	// prepended.
+	DecisionParented = "parented"
+	// DecisionNumbered is a type that clashed even after being parented and got a number appended.
+	DecisionNumbered = "numbered"
+	// DecisionAsIs is a type taken as named in the source, such as swagger components.
+	DecisionAsIs = "as is"
+)
+
+// TypeRename records how a name found in the source ended up as a Go type.
+type TypeRename struct {
+	Source   string `json:"source,omitempty"`
+	Original string `json:"original"`
+	Parent   string `json:"parent,omitempty"`
+	// StructNames is set when --structnames replaced the original name.
+	StructNames bool   `json:"structnames,omitempty"`
+	Name        string `json:"name"`
+	GoName      string `json:"goName"`
+	Decision    string `json:"decision"`
+}
+
+// FieldRename records the Go name of a field.
+type FieldRename struct {
+	Type     string `json:"type"`
+	Original string `json:"original"`
+	GoName   string `json:"goName"`
+}
+
+// Collision records names that would have ended up as the same identifier, Resolution explains
+// what was done about it and is empty when nothing could be done, which means the generated code
+// will not compile.
+type Collision struct {
+	Kind       string   `json:"kind"`
+	GoName     string   `json:"goName"`
+	Names      []string `json:"names"`
+	Resolution string   `json:"resolution,omitempty"`
+}
+
+// renameReport collects the naming decisions taken during generation so reviewers and tooling can
+// track them, a nil report records nothing.
+type renameReport struct {
+	Types      []TypeRename  `json:"types"`
+	Fields     []FieldRename `json:"fields"`
+	Collisions []Collision   `json:"collisions"`
+
+	seen map[TypeRename]bool
+}
+
+func newRenameReport() *renameReport {
+	return &renameReport{
+		Types:      []TypeRename{},
+		Fields:     []FieldRename{},
+		Collisions: []Collision{},
+		seen:       map[TypeRename]bool{},
+	}
+}
+
+// typeResolved records a decision of the registry, the same decision is only recorded once no
+// matter how many times the name was found.
+func (r *renameReport) typeResolved(tr TypeRename) {
+	if r == nil {
+		return
+	}
+	tr.GoName = capitalize(tr.Name)
+	if r.seen[tr] {
+		return
+	}
+	r.seen[tr] = true
+	r.Types = append(r.Types, tr)
+}
+
+// collision records names that clash.
+func (r *renameReport) collision(col Collision) {
+	if r == nil {
+		return
+	}
+	r.Collisions = append(r.Collisions, col)
+}
+
+// finish records the types not resolved through the registry and the names of every field, then
+// looks for the clashes left in the Go identifiers.
+func (r *renameReport) finish(c *config, types []*Type) {
+	if r == nil {
+		return
+	}
+	recorded := map[string]bool{}
+	for _, tr := range r.Types {
+		recorded[tr.Name] = true
+	}
+	byGoName := map[string][]string{}
+	for _, t := range types {
+		structName := capitalize(t.Name)
+		byGoName[structName] = append(byGoName[structName], t.Name)
+		if !recorded[t.Name] {
+			r.typeResolved(TypeRename{Source: t.Source, Original: t.Name, Name: t.Name, Decision: DecisionAsIs})
+		}
+		fields := map[string][]string{}
+		for _, fld := range t.Fields {
+			if fld.Name == "" {
+				continue
+			}
+			goName := fld.GoName()
+			fields[goName] = append(fields[goName], fld.Name)
+			r.Fields = append(r.Fields, FieldRename{Type: structName, Original: fld.Name, GoName: goName})
+		}
+		r.unresolved(c, "field", structName+".", fields)
+	}
+	r.unresolved(c, "type", "", byGoName)
+}
+
+// unresolved records, and warns about, the Go names claimed by more than one name.
+func (r *renameReport) unresolved(c *config, kind, prefix string, byGoName map[string][]string) {
+	clashing := make([]string, 0)
+	for goName, names := range byGoName {
+		if len(names) > 1 {
+			clashing = append(clashing, goName)
+		}
+	}
+	sort.Strings(clashing)
+	for _, goName := range clashing {
+		c.warn(prefix+goName, "%s name used by %q", kind, byGoName[goName])
+		r.collision(Collision{Kind: kind, GoName: prefix + goName, Names: byGoName[goName]})
+	}
+}
+
+// write saves the report as JSON.
+func (r *renameReport) write(c *config, name string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding rename report: %w", err)
+	}
+	if err := writeFile(c, name, func(out io.Writer) error {
+		_, err := out.Write(append(b, '\n'))
+		return err
+	}); err != nil {
+		return fmt.Errorf("writing rename report: %w", err)
+	}
+	return nil
+}
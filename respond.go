@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// responseWriter is a helper of the server writing the responses of an operation whose body has
+// one type, checking that their status is one the spec pairs with it.
+type responseWriter struct {
+	name, body string
+	// responses are the ones with the body, in the order of their statuses.
+	responses []apiResponse
+}
+
+// responseWriters returns the helpers writing the responses of op with a body, one per type. The
+// one of the 2xx responses is Write<Operation>Response and the others are named after the status
+// of their first response, as in Write<Operation>NotFoundResponse.
+func responseWriters(op apiOperation) []responseWriter {
+	var result []responseWriter
+	byBody := map[string]int{}
+	for _, resp := range op.responses {
+		if resp.body == "" || !validStatus(resp.status) {
+			continue
+		}
+		if i, ok := byBody[resp.body]; ok {
+			result[i].responses = append(result[i].responses, resp)
+			continue
+		}
+		byBody[resp.body] = len(result)
+		result = append(result, responseWriter{body: resp.body, responses: []apiResponse{resp}})
+	}
+	for i := range result {
+		first := result[i].responses[0].status
+		suffix := ""
+		if !strings.HasPrefix(first, "2") {
+			suffix = statusName(first)
+		}
+		result[i].name = "Write" + op.name + suffix + "Response"
+	}
+	return result
+}
+
+// validStatus says whether status is a code, a range such as 4XX or default.
+func validStatus(status string) bool {
+	if status == "default" {
+		return true
+	}
+	if len(status) != 3 || status[0] < '1' || status[0] > '5' {
+		return false
+	}
+	rest := strings.ToUpper(status[1:])
+	return rest == "XX" || (rest[0] >= '0' && rest[0] <= '9' && rest[1] >= '0' && rest[1] <= '9')
+}
+
+// isRange says whether status is a range such as 4XX.
+func isRange(status string) bool {
+	return strings.HasSuffix(strings.ToUpper(status), "XX")
+}
+
+// statusName returns the name of the helper writing the responses with status after its
+// operation, as errorName does for the errors of the client.
+func statusName(status string) string {
+	if status == "default" {
+		return "Default"
+	}
+	return strings.TrimSuffix(errorName(status), "Error")
+}
+
+// statusCondition returns the Go condition of code being answered by the response with status
+// rather than by others, the responses of the operation with another body. It is empty for
+// default when every other code is.
+func statusCondition(status string, others []apiResponse) string {
+	var conds []string
+	switch {
+	case status == "default":
+		for _, o := range others {
+			switch {
+			case o.status == "default" || !validStatus(o.status):
+			case isRange(o.status):
+				conds = append(conds, fmt.Sprintf("code/100 != %c", o.status[0]))
+			default:
+				conds = append(conds, "code != "+o.status)
+			}
+		}
+	case isRange(status):
+		conds = append(conds, fmt.Sprintf("code/100 == %c", status[0]))
+		// the codes of the range declared on their own are answered by their response.
+		for _, o := range others {
+			if o.status[0] == status[0] && validStatus(o.status) && !isRange(o.status) {
+				conds = append(conds, "code != "+o.status)
+			}
+		}
+	default:
+		conds = append(conds, "code == "+status)
+	}
+	return strings.Join(conds, " && ")
+}
+
+// writeResponseWriters writes the helpers writing the responses of op with a body, which set
+// their media type and fail without writing for the codes the spec does not answer with it.
+func writeResponseWriters(w *bufio.Writer, op apiOperation) {
+	for _, rw := range responseWriters(op) {
+		own := make(map[string]bool, len(rw.responses))
+		var statuses []string
+		for _, resp := range rw.responses {
+			own[resp.status] = true
+			if resp.status == "default" {
+				statuses = append(statuses, "the ones it declares no other response for")
+				continue
+			}
+			statuses = append(statuses, strings.ToUpper(resp.status))
+		}
+		var others []apiResponse
+		for _, resp := range op.responses {
+			if !own[resp.status] {
+				others = append(others, resp)
+			}
+		}
+		w.WriteString("\n")
+		doc := fmt.Sprintf("%s writes body as the response of %s with code, which has to be one the spec answers with it: %s. It fails without writing for the others.", rw.name, op.name, strings.Join(statuses, ", "))
+		for _, l := range wrapLine(doc, 97) {
+			fmt.Fprintf(w, "// %s\n", l)
+		}
+		fmt.Fprintf(w, "func %s(w http.ResponseWriter, code int, body %s) error {\n", rw.name, rw.body)
+		w.WriteString("\tvar mediaType string\n\tswitch {\n")
+		answered := false
+		for _, resp := range rw.responses {
+			cond := statusCondition(resp.status, others)
+			if cond == "" {
+				w.WriteString("\tdefault:\n")
+				answered = true
+			} else {
+				fmt.Fprintf(w, "\tcase %s:\n", cond)
+			}
+			fmt.Fprintf(w, "\t\tmediaType = %q\n", resp.mediaType)
+		}
+		if !answered {
+			w.WriteString("\tdefault:\n")
+			fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"%s does not answer %%d with %s\", code)\n", op.name, strings.Replace(rw.body, "%", "%%", -1))
+		}
+		w.WriteString("\t}\n\treturn lacWrite(w, mediaType, code, body)\n}\n")
+	}
+}
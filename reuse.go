@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reusePackage is the package of --reuse-package, its structs and enums are referenced instead
+// of generating types with the same shape.
+type reusePackage struct {
+	dir string
+	// importPath is the path the package is imported by, empty when it is the package of the
+	// target, and name the identifier its types are qualified with.
+	importPath string
+	name       string
+	// signatures holds the signature of each struct and enum, see typeSignature.
+	signatures map[string]string
+	// fields holds, for each struct, the Go type of the field of each json key.
+	fields map[string]map[string]string
+}
+
+// qualified returns the name of the type of the package as written in the target.
+func (rp *reusePackage) qualified(name string) string {
+	if rp.importPath == "" {
+		return name
+	}
+	return rp.name + "." + name
+}
+
+// readReusePackage reads the structs and enums of the package in dir, given as dir or
+// dir=importpath. Without an import path it is made of the go.mod above dir, the package of the
+// target needs none. The target itself is left out as it is about to be regenerated.
+func readReusePackage(c *config, value string) (*reusePackage, error) {
+	rp := &reusePackage{
+		dir:        value,
+		signatures: map[string]string{},
+		fields:     map[string]map[string]string{},
+	}
+	if i := strings.Index(value, "="); i >= 0 {
+		rp.dir, rp.importPath = value[:i], value[i+1:]
+	}
+	names, err := c.files().Glob(filepath.Join(rp.dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", rp.dir, err)
+	}
+	fset := token.NewFileSet()
+	strs := map[string]bool{}
+	enums := map[string][]string{}
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") || (c.targetFile != "" && filepath.Clean(name) == filepath.Clean(c.targetFile)) {
+			continue
+		}
+		src, err := readFile(c.files(), name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		rp.name = f.Name.Name
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Assign.IsValid() || !s.Name.IsExported() {
+						continue
+					}
+					switch tt := s.Type.(type) {
+					case *ast.StructType:
+						if fields, ok := reusableFields(tt); ok {
+							rp.fields[s.Name.Name] = fields
+							rp.signatures[s.Name.Name] = structSignature(tt)
+						}
+					case *ast.Ident:
+						if tt.Name == "string" {
+							strs[s.Name.Name] = true
+						}
+					}
+				case *ast.ValueSpec:
+					id, ok := s.Type.(*ast.Ident)
+					if !ok || gd.Tok != token.CONST {
+						continue
+					}
+					for _, v := range s.Values {
+						if lit, ok := v.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+							if value, err := strconv.Unquote(lit.Value); err == nil {
+								enums[id.Name] = append(enums[id.Name], value)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	if rp.name == "" {
+		return nil, fmt.Errorf("no Go files found in %s", rp.dir)
+	}
+	for n, values := range enums {
+		if strs[n] {
+			rp.signatures[n] = enumSignature(values)
+		}
+	}
+	if c.targetFile != "" && filepath.Clean(rp.dir) == filepath.Dir(filepath.Clean(c.targetFile)) {
+		rp.importPath = ""
+		return rp, nil
+	}
+	if rp.importPath != "" {
+		return rp, nil
+	}
+	rp.importPath, err = moduleImportPath(c, rp.dir)
+	if err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// reusableFields returns the Go type of each json key of a struct, false for the ones embedding
+// others as their keys can not be told without the embedded ones.
+func reusableFields(st *ast.StructType) (map[string]string, bool) {
+	fields := map[string]string{}
+	for _, fld := range st.Fields.List {
+		if len(fld.Names) == 0 {
+			return nil, false
+		}
+		if fld.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(fld.Tag.Value)
+		if err != nil {
+			continue
+		}
+		key := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+		if key != "" && key != "-" {
+			fields[key] = types.ExprString(fld.Type)
+		}
+	}
+	return fields, true
+}
+
+// moduleImportPath returns the import path of dir from the module path of the closest go.mod
+// above it.
+func moduleImportPath(c *config, dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("finding the module of %s: %w", dir, err)
+	}
+	root, gomod, ok := moduleRoot(c, abs)
+	if !ok {
+		return "", fmt.Errorf("no go.mod found above %s, give the import path as %s=importpath", dir, dir)
+	}
+	module := modulePath(gomod)
+	if module == "" {
+		return "", fmt.Errorf("%s declares no module", filepath.Join(root, "go.mod"))
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", fmt.Errorf("finding the module of %s: %w", dir, err)
+	}
+	return path.Join(module, filepath.ToSlash(rel)), nil
+}
+
+// moduleRoot returns the directory of the closest go.mod above abs, an absolute directory, along
+// with its contents.
+func moduleRoot(c *config, abs string) (string, []byte, bool) {
+	for root := abs; ; {
+		if b, err := readFile(c.files(), filepath.Join(root, "go.mod")); err == nil {
+			return root, b, true
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", nil, false
+		}
+		root = parent
+	}
+}
+
+// modulePath returns the path of the module directive of a go.mod.
+func modulePath(gomod []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(gomod))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+	return ""
+}
+
+// goIdentifier matches the identifiers of a Go type expression.
+var goIdentifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// reuseTypes leaves out the types with the same shape as a type of the --reuse-package, the same
+// json keys and field types or the same enum values, and makes the fields referencing them
+// reference that type. A type of the package with the same name is preferred, several others
+// with the shape are warned about and none is reused.
+func reuseTypes(c *config, types []*Type) []*Type {
+	rp := c.reuse
+	if rp == nil {
+		return types
+	}
+	// types embedded or held by unions are referenced by their generated names only.
+	embedded := map[string]bool{}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			for _, mt := range fld.Type.multiType {
+				embedded[capitalize(mt)] = true
+			}
+		}
+		if t.union != nil {
+			for _, cs := range t.union.cases {
+				embedded[capitalize(cs.typeName)] = true
+			}
+		}
+	}
+	bySignature := map[string][]string{}
+	for n, sig := range rp.signatures {
+		bySignature[sig] = append(bySignature[sig], n)
+	}
+	for _, names := range bySignature {
+		sort.Strings(names)
+	}
+	// matches maps the Go names of the generated types to the type of the package.
+	matches := map[string]string{}
+	for _, t := range types {
+		n := capitalize(t.Name)
+		if embedded[n] || t.union != nil || t.tuple || t.extra || !reusableType(t) {
+			continue
+		}
+		candidates := bySignature[typeSignature(t)]
+		switch {
+		case len(candidates) == 0:
+		case rp.signatures[n] == typeSignature(t):
+			matches[n] = n
+		case len(candidates) == 1:
+			matches[n] = candidates[0]
+		default:
+			c.warn(n, "the shape of the type is shared by %s in %s, none is reused", strings.Join(candidates, ", "), rp.dir)
+		}
+	}
+	// rename gives the types in a Go type expression the names they have in the package.
+	rename := func(tn string, name func(string) string) string {
+		return goIdentifier.ReplaceAllStringFunc(tn, func(id string) string {
+			if to, ok := matches[id]; ok {
+				return name(to)
+			}
+			return id
+		})
+	}
+	unqualified := func(name string) string { return name }
+	// the field types are compared once the types they hold are known to be reused.
+	for changed := true; changed; {
+		changed = false
+		for _, t := range types {
+			n := capitalize(t.Name)
+			existing, ok := matches[n]
+			if !ok || len(t.Enum) > 0 {
+				continue
+			}
+			for _, fld := range t.Fields {
+				f := fld.Type
+				tn, _ := fieldType(c, n, fld.GoName(), &f)
+				if tn == n {
+					tn = "*" + tn
+				}
+				if here, there := rename(tn, unqualified), rp.fields[existing][fld.Name]; here != there {
+					c.debugf("%s is not reused as %s, %s is %s instead of %s\n", n, existing, fld.Name, here, there)
+					delete(matches, n)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return types
+	}
+	kept := make([]*Type, 0, len(types))
+	for _, t := range types {
+		if existing, ok := matches[capitalize(t.Name)]; ok {
+			c.debugf("%s is reused from %s as %s\n", t.Name, rp.dir, rp.qualified(existing))
+			continue
+		}
+		for i := range t.Fields {
+			f := &t.Fields[i].Type
+			if f.goType != "" || f.IsMultiple() {
+				continue
+			}
+			_, tn := f.Resolve()
+			if c.arrayStyle == arrayStylePointer && f.isStructArray() {
+				tn = "[]*" + strings.TrimPrefix(tn, "[]")
+			}
+			if reused := rename(tn, rp.qualified); reused != tn {
+				f.goType = reused
+				f.goImport = rp.importPath
+			}
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// reusableType returns true for the enums and the structs with no embedded types.
+func reusableType(t *Type) bool {
+	for _, fld := range t.Fields {
+		if fld.Name == "" {
+			return false
+		}
+	}
+	return true
+}
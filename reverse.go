@@ -0,0 +1,629 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// reverseCommand is the first argument that runs runReverse, which goes from Go to a schema.
+const reverseCommand = "reverse"
+
+// Formats of the schemas written by reverse.
+const (
+	reverseJSONSchema = "jsonschema"
+	reverseOpenAPI    = "openapi"
+)
+
+// runReverse describes the types declared in the Go files of args, files, globs or directories
+// and the current one when there are none, as a JSON Schema or the components of an OpenAPI spec
+// so specs can be kept in sync with code written first. Warnings go to stderr.
+func runReverse(ctx context.Context, args []string, fsys FileSystem, stdout, stderr io.Writer) error {
+	c := &config{fileSystem: fsys}
+	flags := flag.NewFlagSet("LAC "+reverseCommand, flag.ContinueOnError)
+	flags.StringVar(&c.targetFile, "target", "", "path to the file where the schema will be written. If none provided stdout will be used.")
+	format := flags.String("format", reverseJSONSchema, "what to write, either `jsonschema` (a 2020-12 JSON Schema with the types in $defs) or openapi (an OpenAPI 3 spec with the types in its components).")
+	roots := flags.StringSlice("types", []string{}, "types to describe, along with the ones they use, all the exported ones by default.")
+	if err := flags.Parse(args); err != nil {
+		return &ErrBadUsage{err: err}
+	}
+	if *format != reverseJSONSchema && *format != reverseOpenAPI {
+		return &ErrBadUsage{err: fmt.Errorf("--format must be %s or %s, not %q", reverseJSONSchema, reverseOpenAPI, *format)}
+	}
+	c.warningHandler = func(w warning) {
+		fmt.Fprintf(stderr, "WARNING: %s\n", w)
+	}
+	locations := flags.Args()
+	if len(locations) == 0 {
+		locations = []string{"."}
+	}
+	r := newReverser(c, *format)
+	if err := r.parse(ctx, locations); err != nil {
+		return err
+	}
+	schemas, err := r.describe(*roots)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	switch *format {
+	case reverseOpenAPI:
+		doc = reverseSpec{
+			OpenAPI:    "3.0.3",
+			Info:       reverseInfo{Title: r.pkg, Version: "0.0.0"},
+			Paths:      struct{}{},
+			Components: reverseComponents{Schemas: schemas},
+		}
+	default:
+		doc = reverseDocument{
+			Schema: "https://json-schema.org/draft/2020-12/schema",
+			Title:  r.pkg,
+			Defs:   schemas,
+		}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding schema: %w", err)
+	}
+	write := func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	if c.targetFile == "" {
+		return write(stdout)
+	}
+	return writeFile(c, c.targetFile, write)
+}
+
+// reverseDocument is the JSON Schema written by reverse.
+type reverseDocument struct {
+	Schema string         `json:"$schema"`
+	Title  string         `json:"title,omitempty"`
+	Defs   reverseSchemas `json:"$defs"`
+}
+
+// reverseSpec is the OpenAPI spec written by reverse, it has no paths.
+type reverseSpec struct {
+	OpenAPI    string            `json:"openapi"`
+	Info       reverseInfo       `json:"info"`
+	Paths      struct{}          `json:"paths"`
+	Components reverseComponents `json:"components"`
+}
+
+type reverseInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type reverseComponents struct {
+	Schemas reverseSchemas `json:"schemas"`
+}
+
+// reverseSchema is the schema of a Go type.
+type reverseSchema struct {
+	Ref                  string           `json:"$ref,omitempty"`
+	Description          string           `json:"description,omitempty"`
+	Type                 string           `json:"type,omitempty"`
+	Format               string           `json:"format,omitempty"`
+	Enum                 []interface{}    `json:"enum,omitempty"`
+	Minimum              *int64           `json:"minimum,omitempty"`
+	MinItems             *int64           `json:"minItems,omitempty"`
+	MaxItems             *int64           `json:"maxItems,omitempty"`
+	Items                *reverseSchema   `json:"items,omitempty"`
+	Properties           *reverseSchemas  `json:"properties,omitempty"`
+	Required             []string         `json:"required,omitempty"`
+	AdditionalProperties *reverseSchema   `json:"additionalProperties,omitempty"`
+	AllOf                []*reverseSchema `json:"allOf,omitempty"`
+}
+
+// reverseSchemas holds schemas by name, written in the order they were added.
+type reverseSchemas struct {
+	Names  []string
+	ByName map[string]*reverseSchema
+}
+
+func (rs *reverseSchemas) add(name string, s *reverseSchema) {
+	if rs.ByName == nil {
+		rs.ByName = map[string]*reverseSchema{}
+	}
+	if _, dup := rs.ByName[name]; !dup {
+		rs.Names = append(rs.Names, name)
+	}
+	rs.ByName[name] = s
+}
+
+// MarshalJSON implements json.Marshaler keeping the order.
+func (rs reverseSchemas) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range rs.Names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(rs.ByName[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = reverseSchemas{}
+
+// reverseDecl is a type declared in the parsed files.
+type reverseDecl struct {
+	name string
+	spec *ast.TypeSpec
+	doc  *ast.CommentGroup
+	// enum holds the values of the constants declared of the type, in order.
+	enum []interface{}
+	// marshaler is the method the type marshals itself with, if any.
+	marshaler string
+}
+
+// reverser describes the types of a Go package as schemas.
+type reverser struct {
+	c      *config
+	format string
+	pkg    string
+	decls  map[string]*reverseDecl
+	order  []string
+	// queued are the types described or waiting to be, pending the ones waiting.
+	queued  map[string]bool
+	pending []string
+}
+
+func newReverser(c *config, format string) *reverser {
+	return &reverser{
+		c:      c,
+		format: format,
+		decls:  map[string]*reverseDecl{},
+		queued: map[string]bool{},
+	}
+}
+
+// parse reads the declarations of the Go files found at locations, test files and files of
+// other packages are left out.
+func (r *reverser) parse(ctx context.Context, locations []string) error {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, location := range locations {
+		pattern := location
+		if !strings.HasSuffix(location, ".go") && !strings.ContainsAny(location, "*?[") {
+			pattern = filepath.Join(location, "*.go")
+		}
+		names, err := r.c.files().Glob(pattern)
+		if err != nil {
+			return &ErrBadUsage{err: fmt.Errorf("invalid pattern %q: %w", location, err)}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no Go files found at %s", location)
+		}
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			src, err := readFile(r.c.files(), name)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", name, err)
+			}
+			f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+			if err != nil {
+				return fmt.Errorf("parsing %s: %w", name, err)
+			}
+			if r.pkg == "" {
+				r.pkg = f.Name.Name
+			}
+			if f.Name.Name != r.pkg {
+				r.c.warn(name, "skipping file of package %s, the types are read from package %s", f.Name.Name, r.pkg)
+				continue
+			}
+			files = append(files, f)
+		}
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				r.decls[ts.Name.Name] = &reverseDecl{name: ts.Name.Name, spec: ts, doc: doc}
+				r.order = append(r.order, ts.Name.Name)
+			}
+		}
+	}
+	// constants and methods can be declared before their types.
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok == token.CONST {
+					r.readEnum(d)
+				}
+			case *ast.FuncDecl:
+				r.readMarshaler(d)
+			}
+		}
+	}
+	return nil
+}
+
+// readEnum adds the constants of d that have a declared type and a literal value to the enum of
+// their type, constants of iota have no literal value and are left out.
+func (r *reverser) readEnum(d *ast.GenDecl) {
+	for _, spec := range d.Specs {
+		vs := spec.(*ast.ValueSpec)
+		ident, ok := vs.Type.(*ast.Ident)
+		if !ok || len(vs.Values) != len(vs.Names) {
+			continue
+		}
+		decl, ok := r.decls[ident.Name]
+		if !ok {
+			continue
+		}
+		for _, v := range vs.Values {
+			if value, ok := literalValue(v); ok {
+				decl.enum = append(decl.enum, value)
+			}
+		}
+	}
+}
+
+// literalValue returns the value of a string or number literal.
+func literalValue(e ast.Expr) (interface{}, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok {
+		return nil, false
+	}
+	switch lit.Kind {
+	case token.STRING:
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	case token.INT:
+		n, err := strconv.ParseInt(lit.Value, 0, 64)
+		return n, err == nil
+	case token.FLOAT:
+		n, err := strconv.ParseFloat(lit.Value, 64)
+		return n, err == nil
+	}
+	return nil, false
+}
+
+// readMarshaler records the types that marshal themselves, their schema can not be told.
+func (r *reverser) readMarshaler(d *ast.FuncDecl) {
+	if d.Recv == nil || len(d.Recv.List) != 1 {
+		return
+	}
+	if d.Name.Name != "MarshalJSON" && d.Name.Name != "MarshalText" {
+		return
+	}
+	recv := d.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return
+	}
+	if decl, ok := r.decls[ident.Name]; ok && decl.marshaler != "MarshalJSON" {
+		decl.marshaler = d.Name.Name
+	}
+}
+
+// describe returns the schemas of roots, or of all the exported types when there are none, and
+// the ones of the types they use, in the order they were declared.
+func (r *reverser) describe(roots []string) (reverseSchemas, error) {
+	var schemas reverseSchemas
+	if len(roots) == 0 {
+		for _, name := range r.order {
+			if ast.IsExported(name) {
+				r.need(name)
+			}
+		}
+	}
+	for _, name := range roots {
+		if _, ok := r.decls[name]; !ok {
+			return schemas, &ErrBadUsage{err: fmt.Errorf("there is no type %s in package %s", name, r.pkg)}
+		}
+		r.need(name)
+	}
+	described := map[string]*reverseSchema{}
+	for len(r.pending) > 0 {
+		name := r.pending[0]
+		r.pending = r.pending[1:]
+		described[name] = r.declSchema(r.decls[name])
+	}
+	for _, name := range r.order {
+		if s, ok := described[name]; ok {
+			schemas.add(name, s)
+		}
+	}
+	return schemas, nil
+}
+
+// need queues the declared type name to be described.
+func (r *reverser) need(name string) {
+	if r.queued[name] {
+		return
+	}
+	r.queued[name] = true
+	r.pending = append(r.pending, name)
+}
+
+// ref returns a reference to the declared type name, which is queued.
+func (r *reverser) ref(name string) *reverseSchema {
+	r.need(name)
+	if r.format == reverseOpenAPI {
+		return &reverseSchema{Ref: "#/components/schemas/" + name}
+	}
+	return &reverseSchema{Ref: "#/$defs/" + name}
+}
+
+// declSchema returns the schema of a declared type.
+func (r *reverser) declSchema(decl *reverseDecl) *reverseSchema {
+	var s *reverseSchema
+	switch decl.marshaler {
+	case "MarshalJSON":
+		r.c.warn(decl.name, "marshals itself with MarshalJSON, its schema can not be told and is left empty")
+		s = &reverseSchema{}
+	case "MarshalText":
+		s = &reverseSchema{Type: "string"}
+	default:
+		s = r.typeSchema(decl.name, decl.spec.Type)
+	}
+	if len(decl.enum) > 0 {
+		s.Enum = decl.enum
+	}
+	if desc := commentDescription(decl.doc, ""); desc != "" {
+		if s.Ref != "" {
+			// siblings of $ref are ignored by older readers.
+			s = &reverseSchema{AllOf: []*reverseSchema{s}}
+		}
+		s.Description = desc
+	}
+	return s
+}
+
+// provenancePrefix starts the first line of the comments LAC writes on the types it generates,
+// it says nothing about the type.
+const provenancePrefix = " is auto generated by github.com/perrito666/LAC"
+
+// commentDescription returns the description in a doc comment, lines wrapped to fit are joined.
+// The provenance line LAC writes on types is left out, as is the leading "Field is the" it
+// writes on fields when field is given.
+func commentDescription(doc *ast.CommentGroup, field string) string {
+	if doc == nil {
+		return ""
+	}
+	text := strings.TrimSpace(doc.Text())
+	if i := strings.Index(text, provenancePrefix); i > 0 && !strings.ContainsAny(text[:i], " \n") {
+		text = ""
+		if nl := strings.IndexByte(doc.Text(), '\n'); nl >= 0 {
+			text = strings.TrimSpace(doc.Text()[nl+1:])
+		}
+	}
+	if field != "" {
+		for _, prefix := range []string{field + " is the ", field + " is "} {
+			if strings.HasPrefix(text, prefix) {
+				text = text[len(prefix):]
+				break
+			}
+		}
+	}
+	var paragraphs []string
+	for _, p := range strings.Split(text, "\n\n") {
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(p), " "))
+	}
+	return strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+}
+
+// typeSchema returns the schema of the Go type expression e, found in source for the warnings.
+func (r *reverser) typeSchema(source string, e ast.Expr) *reverseSchema {
+	switch t := e.(type) {
+	case *ast.Ident:
+		return r.identSchema(source, t.Name)
+	case *ast.ParenExpr:
+		return r.typeSchema(source, t.X)
+	case *ast.StarExpr:
+		return r.typeSchema(source, t.X)
+	case *ast.SelectorExpr:
+		return r.qualifiedSchema(source, t)
+	case *ast.InterfaceType:
+		return &reverseSchema{}
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (ident.Name == "byte" || ident.Name == "uint8") {
+			// encoding/json writes []byte as base64.
+			return &reverseSchema{Type: "string", Format: "byte"}
+		}
+		s := &reverseSchema{Type: "array", Items: r.typeSchema(source, t.Elt)}
+		if t.Len != nil {
+			if n, ok := literalValue(t.Len); ok {
+				if length, ok := n.(int64); ok {
+					s.MinItems, s.MaxItems = &length, &length
+				}
+			}
+		}
+		return s
+	case *ast.MapType:
+		return &reverseSchema{Type: "object", AdditionalProperties: r.typeSchema(source, t.Value)}
+	case *ast.StructType:
+		return r.structSchema(source, t)
+	}
+	r.c.warn(source, "%s can not be described, it is left empty", types.ExprString(e))
+	return &reverseSchema{}
+}
+
+// identSchema returns the schema of a predeclared type or a reference to a declared one.
+func (r *reverser) identSchema(source, name string) *reverseSchema {
+	if decl, ok := r.decls[name]; ok {
+		if decl.spec.Assign.IsValid() {
+			// aliases are the type they alias.
+			return r.typeSchema(source, decl.spec.Type)
+		}
+		return r.ref(name)
+	}
+	zero := int64(0)
+	switch name {
+	case "string":
+		return &reverseSchema{Type: "string"}
+	case "bool":
+		return &reverseSchema{Type: "boolean"}
+	case "int", "int64":
+		return &reverseSchema{Type: "integer", Format: "int64"}
+	case "int8", "int16", "int32", "rune":
+		return &reverseSchema{Type: "integer", Format: "int32"}
+	case "uint", "uint64", "uintptr":
+		return &reverseSchema{Type: "integer", Format: "int64", Minimum: &zero}
+	case "uint8", "uint16", "uint32", "byte":
+		return &reverseSchema{Type: "integer", Format: "int32", Minimum: &zero}
+	case "float64":
+		return &reverseSchema{Type: "number", Format: "double"}
+	case "float32":
+		return &reverseSchema{Type: "number", Format: "float"}
+	case "any":
+		return &reverseSchema{}
+	}
+	r.c.warn(source, "type %s is not declared in package %s, it is left empty", name, r.pkg)
+	return &reverseSchema{}
+}
+
+// qualifiedSchemas are the schemas of the types of other packages LAC knows how they marshal,
+// mostly the ones it generates.
+var qualifiedSchemas = map[string]reverseSchema{
+	"time.Time":       {Type: "string", Format: "date-time"},
+	"time.Duration":   {Type: "integer", Format: "int64"},
+	"json.RawMessage": {},
+	"json.Number":     {Type: "number"},
+	"netip.Addr":      {Type: "string", Format: "ip"},
+	"netip.Prefix":    {Type: "string"},
+	"uuid.UUID":       {Type: "string", Format: "uuid"},
+	"decimal.Decimal": {Type: "string", Format: "decimal"},
+	"url.URL":         {Type: "string", Format: "uri"},
+}
+
+// qualifiedSchema returns the schema of a type of another package.
+func (r *reverser) qualifiedSchema(source string, t *ast.SelectorExpr) *reverseSchema {
+	pkg, ok := t.X.(*ast.Ident)
+	if !ok {
+		r.c.warn(source, "%s can not be described, it is left empty", types.ExprString(t))
+		return &reverseSchema{}
+	}
+	name := pkg.Name + "." + t.Sel.Name
+	if s, ok := qualifiedSchemas[name]; ok {
+		return &s
+	}
+	r.c.warn(source, "type %s is of another package, it is left empty", name)
+	return &reverseSchema{}
+}
+
+// structSchema returns the schema of a struct as encoding/json marshals it. Fields that are
+// neither pointers nor tagged omitempty are always written and so required. Embedded structs are
+// referenced with allOf, the properties of the struct are declared next to it.
+func (r *reverser) structSchema(source string, st *ast.StructType) *reverseSchema {
+	s := &reverseSchema{Type: "object", Properties: &reverseSchemas{}}
+	var embeds []*reverseSchema
+	for _, fld := range st.Fields.List {
+		var tag reflect.StructTag
+		if fld.Tag != nil {
+			if unquoted, err := strconv.Unquote(fld.Tag.Value); err == nil {
+				tag = reflect.StructTag(unquoted)
+			}
+		}
+		jsonName, options := tag.Get("json"), ""
+		if i := strings.IndexByte(jsonName, ','); i >= 0 {
+			jsonName, options = jsonName[:i], jsonName[i:]
+		}
+		if jsonName == "-" && options == "" {
+			continue
+		}
+		names := fld.Names
+		if len(names) == 0 {
+			typ := fld.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			var embedded string
+			switch et := typ.(type) {
+			case *ast.Ident:
+				embedded = et.Name
+			case *ast.SelectorExpr:
+				embedded = et.Sel.Name
+			}
+			if decl, ok := r.decls[embedded]; ok && jsonName == "" {
+				if _, isStruct := decl.spec.Type.(*ast.StructType); isStruct {
+					embeds = append(embeds, r.ref(embedded))
+					continue
+				}
+			}
+			if embedded == "" || !ast.IsExported(embedded) {
+				r.c.warn(source, "embedded %s can not be described, it is left out", embedded)
+				continue
+			}
+			names = []*ast.Ident{ast.NewIdent(embedded)}
+		}
+		for _, name := range names {
+			if !name.IsExported() {
+				continue
+			}
+			key := jsonName
+			if key == "" {
+				key = name.Name
+			}
+			fs := r.typeSchema(source+"."+name.Name, fld.Type)
+			if strings.Contains(options, ",string") {
+				fs = &reverseSchema{Type: "string"}
+			}
+			doc := fld.Doc
+			if doc == nil {
+				doc = fld.Comment
+			}
+			if desc := commentDescription(doc, name.Name); desc != "" {
+				if fs.Ref != "" {
+					fs = &reverseSchema{AllOf: []*reverseSchema{fs}}
+				}
+				fs.Description = desc
+			}
+			s.Properties.add(key, fs)
+			_, pointer := fld.Type.(*ast.StarExpr)
+			if !pointer && !strings.Contains(options, ",omitempty") {
+				s.Required = append(s.Required, key)
+			}
+		}
+	}
+	if len(embeds) > 0 {
+		s.AllOf = embeds
+		if len(s.Properties.Names) == 0 {
+			s.Properties = nil
+		}
+	}
+	return s
+}
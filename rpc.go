@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// GenerateParams are the params of the generate method of --rpc.
+type GenerateParams struct {
+	// Args is the command line of the run, without the program name.
+	Args []string `json:"args"`
+	// Files maps the names of the files the run can read to their contents, there is no disk.
+	Files map[string]string `json:"files"`
+}
+
+// GenerateFromJSONParams are the params of the generateFromJSON method of --rpc, which makes
+// types of a single sample such as the selection of an editor.
+type GenerateFromJSONParams struct {
+	// Name is the name of the outer type.
+	Name string `json:"name"`
+	// JSON is the sample.
+	JSON string `json:"json"`
+	// Args are extra flags for the run, such as --package.
+	Args []string `json:"args,omitempty"`
+}
+
+// GenerateResult is what a run in memory, through --rpc or WebAssembly, produced.
+type GenerateResult struct {
+	// Output is the code written to stdout, the run had no --target.
+	Output string `json:"output"`
+	// Files maps the names of the files the run wrote to their contents.
+	Files map[string]string `json:"files"`
+	// Warnings are the warnings of the run.
+	Warnings []string `json:"warnings"`
+	// Types describes the generated types.
+	Types []IRType `json:"types"`
+}
+
+// IRType describes a generated type.
+type IRType struct {
+	// Name is the name the type was registered with, GoName the one it got in the code.
+	Name        string    `json:"name"`
+	GoName      string    `json:"goName"`
+	Source      string    `json:"source,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Enum        []string  `json:"enum,omitempty"`
+	Fields      []IRField `json:"fields,omitempty"`
+}
+
+// IRField describes a field of a generated type.
+type IRField struct {
+	// Name is the key of the field in the source, empty for the embedded types listed by Embeds.
+	Name        string   `json:"name,omitempty"`
+	GoName      string   `json:"goName,omitempty"`
+	GoType      string   `json:"goType,omitempty"`
+	Embeds      []string `json:"embeds,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Optional    bool     `json:"optional,omitempty"`
+	Nullable    bool     `json:"nullable,omitempty"`
+}
+
+// irTypes describes the types written by the run of c.
+func irTypes(c *config, types []*Type) []IRType {
+	result := make([]IRType, 0, len(types))
+	for _, t := range types {
+		structName := capitalize(t.Name)
+		it := IRType{
+			Name:        t.Name,
+			GoName:      structName,
+			Source:      t.Source,
+			Description: t.Description,
+			Enum:        t.Enum,
+		}
+		for _, fld := range t.Fields {
+			f := fld.Type
+			field := IRField{
+				Name:        fld.Name,
+				Description: f.description,
+				Optional:    f.optional,
+				Nullable:    f.nullable,
+			}
+			if f.IsMultiple() {
+				for _, mt := range f.multiType {
+					field.Embeds = append(field.Embeds, capitalize(mt))
+				}
+			}
+			if fld.Name != "" {
+				field.GoName = fld.GoName()
+				if !f.IsMultiple() {
+					field.GoType, _ = fieldType(c, structName, field.GoName, &f)
+				}
+			}
+			it.Fields = append(it.Fields, field)
+		}
+		result = append(result, it)
+	}
+	return result
+}
+
+// generateInMemory runs LAC as the command line args would over files held in memory. Progress
+// is not printed with --verbose as stdout belongs to the caller.
+func generateInMemory(ctx context.Context, args []string, files map[string]string) (*GenerateResult, error) {
+	contents := make(map[string][]byte, len(files))
+	for name, content := range files {
+		contents[name] = []byte(content)
+	}
+	fsys := newMemoryFileSystem(contents)
+	c, err := parseFlags(flag.NewFlagSet("LAC", flag.ContinueOnError), args, 0, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("flags step: %w", err)
+	}
+	c.verbose = false
+	result := &GenerateResult{
+		Files:    map[string]string{},
+		Warnings: []string{},
+		Types:    []IRType{},
+	}
+	c.warningHandler = func(w warning) {
+		result.Warnings = append(result.Warnings, w.String())
+	}
+	var stdout strings.Builder
+	err = run(ctx, c, &stdout)
+	result.Output = stdout.String()
+	for name, b := range fsys.written() {
+		result.Files[name] = string(b)
+	}
+	result.Types = append(result.Types, irTypes(c, c.generated)...)
+	return result, err
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	// rpcGenerationFailed is the error of runs that failed, its data is the GenerateResult so
+	// far.
+	rpcGenerationFailed = 1
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification when it has no ID.
+type rpcRequest struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response, it has either a Result or an Error.
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is the error of a rpcResponse.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (err *rpcError) Error() string {
+	return err.Message
+}
+
+var _ error = &rpcError{}
+
+// rpcMethods lists the methods --rpc answers, besides exit.
+var rpcMethods = []string{"generate", "generateFromJSON", "shutdown"}
+
+// serveRPC answers the JSON-RPC 2.0 requests read from in on out, one at a time, until the input
+// ends or an exit notification arrives. Messages are framed with a Content-Length header as in
+// the Language Server Protocol so editors can reuse their clients.
+func serveRPC(ctx context.Context, c *config, in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := readRPCMessage(c, r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading rpc message: %w", err)
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			if err := writeRPCMessage(out, rpcResponse{Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, err := handleRPC(ctx, req)
+		if req.ID == nil {
+			// notifications are not answered.
+			continue
+		}
+		resp := rpcResponse{ID: req.ID, Result: result}
+		if err != nil {
+			var rerr *rpcError
+			if !errors.As(err, &rerr) {
+				rerr = &rpcError{Code: rpcGenerationFailed, Message: err.Error(), Data: result}
+			}
+			resp = rpcResponse{ID: req.ID, Error: rerr}
+		} else if result == nil {
+			// a response needs either a result or an error, null is a result.
+			resp.Result = json.RawMessage("null")
+		}
+		if err := writeRPCMessage(out, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handleRPC runs the method of req.
+func handleRPC(ctx context.Context, req rpcRequest) (interface{}, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "not a JSON-RPC 2.0 request"}
+	}
+	switch req.Method {
+	case "generate":
+		var params GenerateParams
+		if err := decodeRPCParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return rpcGenerate(ctx, params.Args, params.Files)
+	case "generateFromJSON":
+		var params GenerateFromJSONParams
+		if err := decodeRPCParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if params.Name == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "the name of the type is missing"}
+		}
+		// the outer type is named after its file.
+		file := params.Name + ".json"
+		args := append([]string{"--source", file}, params.Args...)
+		return rpcGenerate(ctx, args, map[string]string{file: params.JSON})
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, &rpcError{
+		Code:    rpcMethodNotFound,
+		Message: fmt.Sprintf("unknown method %q, it is one of %s", req.Method, strings.Join(rpcMethods, ", ")),
+	}
+}
+
+// rpcGenerate runs generateInMemory, runs that failed before generating have no result at all.
+func rpcGenerate(ctx context.Context, args []string, files map[string]string) (interface{}, error) {
+	result, err := generateInMemory(ctx, args, files)
+	if result == nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// decodeRPCParams decodes the params of a request into v.
+func decodeRPCParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &rpcError{Code: rpcInvalidParams, Message: "the params are missing"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// readRPCMessage returns the content of the next message of r, io.EOF when the input ends
+// between messages. Contents larger than --max-bytes are refused before being read.
+func readRPCMessage(c *config, r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" && length < 0 {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length < 0 {
+				return nil, errors.New("message without Content-Length header")
+			}
+			break
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if !strings.EqualFold(strings.TrimSpace(line[:i]), "Content-Length") {
+			continue
+		}
+		length, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if c.maxBytes > 0 && int64(length) > c.maxBytes {
+			return nil, fmt.Errorf("message of %d bytes is larger than --max-bytes %d", length, c.maxBytes)
+		}
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("reading content: %w", err)
+	}
+	return b, nil
+}
+
+// writeRPCMessage writes resp framed with its Content-Length.
+func writeRPCMessage(out io.Writer, resp rpcResponse) error {
+	resp.JSONRPC = "2.0"
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("encoding rpc response: %w", err)
+	}
+	if _, err := fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(b), b); err != nil {
+		return fmt.Errorf("writing rpc response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scalarRule maps the fields matching it to a Go type, a rule matches either a schema type and
+// format or a field path pattern.
+type scalarRule struct {
+	// Type and Format match the schema of the field, an empty Format matches any.
+	Type   string `yaml:"type"`
+	Format string `yaml:"format"`
+	// Field is a pattern like *.CreatedAt matched against StructName.Member, with Go names.
+	Field string `yaml:"field"`
+	// Go is the fully qualified type, ie github.com/google/uuid.UUID.
+	Go string `yaml:"go"`
+
+	// name and pkg are Go once split into what the code uses and what it imports.
+	name string
+	pkg  string
+}
+
+// scalarMap is the file passed with --scalar-map.
+type scalarMap struct {
+	Scalars []scalarRule `yaml:"scalars"`
+}
+
+// readScalarMap reads the rules in the file at p, they are kept in the order of the file since
+// the first matching one wins.
+func readScalarMap(c *config, p string) ([]scalarRule, error) {
+	b, err := readFile(c.files(), p)
+	if err != nil {
+		return nil, fmt.Errorf("reading scalar map: %w", err)
+	}
+	var sm scalarMap
+	if err := yaml.Unmarshal(b, &sm); err != nil {
+		return nil, fmt.Errorf("parsing scalar map %s: %w", p, err)
+	}
+	for i := range sm.Scalars {
+		r := &sm.Scalars[i]
+		if r.Go == "" {
+			return nil, fmt.Errorf("scalar map %s: rule %d has no go type", p, i+1)
+		}
+		if (r.Type == "") == (r.Field == "") {
+			return nil, fmt.Errorf("scalar map %s: rule %d needs either a type or a field", p, i+1)
+		}
+		if r.Field != "" {
+			if _, err := path.Match(r.Field, ""); err != nil {
+				return nil, fmt.Errorf("scalar map %s: rule %d: invalid field pattern %q: %w", p, i+1, r.Field, err)
+			}
+		}
+		r.name, r.pkg = qualifiedType(r.Go)
+	}
+	return sm.Scalars, nil
+}
+
+// qualifiedType splits a fully qualified type such as *github.com/shopspring/decimal.Decimal into
+// the type as written in code, *decimal.Decimal, and the package to import. Builtin types have no
+// package. Pointers, slices and maps with string keys of it are split alike.
+func qualifiedType(s string) (string, string) {
+	prefix := ""
+	for strings.HasPrefix(s, "*") || strings.HasPrefix(s, "[]") || strings.HasPrefix(s, "map[string]") {
+		if s[0] == '*' {
+			prefix, s = prefix+"*", s[1:]
+			continue
+		}
+		if s[0] == 'm' {
+			prefix, s = prefix+"map[string]", s[len("map[string]"):]
+			continue
+		}
+		prefix, s = prefix+"[]", s[2:]
+	}
+	dot := strings.LastIndex(s, ".")
+	if dot < 0 || dot < strings.LastIndex(s, "/") {
+		return prefix + s, ""
+	}
+	pkg, name := s[:dot], s[dot+1:]
+	return prefix + packageName(pkg) + "." + name, pkg
+}
+
+// packageName guesses the name of the package at an import path, ignoring major version
+// suffixes like /v2 and gopkg.in style .v3 ones.
+func packageName(pkg string) string {
+	elems := strings.Split(pkg, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// isMajorVersion returns true for v followed by digits.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaType returns the schema type of a field holding a Go primitive, or "" for the rest.
+func schemaType(t reflect.Type) string {
+	if t == nil || t == bytesType {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return string(STString)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return string(STInteger)
+	case reflect.Float32, reflect.Float64:
+		return string(STNumber)
+	case reflect.Bool:
+		return string(STBoolean)
+	}
+	return ""
+}
+
+// scalarFor returns the type and import of the first rule of the scalar map matching the field.
+// Field rules give the type of the whole field while arrays match the type rules of their elements
+// and get a slice of the type.
+func scalarFor(c *config, structName, capitalizedFN string, f *maybeType) (string, string, bool) {
+	st := schemaType(f.typeOf)
+	// strings holding base64 only match the rules naming their format.
+	if f.typeOf == bytesType && f.format != "" {
+		st = string(STString)
+	}
+	for _, r := range c.scalars {
+		if r.Field != "" {
+			if ok, _ := path.Match(r.Field, structName+"."+capitalizedFN); ok {
+				return r.name, r.pkg, true
+			}
+			continue
+		}
+		if r.Type != st || (r.Format != "" && r.Format != f.format) || (f.typeOf == bytesType && r.Format == "") {
+			continue
+		}
+		tn := r.name
+		if f.isArray {
+			tn = "[]" + tn
+		}
+		return tn, r.pkg, true
+	}
+	return "", "", false
+}
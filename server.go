@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// serverServeMux routes with the http.ServeMux of the standard library.
+	serverServeMux = "servemux"
+	// serverChi routes with a chi.Router.
+	serverChi = "chi"
+	// serverEcho routes with an echo.Echo or one of its groups.
+	serverEcho = "echo"
+	// serverGin routes with a gin.Engine or one of its groups.
+	serverGin = "gin"
+)
+
+const (
+	// serverInterfacesSingle writes the methods of every operation in the Server interface.
+	serverInterfacesSingle = "single"
+	// serverInterfacesPerTag writes an interface per tag of the operations, which Server embeds.
+	serverInterfacesPerTag = "per-tag"
+)
+
+// routerImports are the packages of the routers other than the http.ServeMux.
+var routerImports = map[string]string{
+	serverChi:  "github.com/go-chi/chi/v5",
+	serverEcho: "github.com/labstack/echo/v4",
+	serverGin:  "github.com/gin-gonic/gin",
+}
+
+// serverNames are the names the server is written with, a type of the spec taking them leaves
+// no room for it.
+var serverNames = []string{"Server", "ServerError", "RegisterHandlers"}
+
+// serverFile returns the name of the file the server of target is written to, next to it.
+func serverFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_server.go"
+}
+
+// writeServer writes, with --server, a Server interface next to the target with a method for
+// each operation of the paths, the ones of the Client of --client, and RegisterHandlers routing
+// the requests to it on a http.ServeMux or the router of chi, echo or gin. The handlers read the
+// parameters and the request body into the generated types and write what the methods return as
+// JSON.
+func writeServer(ctx context.Context, c *config, types []*Type) error {
+	reserved := serverNames
+	if c.server == serverEcho {
+		reserved = append(reserved[:len(reserved):len(reserved)], "EchoRouter")
+	}
+	_, ops, err := readOperations(ctx, c, types, "server", reserved)
+	if err != nil {
+		return err
+	}
+	if c.server != serverChi {
+		ops = wholeSegmentOperations(c, ops)
+	}
+	taken := make(map[string]bool, len(types))
+	for _, t := range types {
+		taken[capitalize(t.Name)] = true
+	}
+	groups := serverGroups(c, ops)
+	for _, g := range groups {
+		if g.tag != "" && (taken[g.name] || taken[g.register]) {
+			return fmt.Errorf("a type takes the name of the interface %s or of %s, written for the operations tagged %s", g.name, g.register, g.tag)
+		}
+	}
+	for _, op := range ops {
+		if taken[bindName(op)] {
+			return fmt.Errorf("the type %s takes the name of the function binding the parameters of %s", bindName(op), op.name)
+		}
+		for _, rw := range responseWriters(op) {
+			if taken[rw.name] {
+				return fmt.Errorf("the type %s takes the name of the function writing the responses of %s", rw.name, op.name)
+			}
+		}
+	}
+	imports := []string{"encoding/json", "errors", "fmt", "net/http", "net/url", "reflect", "strconv", "strings"}
+	if len(ops) > 0 {
+		imports = append(imports, "context")
+	}
+	seen := map[string]bool{}
+	for _, pkg := range imports {
+		seen[pkg] = true
+	}
+	checks := make([]map[string][]fieldCheck, len(ops))
+	for i, op := range ops {
+		var pkgs []string
+		checks[i], pkgs = bindChecks(c, op)
+		for _, pkg := range pkgs {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
+			}
+		}
+	}
+	sort.Strings(imports)
+	if pkg, ok := routerImports[c.server]; ok {
+		imports = append(imports, pkg)
+	}
+	return writeFile(c, serverFile(c.targetFile), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		writeHeading(w, c, imports)
+		if c.serverInterfaces == serverInterfacesPerTag {
+			w.WriteString(`// Server is implemented by the handlers of all the operations of the spec, RegisterHandlers
+// routes the requests to it. Errors that are a *ServerError answer with its status, others with
+// 500.
+type Server interface {
+`)
+			for _, g := range groups {
+				fmt.Fprintf(w, "\t%s\n", g.name)
+			}
+			w.WriteString("}\n")
+			for _, g := range groups {
+				w.WriteString("\n")
+				writeServerInterface(w, g)
+			}
+		} else {
+			w.WriteString(`// Server is implemented by the handlers of the operations of the spec, RegisterHandlers routes
+// the requests to it. Errors that are a *ServerError answer with its status, others with 500.
+`)
+			writeServerInterface(w, groups[0])
+		}
+		// the Params structs are written once, by the client when there is one.
+		if !c.client {
+			for _, op := range ops {
+				if len(op.params) > 0 {
+					writeOperationParams(w, op)
+				}
+			}
+		}
+		for i, op := range ops {
+			writeBind(w, op, checks[i])
+			writeResponseWriters(w, op)
+		}
+		router, param := "router", ""
+		switch c.server {
+		case serverChi:
+			fmt.Fprint(w, "\n// RegisterHandlers routes the operations of the spec to s on router.\n")
+			param = "router chi.Router"
+		case serverEcho:
+			fmt.Fprint(w, "\n// EchoRouter is where RegisterHandlers routes the operations, an *echo.Echo or *echo.Group.\n")
+			fmt.Fprint(w, "type EchoRouter interface {\n")
+			fmt.Fprint(w, "\tAdd(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route\n}\n")
+			fmt.Fprint(w, "\n// RegisterHandlers routes the operations of the spec to s on router.\n")
+			param = "router EchoRouter"
+		case serverGin:
+			fmt.Fprint(w, "\n// RegisterHandlers routes the operations of the spec to s on router, a *gin.Engine or\n")
+			fmt.Fprint(w, "// *gin.RouterGroup.\n")
+			param = "router gin.IRoutes"
+		default:
+			fmt.Fprint(w, "\n// RegisterHandlers routes the operations of the spec to s on mux, with the patterns of the\n")
+			fmt.Fprint(w, "// http.ServeMux of Go 1.22.\n")
+			router, param = "mux", "mux *http.ServeMux"
+		}
+		fmt.Fprintf(w, "func RegisterHandlers(%s, s Server) {\n", param)
+		if c.serverInterfaces != serverInterfacesPerTag {
+			for _, op := range ops {
+				writeHandler(w, c, op)
+			}
+			w.WriteString("}\n")
+		} else {
+			for _, g := range groups {
+				fmt.Fprintf(w, "\t%s(%s, s)\n", g.register, router)
+			}
+			w.WriteString("}\n")
+			for _, g := range groups {
+				fmt.Fprintf(w, "\n// %s routes the %s to s on %s.\n", g.register, g.what(), router)
+				fmt.Fprintf(w, "func %s(%s, s %s) {\n", g.register, param, g.name)
+				for _, op := range g.ops {
+					writeHandler(w, c, op)
+				}
+				w.WriteString("}\n")
+			}
+		}
+		writeServerHelpers(w)
+		if !c.client {
+			writeStyleHelpers(w)
+		}
+		return w.Flush()
+	})
+}
+
+// serverGroup is an interface of the server, with the operations it has the methods of and the
+// function registering their handlers.
+type serverGroup struct {
+	name, register string
+	// tag is the one of the operations, empty for the Server holding them all and for the
+	// operations without tags.
+	tag string
+	ops []apiOperation
+}
+
+// what returns the operations of g as the docs of the group tell them.
+func (g serverGroup) what() string {
+	if g.tag == "" {
+		return "operations without tags"
+	}
+	return "operations tagged " + g.tag
+}
+
+// untaggedServer is the interface of the operations without tags with --server-interfaces=per-tag.
+const untaggedServer = "UntaggedServer"
+
+// serverGroups returns the interfaces of the server, the Server alone or, with
+// --server-interfaces=per-tag, one per tag, named after it as PetsServer, in the order the
+// operations use them. Operations with several tags are in the interface of the first one.
+func serverGroups(c *config, ops []apiOperation) []serverGroup {
+	if c.serverInterfaces != serverInterfacesPerTag {
+		return []serverGroup{{name: "Server", ops: ops}}
+	}
+	var groups []serverGroup
+	byName := map[string]int{}
+	for _, op := range ops {
+		name := untaggedServer
+		if op.tag != "" {
+			name = fieldName(nonIdentifier.ReplaceAllString(op.tag, "_")) + "Server"
+		}
+		i, ok := byName[name]
+		if !ok {
+			i = len(groups)
+			byName[name] = i
+			groups = append(groups, serverGroup{name: name, register: "Register" + strings.TrimSuffix(name, "Server") + "Handlers", tag: op.tag})
+		}
+		groups[i].ops = append(groups[i].ops, op)
+	}
+	return groups
+}
+
+// writeServerInterface writes the interface of g, its doc comment is written by the caller for
+// the Server alone.
+func writeServerInterface(w *bufio.Writer, g serverGroup) {
+	if g.tag != "" || g.name == untaggedServer {
+		doc := fmt.Sprintf("%s is implemented by the handlers of the %s, %s routes the requests to it.", g.name, g.what(), g.register)
+		for _, l := range wrapLine(doc, 97) {
+			fmt.Fprintf(w, "// %s\n", l)
+		}
+	}
+	fmt.Fprintf(w, "type %s interface {\n", g.name)
+	for i, op := range g.ops {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		fmt.Fprintf(w, "\t// %s handles %s %s.\n", op.name, op.method, op.path)
+		writeSummary(w, "\t", op)
+		fmt.Fprintf(w, "\t%s\n", operationSignature(op))
+	}
+	w.WriteString("}\n")
+}
+
+// routePath returns the path of op with its parameters named as in Go, as {name} or, for echo and
+// gin, :name.
+func routePath(c *config, op apiOperation) string {
+	byName := make(map[string]string, len(op.pathParams))
+	for _, p := range op.pathParams {
+		byName[p.name] = p.goName
+	}
+	return pathTemplate.ReplaceAllStringFunc(op.path, func(v string) string {
+		if c.server == serverEcho || c.server == serverGin {
+			return ":" + byName[v[1:len(v)-1]]
+		}
+		return "{" + byName[v[1:len(v)-1]] + "}"
+	})
+}
+
+// wholeSegmentOperations leaves out the operations the http.ServeMux, echo and gin can not route,
+// the ones with path parameters that are only part of a segment.
+func wholeSegmentOperations(c *config, ops []apiOperation) []apiOperation {
+	kept := ops[:0]
+	for _, op := range ops {
+		partial := false
+		for _, segment := range strings.Split(op.path, "/") {
+			if strings.Contains(segment, "{") && pathTemplate.FindString(segment) != segment {
+				partial = true
+			}
+		}
+		if partial {
+			c.warn(op.method+" "+op.path, "the router of %s only matches whole segments, the operation is left out of the server", c.server)
+			continue
+		}
+		kept = append(kept, op)
+	}
+	return kept
+}
+
+// writeHandler writes the registration of the handler of op, which binds its parameters, reads its
+// body, calls the method of the Server and writes what it returns.
+func writeHandler(w *bufio.Writer, c *config, op apiOperation) {
+	pattern := routePath(c, op)
+	// echo handlers return an error, the ones of the handlers are already written.
+	ret := "return"
+	switch c.server {
+	case serverChi:
+		fmt.Fprintf(w, "\trouter.MethodFunc(%q, %q, func(w http.ResponseWriter, r *http.Request) {\n", op.method, pattern)
+	case serverEcho:
+		fmt.Fprintf(w, "\trouter.Add(%q, %q, func(e echo.Context) error {\n", op.method, pattern)
+		fmt.Fprint(w, "\t\tw, r := e.Response(), e.Request()\n")
+		ret = "return nil"
+	case serverGin:
+		fmt.Fprintf(w, "\trouter.Handle(%q, %q, func(g *gin.Context) {\n", op.method, pattern)
+		fmt.Fprint(w, "\t\tw, r := g.Writer, g.Request\n")
+	default:
+		// a path ending in a slash matches the ones below it unless it is closed by {$}.
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "{$}"
+		}
+		fmt.Fprintf(w, "\tmux.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n", op.method+" "+pattern)
+	}
+	args := []string{"r.Context()"}
+	bound := len(op.pathParams)+len(op.params) > 0
+	if bound {
+		var values []string
+		for _, p := range op.pathParams {
+			values = append(values, p.goName)
+		}
+		if len(op.params) > 0 {
+			values = append(values, "params")
+		}
+		fmt.Fprintf(w, "\t\t%s, err := %s\n", strings.Join(values, ", "), bindCall(c, op))
+		fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\tlacError(w, err)\n\t\t\t%s\n\t\t}\n", ret)
+		args = append(args, values...)
+	}
+	if op.body != "" {
+		fmt.Fprintf(w, "\t\tvar body %s\n", op.body)
+		fmt.Fprint(w, "\t\tif err := json.NewDecoder(r.Body).Decode(&body); err != nil {\n")
+		fmt.Fprintf(w, "\t\t\tlacError(w, lacBadRequest(\"decoding the request body\", err))\n\t\t\t%s\n\t\t}\n", ret)
+		args = append(args, "body")
+	}
+	call := fmt.Sprintf("s.%s(%s)", op.name, strings.Join(args, ", "))
+	switch {
+	case op.result != "":
+		fmt.Fprintf(w, "\t\tresult, err := %s\n", call)
+	case bound:
+		fmt.Fprintf(w, "\t\terr = %s\n", call)
+	default:
+		fmt.Fprintf(w, "\t\terr := %s\n", call)
+	}
+	fmt.Fprintf(w, "\t\tif err != nil {\n\t\t\tlacError(w, err)\n\t\t\t%s\n\t\t}\n", ret)
+	if op.result != "" {
+		fmt.Fprintf(w, "\t\tlacRespond(w, %d, result)\n", op.status)
+	} else {
+		fmt.Fprintf(w, "\t\tw.WriteHeader(%d)\n", op.status)
+	}
+	if c.server == serverEcho {
+		fmt.Fprint(w, "\t\treturn nil\n")
+	}
+	fmt.Fprint(w, "\t})\n")
+}
+
+// writeServerHelpers writes the ServerError and the helpers of the handlers.
+func writeServerHelpers(w *bufio.Writer) {
+	w.WriteString(`
+// ServerError is returned by the methods of Server to answer with a status other than 500,
+// Message is written as the body.
+type ServerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ServerError) Error() string {
+	return e.Message
+}
+
+// lacError answers with the status and message of err when it is a *ServerError, other errors
+// are not written as they may tell more than the clients should know.
+func lacError(w http.ResponseWriter, err error) {
+	var se *ServerError
+	if errors.As(err, &se) {
+		http.Error(w, se.Message, se.StatusCode)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// lacBadRequest returns the *ServerError answering 400 for err, which happened reading what.
+func lacBadRequest(what string, err error) error {
+	return &ServerError{StatusCode: http.StatusBadRequest, Message: what + ": " + err.Error()}
+}
+
+// lacWrite writes v as the JSON body of a response with status and mediaType.
+func lacWrite(w http.ResponseWriter, mediaType string, status int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+// lacRespond writes result as the JSON body of a response with status.
+func lacRespond(w http.ResponseWriter, status int, result interface{}) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		lacError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// lacParam reads the values of a parameter into v, a pointer to where it is kept. Pointers are
+// left nil and slices empty when there are no values.
+func lacParam(values []string, required bool, v interface{}) error {
+	if len(values) == 0 {
+		if required {
+			return errors.New("it is required")
+		}
+		return nil
+	}
+	dst := reflect.ValueOf(v).Elem()
+	switch dst.Kind() {
+	case reflect.Ptr:
+		dst.Set(reflect.New(dst.Type().Elem()))
+		return lacParse(values[0], dst.Elem())
+	case reflect.Slice:
+		items := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := lacParse(s, items.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(items)
+		return nil
+	}
+	return lacParse(values[0], dst)
+}
+
+// lacQueryParam reads the query parameter name into v as style and explode say, for the arrays and
+// objects whose values are not the key of the parameter repeated. Arrays are split at the
+// delimiter of the style, the fields of objects are read from name[field] with deepObject, from
+// keys of their own when exploded and from pairs of keys and values joined with commas otherwise.
+func lacQueryParam(query url.Values, name, style string, explode, required bool, v interface{}) error {
+	dst := reflect.ValueOf(v).Elem()
+	t := dst.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		values := query[name]
+		if t.Kind() == reflect.Slice && !(style == "form" && explode) && len(values) > 0 {
+			values = strings.Split(values[0], lacDelimiter(style))
+		}
+		return lacParam(values, required, v)
+	}
+	fields := map[string]string{}
+	switch {
+	case style == "deepObject":
+		for key, values := range query {
+			if strings.HasPrefix(key, name+"[") && strings.HasSuffix(key, "]") && len(values) > 0 {
+				fields[key[len(name)+1:len(key)-1]] = values[0]
+			}
+		}
+	case explode:
+		for key, values := range query {
+			if len(values) > 0 {
+				fields[key] = values[0]
+			}
+		}
+	case query.Get(name) != "":
+		pairs := strings.Split(query.Get(name), ",")
+		if len(pairs)%2 != 0 {
+			return errors.New("its fields are not pairs of keys and values")
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			fields[pairs[i]] = pairs[i+1]
+		}
+	}
+	obj := reflect.New(t)
+	found := false
+	for i := 0; i < t.NumField(); i++ {
+		key, _ := lacFieldKey(t.Field(i))
+		s, ok := fields[key]
+		if key == "" || !ok {
+			continue
+		}
+		found = true
+		f := obj.Elem().Field(i)
+		if f.Kind() == reflect.Ptr {
+			f.Set(reflect.New(f.Type().Elem()))
+			f = f.Elem()
+		}
+		if err := lacParse(s, f); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	switch {
+	case !found && required:
+		return errors.New("it is required")
+	case !found:
+	case dst.Kind() == reflect.Ptr:
+		dst.Set(obj)
+	default:
+		dst.Set(obj.Elem())
+	}
+	return nil
+}
+
+// lacParse sets v, a string, number or bool, to the value s holds.
+func lacParse(s string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("a %s can not be read from a string", v.Type())
+	}
+	return nil
+}
+`)
+}
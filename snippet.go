@@ -0,0 +1,104 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// snippetCommand is the first argument that runs runSnippet instead of a regular generation.
+const snippetCommand = "snippet"
+
+// runSnippet makes the types of a single JSON blob, the argument, stdin when it is - or the
+// clipboard when there is none, and prints only their definitions, formatted, to be pasted into
+// an existing file. The imports they need and the warnings go to stderr.
+func runSnippet(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("LAC "+snippetCommand, flag.ContinueOnError)
+	name := flags.String("name", "AutoGenerated", "name of the outer type.")
+	c, err := parseFlags(flags, args, 1, nil)
+	if err != nil {
+		return fmt.Errorf("flags step: %w", err)
+	}
+	var blob []byte
+	switch {
+	case flags.NArg() == 0:
+		blob, err = readClipboard(ctx)
+	case flags.Arg(0) == "-":
+		blob, err = ioutil.ReadAll(os.Stdin)
+	default:
+		blob = []byte(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(blob)) == 0 {
+		return errors.New("there is no JSON to make types of")
+	}
+	file := *name + ".json"
+	c.fileSystem = newMemoryFileSystem(map[string][]byte{file: blob})
+	c.sourceFiles, c.harFiles = []string{file}, nil
+	c.swaggerFile, c.jsonSchema, c.asyncAPI, c.proto, c.versions = "", false, false, false, nil
+	// the snippet is always printed.
+	c.targetFile = ""
+	c.snippet = true
+	var out bytes.Buffer
+	if err := run(ctx, c, &out); err != nil {
+		return err
+	}
+	code := bytes.TrimSpace(out.Bytes())
+	if formatted, err := format.Source(code); err == nil {
+		code = formatted
+	}
+	if imports := collectImports(c, c.generated); len(imports) > 0 {
+		fmt.Fprintf(os.Stderr, "the snippet needs these imports: %s\n", strings.Join(imports, ", "))
+	}
+	_, err = fmt.Fprintf(os.Stdout, "%s\n", code)
+	return err
+}
+
+// clipboardCommands are the commands that print the clipboard, by GOOS, the first one found is
+// used.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbpaste"}},
+	"windows": {{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}},
+}
+
+// unixClipboardCommands print the clipboard on the rest, for Wayland and X11.
+var unixClipboardCommands = [][]string{
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+}
+
+// readClipboard returns the contents of the clipboard with the first clipboard command installed.
+func readClipboard(ctx context.Context) ([]byte, error) {
+	commands, ok := clipboardCommands[runtime.GOOS]
+	if !ok {
+		commands = unixClipboardCommands
+	}
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, command[0])
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		b, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("reading the clipboard with %s: %w", command[0], err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("no JSON given and none of %s is installed to read the clipboard", strings.Join(names, ", "))
+}
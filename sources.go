@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// sourceRef identifies a single document inside a sourceProvider, it is a path, URL or whatever
+// else the provider understands.
+type sourceRef string
+
+// sourceProvider lists and opens documents from some origin such as the local disk, an HTTP
+// server or, through --provider, a git repo or an internal schema registry.
+type sourceProvider interface {
+	// List returns all the documents the provider knows about.
+	List() []sourceRef
+	// Open returns the contents of a document, the caller must close it.
+	Open(sourceRef) (io.ReadCloser, error)
+}
+
+// sourceProviderFactory builds a sourceProvider from a location as passed in the flags.
+type sourceProviderFactory func(ctx context.Context, location string) (sourceProvider, error)
+
+// sourceProviders holds the factories for each supported location scheme, anything without a
+// scheme is considered to be a file. The commands given with --provider take precedence.
+var sourceProviders = map[string]sourceProviderFactory{
+	"http":  newHTTPProvider,
+	"https": newHTTPProvider,
+}
+
+// providerFor returns the sourceProvider in charge of the passed location.
+func providerFor(ctx context.Context, c *config, location string) (sourceProvider, error) {
+	i := strings.Index(location, "://")
+	if i <= 0 {
+		return &fileProvider{files: c.files(), pattern: location}, nil
+	}
+	scheme := location[:i]
+	if command, ok := c.providers[scheme]; ok {
+		return newCommandProvider(ctx, command, location)
+	}
+	factory, ok := sourceProviders[scheme]
+	if !ok {
+		return nil, fmt.Errorf("no source provider for %q, one can be given with --provider %s=command", scheme, scheme)
+	}
+	return factory(ctx, location)
+}
+
+// fileProvider reads documents from the FileSystem of the run, its pattern can contain
+// wildcards.
+type fileProvider struct {
+	files   FileSystem
+	pattern string
+}
+
+func (f *fileProvider) List() []sourceRef {
+	g, err := f.files.Glob(f.pattern)
+	if err != nil {
+		return []sourceRef{sourceRef(f.pattern)}
+	}
+	refs := make([]sourceRef, 0, len(g))
+	for _, e := range g {
+		refs = append(refs, sourceRef(e))
+	}
+	return refs
+}
+
+func (f *fileProvider) Open(r sourceRef) (io.ReadCloser, error) {
+	return f.files.Open(string(r))
+}
+
+var _ sourceProvider = &fileProvider{}
+
+// httpProvider fetches a single document with a GET request.
+type httpProvider struct {
+	ctx context.Context
+	url string
+}
+
+func newHTTPProvider(ctx context.Context, location string) (sourceProvider, error) {
+	return &httpProvider{ctx: ctx, url: location}, nil
+}
+
+func (h *httpProvider) List() []sourceRef {
+	return []sourceRef{sourceRef(h.url)}
+}
+
+func (h *httpProvider) Open(r sourceRef) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, string(r), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", r, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", r, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+var _ sourceProvider = &httpProvider{}
+
+// commandProvider runs the command given with --provider for the scheme of its location, as
+// "command list location" to print the documents there one per line and as "command open
+// document" to print the contents of one.
+type commandProvider struct {
+	ctx      context.Context
+	command  []string
+	location string
+	// listErr is why listing failed, opening reports it.
+	listErr error
+}
+
+func newCommandProvider(ctx context.Context, command, location string) (sourceProvider, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the provider of %s has no command", location)
+	}
+	return &commandProvider{ctx: ctx, command: fields, location: location}, nil
+}
+
+// run returns what the command prints to stdout for args, along with what it printed to stderr
+// when it fails.
+func (p *commandProvider) run(args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(p.ctx, p.command[0], append(p.command[1:], args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		return nil, fmt.Errorf("running %s %s: %w: %s", p.command[0], strings.Join(args, " "), err, msg)
+	} else if err != nil {
+		return nil, fmt.Errorf("running %s %s: %w", p.command[0], strings.Join(args, " "), err)
+	}
+	return b, nil
+}
+
+// List returns the location itself when the command lists nothing or fails, opening it then
+// reports what went wrong.
+func (p *commandProvider) List() []sourceRef {
+	b, err := p.run("list", p.location)
+	if err != nil {
+		p.listErr = err
+		return []sourceRef{sourceRef(p.location)}
+	}
+	var refs []sourceRef
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			refs = append(refs, sourceRef(line))
+		}
+	}
+	if len(refs) == 0 {
+		return []sourceRef{sourceRef(p.location)}
+	}
+	return refs
+}
+
+func (p *commandProvider) Open(r sourceRef) (io.ReadCloser, error) {
+	if p.listErr != nil {
+		return nil, p.listErr
+	}
+	b, err := p.run("open", string(r))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+var _ sourceProvider = &commandProvider{}
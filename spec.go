@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// specDocs is the page showing the spec, Redoc is loaded from its CDN so nothing else needs to
+// be served.
+const specDocs = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+</head>
+<body>
+<redoc spec-url="openapi.json"></redoc>
+<script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// writeSpecHandler writes the spec the types were generated from as a constant, along with a
+// handler serving it at /openapi.json and a Redoc page showing it at /docs.
+func writeSpecHandler(ctx context.Context, w io.Writer, c *config) error {
+	// YAML specs are served as the JSON they were converted to.
+	spec, err := readSpec(ctx, c)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "// openAPISpec is the spec the types in this file were generated from, %q.\n", c.swaggerFile)
+	fmt.Fprintf(w, "const openAPISpec = %s\n\n", rawString(string(spec)))
+	fmt.Fprint(w, "// openAPIDocs is the page showing openAPISpec.\n")
+	fmt.Fprintf(w, "const openAPIDocs = %s\n\n", rawString(specDocs))
+	fmt.Fprint(w, "// OpenAPIHandler serves the spec at /openapi.json and a page documenting it at /docs.\n")
+	fmt.Fprint(w, "func OpenAPIHandler() http.Handler {\n")
+	fmt.Fprint(w, "\tmux := http.NewServeMux()\n")
+	fmt.Fprint(w, "\tmux.HandleFunc(\"/openapi.json\", func(w http.ResponseWriter, r *http.Request) {\n")
+	fmt.Fprint(w, "\t\tw.Header().Set(\"Content-Type\", \"application/json\")\n")
+	fmt.Fprint(w, "\t\t_, _ = io.WriteString(w, openAPISpec)\n\t})\n")
+	fmt.Fprint(w, "\tmux.HandleFunc(\"/docs\", func(w http.ResponseWriter, r *http.Request) {\n")
+	fmt.Fprint(w, "\t\tw.Header().Set(\"Content-Type\", \"text/html; charset=utf-8\")\n")
+	fmt.Fprint(w, "\t\t_, _ = io.WriteString(w, openAPIDocs)\n\t})\n")
+	fmt.Fprint(w, "\treturn mux\n}\n\n")
+	return nil
+}
+
+// rawString returns s as a Go raw string literal, backquotes are concatenated as interpreted
+// strings since raw ones can not hold them.
+func rawString(s string) string {
+	return "`" + strings.Replace(s, "`", "` + \"`\" + `", -1) + "`"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// sqlNullImports are the packages needed by the null types of --db sql.
+var sqlNullImports = []string{"database/sql", "encoding/json"}
+
+// sqlNulls maps the null types of --db sql to the database/sql type they embed and the field of
+// it holding the value. The types of database/sql have no JSON methods, these add them.
+var sqlNulls = map[string][2]string{
+	"NullString":  {"sql.NullString", "String"},
+	"NullInt64":   {"sql.NullInt64", "Int64"},
+	"NullInt32":   {"sql.NullInt32", "Int32"},
+	"NullInt16":   {"sql.NullInt16", "Int16"},
+	"NullFloat64": {"sql.NullFloat64", "Float64"},
+	"NullBool":    {"sql.NullBool", "Bool"},
+	"NullTime":    {"sql.NullTime", "Time"},
+}
+
+// sqlNullHelpersFor returns the null types of --db sql held by the fields of types.
+func sqlNullHelpersFor(c *config, types []*Type) map[string]bool {
+	helpers := map[string]bool{}
+	if c.dbMode != "sql" {
+		return helpers
+	}
+	for _, t := range types {
+		structName := capitalize(t.Name)
+		for _, fld := range t.Fields {
+			if fld.Name == "" {
+				continue
+			}
+			tn, _ := fieldType(c, structName, fld.GoName(), &fld.Type)
+			if tn = strings.TrimPrefix(tn, "*"); sqlNulls[tn][0] != "" {
+				helpers[tn] = true
+			}
+		}
+	}
+	return helpers
+}
+
+// markSQLNulls leaves the null types whose name is taken by a type out, the fields needing them
+// are pointers instead.
+func markSQLNulls(c *config, types []*Type) {
+	c.sqlNullsTaken = map[string]bool{}
+	helpers := sqlNullHelpersFor(c, types)
+	for _, t := range types {
+		name := capitalize(t.Name)
+		if !helpers[name] {
+			continue
+		}
+		c.warn(t.Name, "the type is named %s, the fields needing it are pointers", name)
+		c.sqlNullsTaken[name] = true
+	}
+}
+
+// writeSQLNullHelpers writes the null types of --db sql needed by the fields of types.
+func writeSQLNullHelpers(w io.Writer, c *config, types []*Type) {
+	helpers := sqlNullHelpersFor(c, types)
+	names := make([]string, 0, len(helpers))
+	for name := range helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		io.WriteString(w, sqlNullHelper(name, sqlNulls[name][0], sqlNulls[name][1]))
+	}
+}
+
+// sqlNullHelper returns the declaration of the null type name, embedding the database/sql type
+// embedded, whose value is in field, so it is read from and written as a JSON value or null.
+func sqlNullHelper(name, embedded, field string) string {
+	return fmt.Sprintf(`// %[1]s is a %[2]s read from and written as JSON, null when it is not Valid.
+type %[1]s struct {
+	%[2]s
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n %[1]s) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.%[3]s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *%[1]s) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		n.%[4]s = %[2]s{}
+		return nil
+	}
+	if err := json.Unmarshal(b, &n.%[3]s); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+var (
+	_ json.Marshaler   = %[1]s{}
+	_ json.Unmarshaler = (*%[1]s)(nil)
+)
+
+`, name, embedded, field, strings.TrimPrefix(embedded, "sql."))
+}
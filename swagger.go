@@ -1,9 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"reflect"
 	"strings"
 )
@@ -29,7 +30,8 @@ const (
 // SwaggerXML represents the XML attribute in swagger specs
 type SwaggerXML struct {
 	Name      string `json:"name,omitempty"`
-	Attribute string `json:"attribute,omitempty"`
+	Attribute bool   `json:"attribute,omitempty"`
+	Wrapped   bool   `json:"wrapped,omitempty"`
 }
 
 // OnlyRef represents a simple object that only contains a ref to another component.
@@ -46,44 +48,404 @@ type MultiProperties struct {
 
 // MetaSwaggerProperty holds the set of common fields to several properties.
 type MetaSwaggerProperty struct {
-	Type            SwaggerType `json:"type,omitempty"`
-	Ref             string      `json:"$ref,omitempty"`
-	Required        bool        `json:"required,omitempty"`
-	Description     string      `json:"description,omitempty"`
-	Format          string      `json:"format,omitempty"`
-	ReadOnly        bool        `json:"readOnly,omitempty"` // ill ignore this
-	Enum            []string    `json:"enum,omitempty"`
+	Type        SwaggerType     `json:"type,omitempty"`
+	Ref         string          `json:"$ref,omitempty"`
+	Required    SwaggerRequired `json:"required,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Format      string          `json:"format,omitempty"`
+	Nullable    bool            `json:"nullable,omitempty"`
+	ReadOnly    bool            `json:"readOnly,omitempty"`
+	WriteOnly   bool            `json:"writeOnly,omitempty"`
+	// Go specific extensions, they override what LAC makes of the schema.
+	GoType       string           `json:"x-go-type,omitempty"`
+	GoTypeImport *SwaggerGoImport `json:"x-go-type-import,omitempty"`
+	GoName       string           `json:"x-go-name,omitempty"`
+	Enum         []interface{}    `json:"enum,omitempty"`
+	// Example and Examples make the fixtures of --fixtures.
+	Example  json.RawMessage `json:"example,omitempty"`
+	Examples SwaggerExamples `json:"examples,omitempty"`
+	// Default is the value of the property when it is left out, see --constructors.
+	Default json.RawMessage `json:"default,omitempty"`
+	XML     *SwaggerXML     `json:"xml,omitempty"`
+	// validation keywords.
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum bool     `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool     `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int64   `json:"minLength,omitempty"`
+	MaxLength        *int64   `json:"maxLength,omitempty"`
+	MinItems         *int64   `json:"minItems,omitempty"`
+	MaxItems         *int64   `json:"maxItems,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	MinProperties    *int64   `json:"minProperties,omitempty"`
+	MaxProperties    *int64   `json:"maxProperties,omitempty"`
+	// content of strings holding encoded data.
+	ContentEncoding  string           `json:"contentEncoding,omitempty"`
+	ContentMediaType string           `json:"contentMediaType,omitempty"`
+	ContentSchema    *SwaggerProperty `json:"contentSchema,omitempty"`
+	// Not holds a schema the values must not match.
+	Not             *SwaggerProperty `json:"not,omitempty"`
 	MultiProperties `json:",inline"`
 }
 
+// SwaggerRequired represents the required keyword, the names of the required properties of an
+// object schema or, as some specs still write it, whether the property itself is.
+type SwaggerRequired struct {
+	Names []string
+	Self  bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler accepting both the array and the boolean forms.
+func (sr *SwaggerRequired) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		return withinPart(trimmed, json.Unmarshal(trimmed, &sr.Names))
+	}
+	return withinPart(trimmed, json.Unmarshal(trimmed, &sr.Self))
+}
+
+// has returns true if the property name is required.
+func (sr SwaggerRequired) has(name string) bool {
+	for _, n := range sr.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // SwaggerItems represents the Item property of swagger schemas
 type SwaggerItems struct {
 	MetaSwaggerProperty `json:",inline"`
+	// Tuple holds the schema of each position when items is an array, the legacy form of
+	// prefixItems.
+	Tuple []SwaggerProperty `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler accepting the array form of items too, booleans
+// allow or forbid more items and are ignored.
+func (si *SwaggerItems) UnmarshalJSON(b []byte) error {
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return withinPart(trimmed, json.Unmarshal(trimmed, &si.Tuple))
+	case bytes.Equal(trimmed, []byte("true")), bytes.Equal(trimmed, []byte("false")):
+		return nil
+	}
+	return withinPart(b, json.Unmarshal(b, &si.MetaSwaggerProperty))
 }
 
 // SwaggerProperty represents the Property attribute of swagger schemas.
 type SwaggerProperty struct {
 	MetaSwaggerProperty  `json:",inline"`
-	Items                SwaggerItems     `json:"items,omitempty"`
-	AdditionalProperties *SwaggerProperty `json:"additionalProperties,omitempty"`
+	Items                SwaggerItems      `json:"items,omitempty"`
+	PrefixItems          []SwaggerProperty `json:"prefixItems,omitempty"`
+	AdditionalProperties *SwaggerProperty  `json:"additionalProperties,omitempty"`
+	// PatternProperties holds the schemas of the values by the pattern their keys match.
+	PatternProperties SwaggerProperties `json:"patternProperties,omitempty"`
+	// PropertyNames holds the schema the keys of additionalProperties must match.
+	PropertyNames *SwaggerProperty `json:"propertyNames,omitempty"`
+	// Properties holds the properties of inline objects, such as the values of
+	// additionalProperties.
+	Properties SwaggerProperties `json:"properties,omitempty"`
+	// False is set for the false schema, which forbids any value where true allows all of them.
+	False bool `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler accepting the boolean schemas too, true is read as
+// the empty schema.
+func (sp *SwaggerProperty) UnmarshalJSON(b []byte) error {
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.Equal(trimmed, []byte("true")):
+		return nil
+	case bytes.Equal(trimmed, []byte("false")):
+		sp.False = true
+		return nil
+	}
+	type plain SwaggerProperty
+	return withinPart(b, json.Unmarshal(b, (*plain)(sp)))
 }
 
 // SwaggerSchema represents the Schema attribute on swagger schemas
 type SwaggerSchema struct {
-	Type            SwaggerType                `json:"type,omitempty"`
-	Description     string                     `json:"description,omitempty"`
-	Properties      map[string]SwaggerProperty `json:"properties,omitempty"`
+	// Ref is only found in the schemas of bodies and parameters, components are not refs.
+	Ref             string            `json:"$ref,omitempty"`
+	Type            SwaggerType       `json:"type,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	Enum            []interface{}     `json:"enum,omitempty"`
+	Properties      SwaggerProperties `json:"properties,omitempty"`
+	Required        SwaggerRequired   `json:"required,omitempty"`
+	Items           SwaggerItems      `json:"items,omitempty"`
+	PrefixItems     []SwaggerProperty `json:"prefixItems,omitempty"`
 	MultiProperties `json:",inline"`
+	// Discriminator tells the schemas of a oneOf apart.
+	Discriminator *SwaggerDiscriminator `json:"discriminator,omitempty"`
+	// PatternProperties only become maps in properties, components declaring them are warned.
+	PatternProperties SwaggerProperties `json:"patternProperties,omitempty"`
+	// GoName is the name of the type, from x-go-name. x-go-type is only honored for properties.
+	GoName string `json:"x-go-name,omitempty"`
+	GoType string `json:"x-go-type,omitempty"`
+	// Example and Examples make the fixtures of --fixtures.
+	Example  json.RawMessage `json:"example,omitempty"`
+	Examples SwaggerExamples `json:"examples,omitempty"`
+
+	// enumNames are the names of the constants of Enum, only .proto files give them.
+	enumNames []string
+}
+
+// SwaggerDiscriminator represents the discriminator of a oneOf, the property naming the schema
+// of the value and, optionally, the schema for each value of it.
+type SwaggerDiscriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler accepting the Swagger 2.0 form too, where the
+// discriminator is only the name of the property.
+func (sd *SwaggerDiscriminator) UnmarshalJSON(b []byte) error {
+	if trimmed := bytes.TrimSpace(b); bytes.HasPrefix(trimmed, []byte(`"`)) {
+		return withinPart(trimmed, json.Unmarshal(trimmed, &sd.PropertyName))
+	}
+	type plain SwaggerDiscriminator
+	return withinPart(b, json.Unmarshal(b, (*plain)(sd)))
+}
+
+// SwaggerProperties holds the properties of a schema in the order they were declared.
+type SwaggerProperties struct {
+	Names  []string
+	ByName map[string]SwaggerProperty
+}
+
+// UnmarshalJSON implements json.Unmarshaler keeping the declaration order.
+func (sp *SwaggerProperties) UnmarshalJSON(b []byte) error {
+	sp.ByName = map[string]SwaggerProperty{}
+	return decodeObjectInOrder(b, func(key string, value []byte) error {
+		var p SwaggerProperty
+		if err := json.Unmarshal(value, &p); err != nil {
+			return fmt.Errorf("decoding property %s: %w", key, err)
+		}
+		if _, dup := sp.ByName[key]; !dup {
+			sp.Names = append(sp.Names, key)
+		}
+		sp.ByName[key] = p
+		return nil
+	})
+}
+
+// SwaggerSchemas holds the schemas of a spec in the order they were declared.
+type SwaggerSchemas struct {
+	Names  []string
+	ByName map[string]SwaggerSchema
+}
+
+// UnmarshalJSON implements json.Unmarshaler keeping the declaration order.
+func (ss *SwaggerSchemas) UnmarshalJSON(b []byte) error {
+	ss.ByName = map[string]SwaggerSchema{}
+	return decodeObjectInOrder(b, func(key string, value []byte) error {
+		var s SwaggerSchema
+		if err := json.Unmarshal(value, &s); err != nil {
+			return fmt.Errorf("decoding schema %s: %w", key, err)
+		}
+		if _, dup := ss.ByName[key]; !dup {
+			ss.Names = append(ss.Names, key)
+		}
+		ss.ByName[key] = s
+		return nil
+	})
+}
+
+// decodeObjectInOrder calls fn for every key of the JSON object in b, in the order they appear,
+// with its value. Errors decoding the values are kept along with where they are in b.
+func decodeObjectInOrder(b []byte, fn func(key string, value []byte) error) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return withinPart(b, err)
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("expected an object, got %v", tok)
+	}
+	for dec.More() {
+		kt, err := dec.Token()
+		if err != nil {
+			return withinPart(b, err)
+		}
+		key, _ := kt.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return withinPart(b, err)
+		}
+		end := int(dec.InputOffset())
+		if err := fn(key, b[end-len(raw):end]); err != nil {
+			return withinPart(b, atPart(raw, end-len(raw), err))
+		}
+	}
+	_, err = dec.Token()
+	return withinPart(b, err)
 }
 
 // SwaggerComponents represents the components attribute of swagger schemas.
 type SwaggerComponents struct {
-	Schemas map[string]SwaggerSchema `json:"schemas,omitempty"`
+	Schemas       SwaggerSchemas                `json:"schemas,omitempty"`
+	RequestBodies map[string]SwaggerRequestBody `json:"requestBodies,omitempty"`
+	Responses     map[string]SwaggerResponse    `json:"responses,omitempty"`
+	Parameters    map[string]SwaggerParameter   `json:"parameters,omitempty"`
+}
+
+// SwaggerMediaType represents the schema of a body in one of its content types.
+type SwaggerMediaType struct {
+	Schema SwaggerSchema `json:"schema,omitempty"`
+}
+
+// SwaggerParameter represents a parameter of an operation, the ones with content hold it
+// serialized in that content type.
+type SwaggerParameter struct {
+	Ref      string                      `json:"$ref,omitempty"`
+	Name     string                      `json:"name"`
+	In       string                      `json:"in"`
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]SwaggerMediaType `json:"content,omitempty"`
+	// Schema is the schema of the value, Swagger 2.0 declares the one of the rest of the
+	// parameters in place, with Type, Format and Items.
+	Schema *SwaggerProperty `json:"schema,omitempty"`
+	Type   SwaggerType      `json:"type,omitempty"`
+	Format string           `json:"format,omitempty"`
+	Items  SwaggerItems     `json:"items,omitempty"`
+	// the validation keywords of Swagger 2.0 parameters are declared in place too.
+	Enum             []interface{} `json:"enum,omitempty"`
+	Minimum          *float64      `json:"minimum,omitempty"`
+	Maximum          *float64      `json:"maximum,omitempty"`
+	ExclusiveMinimum bool          `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool          `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int64        `json:"minLength,omitempty"`
+	MaxLength        *int64        `json:"maxLength,omitempty"`
+	MinItems         *int64        `json:"minItems,omitempty"`
+	MaxItems         *int64        `json:"maxItems,omitempty"`
+	Pattern          string        `json:"pattern,omitempty"`
+	// Style and Explode say how the values of arrays and objects are written, Swagger 2.0 says
+	// it of arrays alone with CollectionFormat.
+	Style            string `json:"style,omitempty"`
+	Explode          *bool  `json:"explode,omitempty"`
+	CollectionFormat string `json:"collectionFormat,omitempty"`
+	// Example is the one of the value, Swagger 2.0 declares its Default in place too.
+	Example json.RawMessage `json:"example,omitempty"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+// SwaggerRequestBody represents the body of an operation by content type.
+type SwaggerRequestBody struct {
+	Ref     string                      `json:"$ref,omitempty"`
+	Content map[string]SwaggerMediaType `json:"content,omitempty"`
+}
+
+// SwaggerResponse represents a response of an operation by content type, Swagger 2.0 declares
+// only the schema of its body.
+type SwaggerResponse struct {
+	Ref         string                      `json:"$ref,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Content     map[string]SwaggerMediaType `json:"content,omitempty"`
+	Schema      *SwaggerProperty            `json:"schema,omitempty"`
+}
+
+// SwaggerOperation represents the parts of an operation LAC uses.
+type SwaggerOperation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []SwaggerParameter  `json:"parameters,omitempty"`
+	RequestBody *SwaggerRequestBody `json:"requestBody,omitempty"`
+	// Responses are keyed by status code or default.
+	Responses map[string]SwaggerResponse `json:"responses,omitempty"`
+}
+
+// SwaggerPathItem represents the operations of a path.
+type SwaggerPathItem struct {
+	Get     *SwaggerOperation `json:"get,omitempty"`
+	Put     *SwaggerOperation `json:"put,omitempty"`
+	Post    *SwaggerOperation `json:"post,omitempty"`
+	Delete  *SwaggerOperation `json:"delete,omitempty"`
+	Options *SwaggerOperation `json:"options,omitempty"`
+	Head    *SwaggerOperation `json:"head,omitempty"`
+	Patch   *SwaggerOperation `json:"patch,omitempty"`
+	Trace   *SwaggerOperation `json:"trace,omitempty"`
+
+	// Parameters are shared by all the operations of the path.
+	Parameters []SwaggerParameter `json:"parameters,omitempty"`
+}
+
+// Operations returns the operations declared for the path.
+func (pi SwaggerPathItem) Operations() []*SwaggerOperation {
+	var ops []*SwaggerOperation
+	for _, mo := range pi.MethodOperations() {
+		ops = append(ops, mo.Operation)
+	}
+	return ops
+}
+
+// MethodOperation is an operation along with the HTTP method it is declared for.
+type MethodOperation struct {
+	Method    string
+	Operation *SwaggerOperation
+}
+
+// MethodOperations returns the operations declared for the path with their methods.
+func (pi SwaggerPathItem) MethodOperations() []MethodOperation {
+	var ops []MethodOperation
+	for _, mo := range []MethodOperation{
+		{"GET", pi.Get}, {"PUT", pi.Put}, {"POST", pi.Post}, {"DELETE", pi.Delete},
+		{"OPTIONS", pi.Options}, {"HEAD", pi.Head}, {"PATCH", pi.Patch}, {"TRACE", pi.Trace},
+	} {
+		if mo.Operation != nil {
+			ops = append(ops, mo)
+		}
+	}
+	return ops
+}
+
+// SwaggerServer represents a server of an OpenAPI 3 spec, its URL may hold variables.
+type SwaggerServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables,omitempty"`
 }
 
 // SwaggerSimplification represents a subset of Swagger schemas
 type SwaggerSimplification struct {
-	Components SwaggerComponents `json:"components,omitempty"`
+	Components SwaggerComponents          `json:"components,omitempty"`
+	Paths      map[string]SwaggerPathItem `json:"paths,omitempty"`
+
+	// Swagger is the version of Swagger 2.0 specs, OpenAPI the one of OpenAPI 3 ones.
+	Swagger string `json:"swagger,omitempty"`
+	OpenAPI string `json:"openapi,omitempty"`
+	// Definitions holds the schemas of Swagger 2.0 specs.
+	Definitions SwaggerSchemas `json:"definitions,omitempty"`
+
+	// Servers of OpenAPI 3 specs and Schemes, Host and BasePath of Swagger 2.0 ones say where
+	// the API is, see --client.
+	Servers  []SwaggerServer `json:"servers,omitempty"`
+	Schemes  []string        `json:"schemes,omitempty"`
+	Host     string          `json:"host,omitempty"`
+	BasePath string          `json:"basePath,omitempty"`
+	// Parameters and Responses are the ones Swagger 2.0 specs share among operations.
+	Parameters map[string]SwaggerParameter `json:"parameters,omitempty"`
+	Responses  map[string]SwaggerResponse  `json:"responses,omitempty"`
+}
+
+// Schemas returns the schemas of the spec, from definitions for Swagger 2.0 and from components
+// for OpenAPI 3. Specs that do not say their version are told apart by where they have schemas.
+func (s *SwaggerSimplification) Schemas() SwaggerSchemas {
+	switch {
+	case strings.HasPrefix(s.Swagger, "2"):
+		return s.Definitions
+	case s.OpenAPI != "":
+		return s.Components.Schemas
+	case len(s.Components.Schemas.Names) == 0:
+		return s.Definitions
+	}
+	return s.Components.Schemas
 }
 
 func typeFromRef(ref string) string {
@@ -94,6 +456,22 @@ func typeFromRef(ref string) string {
 	return ref[i+1:]
 }
 
+// numberType returns the Go type of integers and numbers, the int32 and float formats are held
+// in the narrower types.
+func numberType(t SwaggerType, format string) reflect.Type {
+	switch {
+	case t == STInteger && format == "int32":
+		return reflect.TypeOf(int32(1))
+	case t == STInteger:
+		return reflect.TypeOf(int64(1))
+	case format == "float":
+		return reflect.TypeOf(float32(1.1))
+	}
+	return reflect.TypeOf(float64(1.1))
+}
+
+// processMultiple returns the field embedding the variants of an allOf, oneOf or anyOf, in the
+// order the schema lists them.
 func processMultiple(multi []OnlyRef, description string) maybeType {
 	result := maybeType{
 		description: description,
@@ -102,10 +480,24 @@ func processMultiple(multi []OnlyRef, description string) maybeType {
 	for _, m := range multi {
 		result.multiType = append(result.multiType, typeFromRef(m.Ref))
 	}
+	result.multiType = uniqueVariants(result.multiType)
 	return result
 }
 
-func resolveSwaggerType(prop SwaggerProperty) maybeType {
+// resolveSwaggerType returns what is known of the type of prop, x-go-type says what Go type it is
+// regardless of the rest.
+func resolveSwaggerType(c *config, prop SwaggerProperty) maybeType {
+	mt := inferSwaggerType(c, prop)
+	mt.goType, mt.goImport = goTypeOf(prop.MetaSwaggerProperty)
+	if mt.goType == "" && prop.Type == STArray && prop.Items.GoType != "" {
+		mt.goType, mt.goImport = goTypeOf(prop.Items.MetaSwaggerProperty)
+		mt.goType = "[]" + mt.goType
+	}
+	return mt
+}
+
+// inferSwaggerType returns the type LAC makes of prop.
+func inferSwaggerType(c *config, prop SwaggerProperty) maybeType {
 	switch prop.Type {
 	case STArray:
 		if prop.Items.Ref != "" {
@@ -126,7 +518,7 @@ func resolveSwaggerType(prop SwaggerProperty) maybeType {
 			fieldType = processMultiple(prop.Items.AnyOf, prop.Description)
 		}
 		if prop.Items.Type != "" {
-			fieldType = resolveSwaggerType(SwaggerProperty{
+			fieldType = resolveSwaggerType(c, SwaggerProperty{
 				MetaSwaggerProperty: prop.Items.MetaSwaggerProperty,
 			})
 		}
@@ -137,42 +529,43 @@ func resolveSwaggerType(prop SwaggerProperty) maybeType {
 			description: prop.Description,
 			typeOf:      reflect.TypeOf(bool(true)),
 		}
-	case STInteger:
+	case STInteger, STNumber:
 		return maybeType{
 			description: prop.Description,
-			typeOf:      reflect.TypeOf(int64(1)),
-		}
-	case STNumber:
-		return maybeType{
-			description: prop.Description,
-			typeOf:      reflect.TypeOf(float64(1.1)),
+			typeOf:      numberType(prop.Type, prop.Format),
 		}
 	case STString:
+		if isBytesFormat(prop.Format) {
+			// encoding/json reads and writes []byte as standard base64.
+			return maybeType{
+				description: prop.Description,
+				typeOf:      bytesType,
+				base64:      true,
+			}
+		}
 		return maybeType{
 			description: prop.Description,
 			typeOf:      reflect.TypeOf(""),
 		}
 	case STObject:
 		if len(prop.AllOf) > 0 {
-			fmt.Println("processing all of")
+			c.debugf("processing all of\n")
 			return processMultiple(prop.AllOf, prop.Description)
 		}
 		if len(prop.OneOf) > 0 {
-			fmt.Println("processing one of")
+			c.debugf("processing one of\n")
 			return processMultiple(prop.OneOf, prop.Description)
 		}
 		if len(prop.AnyOf) > 0 {
-			fmt.Println("processing any of")
+			c.debugf("processing any of\n")
 			return processMultiple(prop.AnyOf, prop.Description)
 		}
-		if prop.AdditionalProperties != nil {
-			aps := resolveSwaggerType(*prop.AdditionalProperties)
-			if aps.nameOftype != "" {
-				aps.nameOftype = "map[string]" + aps.nameOftype
-			} else if aps.typeOf == nil {
-				aps.nameOftype = "map[string]interface{}"
+		if isMapSchema(prop) {
+			vt, _ := mapValues(c, "", prop)
+			return maybeType{
+				description: prop.Description,
+				nameOftype:  "map[string]" + vt,
 			}
-			return aps
 		}
 		if prop.Ref != "" {
 			return maybeType{
@@ -186,15 +579,15 @@ func resolveSwaggerType(prop SwaggerProperty) maybeType {
 	default:
 		// No type can happen for multi items
 		if len(prop.AllOf) > 0 {
-			fmt.Println("processing all of")
+			c.debugf("processing all of\n")
 			return processMultiple(prop.AllOf, prop.Description)
 		}
 		if len(prop.OneOf) > 0 {
-			fmt.Println("processing one of")
+			c.debugf("processing one of\n")
 			return processMultiple(prop.OneOf, prop.Description)
 		}
 		if len(prop.AnyOf) > 0 {
-			fmt.Println("processing any of")
+			c.debugf("processing any of\n")
 			return processMultiple(prop.AnyOf, prop.Description)
 		}
 		if prop.Ref != "" {
@@ -207,61 +600,317 @@ func resolveSwaggerType(prop SwaggerProperty) maybeType {
 	return maybeType{description: prop.Description}
 }
 
-func processProperty(ps map[string]SwaggerProperty) map[string]maybeType {
-	t := map[string]maybeType{}
-	for fieldName, prop := range ps {
-		fmt.Printf("processing field %s\n", fieldName)
-		t[fieldName] = resolveSwaggerType(prop)
-		fmt.Printf("resulting in: %#v\n", t[fieldName])
+// isMapSchema returns true if prop is an object with no properties of its own whose values are
+// described by additionalProperties or patternProperties, which makes a map of them.
+func isMapSchema(prop SwaggerProperty) bool {
+	if (prop.AdditionalProperties == nil || prop.AdditionalProperties.False) && len(prop.PatternProperties.Names) == 0 {
+		return false
+	}
+	if prop.Type != STObject && prop.Type != "" || prop.Ref != "" || len(prop.Properties.Names) > 0 {
+		return false
 	}
-	return t
+	return len(prop.AllOf)+len(prop.AnyOf)+len(prop.OneOf) == 0
 }
 
-func schemaIntoMap(c *config) (map[string]map[string]maybeType, map[string]string, error) {
+// mapValues returns the Go type of the values of the map prop describes, the one of its
+// patternProperties and additionalProperties when they all agree and interface{} otherwise.
+// Objects with properties only become a struct called name when nothing else describes values.
+func mapValues(c *config, name string, prop SwaggerProperty) (string, []*Type) {
+	schemas := make([]SwaggerProperty, 0, len(prop.PatternProperties.Names)+1)
+	for _, pattern := range prop.PatternProperties.Names {
+		schemas = append(schemas, prop.PatternProperties.ByName[pattern])
+	}
+	if prop.AdditionalProperties != nil && !prop.AdditionalProperties.False {
+		schemas = append(schemas, *prop.AdditionalProperties)
+	}
+	if len(schemas) > 1 {
+		name = ""
+	}
+	var (
+		vt    string
+		extra []*Type
+	)
+	for i, s := range schemas {
+		t, types := mapValueType(c, name, s)
+		if i > 0 && t != vt {
+			return "interface{}", nil
+		}
+		vt = t
+		extra = append(extra, types...)
+	}
+	return vt, extra
+}
 
-	result := map[string]map[string]maybeType{}
-	extraComments := map[string]string{}
+// mapValueType returns the Go type of the values of a map described by the additionalProperties
+// ap, objects with properties become a struct called name along with the types it needs. Without
+// a name they are left as interface{}.
+func mapValueType(c *config, name string, ap SwaggerProperty) (string, []*Type) {
+	switch {
+	case ap.Ref != "":
+		return capitalize(typeFromRef(ap.Ref)), nil
+	case isMapSchema(ap):
+		vt, extra := mapValues(c, name, ap)
+		return "map[string]" + vt, extra
+	case len(ap.Properties.Names) > 0:
+		if name == "" || len(ap.AllOf)+len(ap.AnyOf)+len(ap.OneOf) > 0 {
+			return "interface{}", nil
+		}
+		fields, extra := processProperty(c, name, ap.Properties, ap.Required)
+		value := &Type{
+			Name:        name,
+			Source:      c.swaggerFile,
+			Description: ap.Description,
+			Fields:      fields,
+		}
+		return capitalize(name), append([]*Type{value}, extra...)
+	}
+	vt := resolveSwaggerType(c, ap)
+	if len(vt.multiType) > 0 {
+		return "interface{}", nil
+	}
+	_, tn := vt.Resolve()
+	return tn, nil
+}
+
+// processProperty returns the fields of the owner component along with any type that had to be
+// made up for them, such as inline enums.
+func processProperty(c *config, owner string, ps SwaggerProperties, required SwaggerRequired) ([]Field, []*Type) {
+	t := make([]Field, 0, len(ps.Names))
+	var extra []*Type
+	for _, fieldName := range ps.Names {
+		c.debugf("processing field %s\n", fieldName)
+		prop := ps.ByName[fieldName]
+		isRequired := required.has(fieldName) || prop.Required.Self
+		if items := tupleItems(prop.PrefixItems, prop.Items); prop.Type == STArray && prop.GoType == "" && len(items) > 0 {
+			tuple := tupleTypes(c, owner+"."+fieldName, prop.Description, items)
+			f := Field{Name: fieldName, goName: prop.GoName, Type: maybeType{description: prop.Description, nameOftype: tuple[0].Name}}
+			f.Type.nullable = prop.Nullable
+			f.Type.optional = !isRequired
+			t = append(t, f)
+			extra = append(extra, tuple...)
+			continue
+		}
+		f := Field{Name: fieldName, goName: prop.GoName, Type: resolveSwaggerType(c, prop)}
+		if isMapSchema(prop) && f.Type.goType == "" {
+			vt, values := mapValues(c, owner+"."+fieldName, prop)
+			f.Type = maybeType{description: prop.Description, nameOftype: "map[string]" + vt}
+			f.Type.keyPatterns = prop.PatternProperties.Names
+			extra = append(extra, values...)
+		}
+		if prop.Type == STString && (prop.ContentEncoding != "" || prop.ContentMediaType != "") {
+			applyContent(c, owner+"."+fieldName, prop, &f.Type)
+		}
+		f.Type.nullable = prop.Nullable
+		f.Type.optional = !isRequired
+		f.Type.format = prop.Format
+		f.Type.decimal = isSchemaDecimal(fieldName, prop.Type, prop.Format)
+		f.Type.duration = isSchemaDuration(prop.Type, prop.Format)
+		f.Type.netip = schemaNetIP(prop.Type, prop.Format)
+		f.Type.time = schemaTime(prop.Type, prop.Format)
+		if prop.Type == STArray {
+			f.Type.format = prop.Items.Format
+			f.Type.decimal = isSchemaDecimal(fieldName, prop.Items.Type, prop.Items.Format)
+			f.Type.duration = isSchemaDuration(prop.Items.Type, prop.Items.Format)
+			f.Type.netip = schemaNetIP(prop.Items.Type, prop.Items.Format)
+			f.Type.time = schemaTime(prop.Items.Type, prop.Items.Format)
+		}
+		if f.Type.goType != "" {
+			// x-go-type already says what it is, nothing is made of the format.
+			f.Type.decimal, f.Type.duration, f.Type.base64 = false, false, false
+			f.Type.netip, f.Type.time = "", ""
+		}
+		f.Type.xmlTag = xmlTag(fieldName, prop)
+		f.Type.readOnly = prop.ReadOnly
+		f.Type.defaultValue = prop.Default
+		f.Type.constraints = propertyConstraints(prop)
+		if isRequired {
+			f.Type.constraints = withRequired(f.Type.constraints)
+		}
+		if prop.Not != nil {
+			f.Type.constraints = withNot(f.Type.constraints, notConstraints(c, owner+"."+fieldName, prop))
+		}
+		if prop.PropertyNames != nil {
+			f.Type.constraints = withKeys(f.Type.constraints, keyConstraints(c, owner+"."+fieldName, prop))
+		}
+		if patterns := prop.PatternProperties.Names; len(patterns) > 0 && prop.AdditionalProperties != nil && prop.AdditionalProperties.False {
+			f.Type.constraints = withKeys(f.Type.constraints, patternKeys(c, owner+"."+fieldName, f.Type.constraints, patterns))
+		}
+		if et := inlineEnum(c, owner, fieldName, prop); et != nil && f.Type.goType == "" {
+			f.Type.typeOf = nil
+			f.Type.nameOftype = et.Name
+			f.Type.enum = true
+			// the values are now checked by the type itself.
+			f.Type.constraints = withoutEnum(f.Type.constraints)
+			extra = append(extra, et)
+		}
+		c.debugf("resulting in: %#v\n", f.Type)
+		t = append(t, f)
+	}
+	return t, extra
+}
+
+// inlineEnum returns an enum type for properties declaring their valid values inline, or for
+// arrays of them, when enums are requested.
+func inlineEnum(c *config, owner, fieldName string, prop SwaggerProperty) *Type {
+	if !c.enums {
+		return nil
+	}
+	values := prop.Enum
+	if prop.Type == STArray {
+		values = prop.Items.Enum
+	}
+	enum, ok := stringEnum(values)
+	if !ok {
+		return nil
+	}
+	return &Type{
+		Name:        owner + "." + fieldName,
+		Source:      c.swaggerFile,
+		Description: prop.Description,
+		Enum:        enum,
+	}
+}
+
+func schemaIntoTypes(ctx context.Context, c *config) ([]*Type, error) {
+	result := []*Type{}
 
 	var tgt SwaggerSimplification
-	fp, err := os.Open(c.swaggerFile)
+	if err := decodeSpec(ctx, c, &tgt); err != nil {
+		return nil, fmt.Errorf("decoding file contents: %w", err)
+	}
+	// components are independent from each other, refs are only names at this point, so they
+	// can be resolved concurrently and then merged in declaration order.
+	schemas := tgt.Schemas()
+	if err := checkComponents(c, len(schemas.Names)); err != nil {
+		return nil, err
+	}
+	if len(schemas.Names) == 0 {
+		c.warn(c.swaggerFile, "no schemas found in components.schemas or definitions")
+	}
+	// with --root the references are only known once processed, so filtering waits until then.
+	if len(c.only) > 0 && len(c.roots) == 0 {
+		schemas = onlySchemas(c, schemas)
+	}
+	processed := make([][]*Type, len(schemas.Names))
+	err := parallel(ctx, c.jobs, len(schemas.Names), func(i int) {
+		compName := schemas.Names[i]
+		processed[i] = processComponent(c, compName, schemas.ByName[compName])
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("opening json file: %w", err)
-	}
-	if err := json.NewDecoder(fp).Decode(&tgt); err != nil {
-		return nil, nil, fmt.Errorf("decoding file contents: %w", err)
-	}
-	for compName, component := range tgt.Components.Schemas {
-		newType := map[string]maybeType{}
-		extraComments[compName] = component.Description
-		switch component.Type {
-		case STObject:
-			fmt.Printf("processing %s\n", compName)
-			if len(component.AllOf) > 0 {
-				fmt.Println("processing all of")
-				result[compName] = map[string]maybeType{
-					"": processMultiple(component.AllOf, component.Description),
-				}
-				continue
-			}
-			if len(component.OneOf) > 0 {
-				fmt.Println("processing one of")
-				result[compName] = map[string]maybeType{
-					"": processMultiple(component.OneOf, component.Description),
-				}
-				continue
-			}
-			if len(component.AnyOf) > 0 {
-				fmt.Println("processing any of")
-				result[compName] = map[string]maybeType{
-					"": processMultiple(component.AnyOf, component.Description),
-				}
-				continue
+		return nil, fmt.Errorf("processing components: %w", err)
+	}
+	for _, ts := range processed {
+		result = append(result, ts...)
+	}
+	// only the fixtures use the examples.
+	if c.fixtures {
+		if err := markExamples(ctx, result, schemas); err != nil {
+			return nil, err
+		}
+	}
+	names := goNames(schemas)
+	renameTypes(result, names)
+	result = splitReadOnly(c, result)
+	components := len(result)
+	result = append(result, paramTypes(c, tgt.Paths, result)...)
+	result = append(result, bodyTypes(c, tgt.Components, tgt.Paths, result)...)
+	// parameters and bodies reference the components by the names in the spec.
+	renameTypes(result[components:], names)
+	markEnumRefs(result)
+	markForms(c, tgt.Paths, result)
+	markXML(c, tgt.Paths, result)
+	return canonicalOrder(result), nil
+}
+
+// markEnumRefs flags the fields referencing enum components, which is only known once all the
+// components were processed.
+func markEnumRefs(types []*Type) {
+	enums := map[string]bool{}
+	for _, t := range types {
+		if len(t.Enum) > 0 {
+			enums[t.Name] = true
+		}
+	}
+	if len(enums) == 0 {
+		return
+	}
+	for _, t := range types {
+		for i := range t.Fields {
+			if f := &t.Fields[i].Type; enums[f.nameOftype] {
+				f.enum = true
 			}
-			newType = processProperty(component.Properties)
-			result[compName] = newType
-		default:
-			fmt.Printf("%s is just a %s", compName, component.Type)
 		}
 	}
-	return result, extraComments, nil
+}
+
+// onlySchemas returns the schemas asked for with --only, in their declaration order.
+func onlySchemas(c *config, schemas SwaggerSchemas) SwaggerSchemas {
+	result := SwaggerSchemas{ByName: map[string]SwaggerSchema{}}
+	for _, n := range schemas.Names {
+		if c.wantsType(n) {
+			result.Names = append(result.Names, n)
+			result.ByName[n] = schemas.ByName[n]
+		}
+	}
+	return result
+}
+
+// processComponent turns a component schema into a Type plus any other types made up for its
+// fields, it returns nil for components that can not be represented in Go.
+func processComponent(c *config, compName string, component SwaggerSchema) []*Type {
+	newType := &Type{
+		Name:        compName,
+		Source:      c.swaggerFile,
+		Description: component.Description,
+	}
+	if component.GoType != "" {
+		c.warn(compName, "x-go-type is only honored for properties, the component is generated")
+	}
+	if len(component.PatternProperties.Names) > 0 {
+		c.warn(compName, "patternProperties only become maps for properties, the ones of components are left out")
+	}
+	// unions and compositions are often declared without a type, they are objects all the same.
+	if component.Type == "" && len(component.AllOf)+len(component.OneOf)+len(component.AnyOf) > 0 {
+		component.Type = STObject
+	}
+	switch component.Type {
+	case STObject:
+		c.debugf("processing %s\n", compName)
+		if len(component.AllOf) > 0 {
+			c.debugf("processing all of\n")
+			// the properties next to the allOf extend the schemas it references.
+			fields, extra := processProperty(c, compName, component.Properties, component.Required)
+			newType.Fields = append([]Field{{Type: processMultiple(component.AllOf, component.Description)}}, fields...)
+			return append([]*Type{newType}, extra...)
+		}
+		if len(component.OneOf) > 0 {
+			c.debugf("processing one of\n")
+			newType.Fields = []Field{{Type: processMultiple(component.OneOf, component.Description)}}
+			newType.union = discriminatedUnion(component.OneOf, component.Discriminator)
+			return []*Type{newType}
+		}
+		if len(component.AnyOf) > 0 {
+			c.debugf("processing any of\n")
+			newType.Fields = []Field{{Type: processMultiple(component.AnyOf, component.Description)}}
+			return []*Type{newType}
+		}
+		var extra []*Type
+		newType.Fields, extra = processProperty(c, compName, component.Properties, component.Required)
+		return append([]*Type{newType}, extra...)
+	case STString:
+		if enum, ok := stringEnum(component.Enum); ok && c.enums {
+			newType.Enum = enum
+			newType.enumNames = component.enumNames
+			return []*Type{newType}
+		}
+		c.warn(compName, "skipping component, %q is not an object so no struct can be made of it", component.Type)
+	case STArray:
+		if items := tupleItems(component.PrefixItems, component.Items); len(items) > 0 {
+			return tupleTypes(c, compName, component.Description, items)
+		}
+		c.warn(compName, "skipping component, %q is not an object so no struct can be made of it", component.Type)
+	default:
+		c.warn(compName, "skipping component, %q is not an object so no struct can be made of it", component.Type)
+	}
+	return nil
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldTag returns the struct tag of a field of owner, fn is the name of the field in the source
+// which is used as key for json and any extra tag requested, such as mapstructure or koanf. The
+// types the client sends as XML get xml tags even if they were not requested.
+func fieldTag(c *config, owner *Type, fn, tn string, f *maybeType) string {
+	tags := make([]string, 0, len(c.extraTags)+3)
+	if c.optional != optionalPlain && f.optional {
+		tags = append(tags, fmt.Sprintf(`json:"%s,omitempty"`, fn))
+	} else {
+		tags = append(tags, fmt.Sprintf(`json:"%s"`, fn))
+	}
+	extraTags := c.extraTags
+	if owner.xml && !hasTag(extraTags, "xml") {
+		extraTags = append(append([]string(nil), extraTags...), "xml")
+	}
+	for _, t := range extraTags {
+		if t == "xml" && f.xmlTag != "" {
+			tags = append(tags, fmt.Sprintf(`xml:"%s"`, f.xmlTag))
+			continue
+		}
+		tags = append(tags, fmt.Sprintf(`%s:"%s"`, t, fn))
+	}
+	if c.envTags {
+		if t := envTag(fn, f); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	if c.dbMode != "" {
+		tags = append(tags, fmt.Sprintf(`db:"%s"`, snakeName(fn)))
+	}
+	if c.validatorTags {
+		if t := validateTag(c, fn, tn, f); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return "`" + strings.Join(tags, " ") + "`"
+}
+
+// hasTag returns true if tags holds tag.
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlTag returns the xml tag for a property with an xml object, renamed, as an attribute or, for
+// arrays, wrapped in an element. It is empty when the source says nothing about XML.
+func xmlTag(fn string, prop SwaggerProperty) string {
+	if prop.XML == nil && (prop.Type != STArray || prop.Items.XML == nil) {
+		return ""
+	}
+	name := fn
+	if prop.XML != nil && prop.XML.Name != "" {
+		name = prop.XML.Name
+	}
+	if prop.Type == STArray {
+		item := name
+		if prop.Items.XML != nil && prop.Items.XML.Name != "" {
+			item = prop.Items.XML.Name
+		}
+		if prop.XML != nil && prop.XML.Wrapped {
+			return name + ">" + item
+		}
+		return item
+	}
+	if prop.XML.Attribute {
+		return name + ",attr"
+	}
+	return name
+}
+
+// envTag returns a caarlos0/env style tag, nested structs get an envPrefix so their fields end up
+// named after the full path, ie DATABASE_HOST for {"database": {"host": ""}}. Since types are
+// shared between paths this is the only way to derive names from the path. Arrays of structs can
+// not be loaded from the environment so they get none.
+func envTag(fn string, f *maybeType) string {
+	name := envName(fn)
+	switch {
+	case f.IsMultiple():
+		return fmt.Sprintf(`envPrefix:"%s_"`, name)
+	case f.typeOf != nil:
+		return fmt.Sprintf(`env:"%s"`, name)
+	case f.nameOftype == "" || f.nameOftype == "interface{}" || strings.HasPrefix(f.nameOftype, "map["):
+		return fmt.Sprintf(`env:"%s"`, name)
+	case f.isArray:
+		return ""
+	}
+	return fmt.Sprintf(`envPrefix:"%s_"`, name)
+}
+
+// envName turns a field name into an environment variable name, ie fieldName into FIELD_NAME.
+func envName(fn string) string {
+	return strings.ToUpper(snakeName(fn))
+}
+
+// snakeName turns a field name into snake_case, ie fieldName into field_name.
+func snakeName(fn string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '.', ' ', '\\':
+			return '_'
+		}
+		return r
+	}, normalizeNames(fn, ""))
+}
+
+// dbNullTypes maps Go types to the types able to hold NULL for each --db mode.
+var dbNullTypes = map[string]map[string]string{
+	// the ones of database/sql are wrapped to be read from JSON, see sqlNulls.
+	"sql": {
+		"string":    "NullString",
+		"int64":     "NullInt64",
+		"int32":     "NullInt32",
+		"int16":     "NullInt16",
+		"float64":   "NullFloat64",
+		"bool":      "NullBool",
+		"time.Time": "NullTime",
+	},
+	"pgx": {
+		"string":    "pgtype.Text",
+		"int64":     "pgtype.Int8",
+		"int32":     "pgtype.Int4",
+		"int16":     "pgtype.Int2",
+		"float64":   "pgtype.Float8",
+		"float32":   "pgtype.Float4",
+		"bool":      "pgtype.Bool",
+		"time.Time": "pgtype.Timestamptz",
+	},
+}
+
+// dbNullPackages holds the import needed by the null types of each --db mode.
+var dbNullPackages = map[string]string{
+	"sql": "database/sql",
+	"pgx": "github.com/jackc/pgx/v5/pgtype",
+}
+
+// dbNullType returns the nullable version of tn for the given --db mode and its package.
+func dbNullType(mode, tn string) (string, string, bool) {
+	nt, ok := dbNullTypes[mode][tn]
+	if !ok {
+		return "", "", false
+	}
+	return nt, dbNullPackages[mode], true
+}
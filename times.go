@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Special values of --timeformat.
+const (
+	// timeFormatRFC3339 turns date-time strings into time.Time, which reads and writes RFC 3339.
+	timeFormatRFC3339 = "rfc3339"
+	// timeFormatNone keeps date and date-time strings as strings.
+	timeFormatNone = "none"
+)
+
+// Kinds of time held by strings, from their format.
+const (
+	timeDateTime = "date-time"
+	timeDate     = "date"
+)
+
+// namedTimeLayouts are the layouts of the time package --timeformat accepts by name.
+var namedTimeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339Nano": time.RFC3339Nano,
+}
+
+// timeLayout returns the Go layout of --timeformat, either the name of one of the time package or
+// a layout itself.
+func timeLayout(format string) (string, error) {
+	if layout, ok := namedTimeLayouts[format]; ok {
+		return layout, nil
+	}
+	// layouts are written as the reference time, so formatting one changes it.
+	if time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(format) == format {
+		names := make([]string, 0, len(namedTimeLayouts))
+		for n := range namedTimeLayouts {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("--timeformat %q is neither %s, %s, a layout of the time package (%s) nor a layout", format, timeFormatRFC3339, timeFormatNone, strings.Join(names, ", "))
+	}
+	return format, nil
+}
+
+// Names of the types written by timeHelper.
+const (
+	dateType      = "Date"
+	timestampType = "Timestamp"
+)
+
+// dateLayout is the layout of format date, full-date in RFC 3339.
+const dateLayout = "2006-01-02"
+
+// timeHelper returns a type named name holding a time.Time that is read from and written as a
+// string with layout, empty strings and null are the zero time.
+func timeHelper(name, layout string) string {
+	return fmt.Sprintf(`// %[1]s is a time.Time read from and written as a string with the layout %[2]s.
+type %[1]s struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t %[1]s) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Format(%[3]q))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *%[1]s) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	v, err := time.Parse(%[3]q, s)
+	if err != nil {
+		return err
+	}
+	t.Time = v
+	return nil
+}
+
+var (
+	_ json.Marshaler   = %[1]s{}
+	_ json.Unmarshaler = (*%[1]s)(nil)
+)
+
+`, name, layout, layout)
+}
+
+// schemaTime returns the kind of time held by strings with format, if any.
+func schemaTime(t SwaggerType, format string) string {
+	if t != STString {
+		return ""
+	}
+	switch format {
+	case timeDateTime, timeDate:
+		return format
+	}
+	return ""
+}
+
+// timeFor returns the type and import of a field holding a time, date-time is a time.Time unless
+// --timeformat asks for another layout.
+func timeFor(c *config, f *maybeType) (string, string, bool) {
+	var tn, pkg string
+	switch {
+	case c.timeFormat == timeFormatNone || f.time == "":
+		return "", "", false
+	case f.time == timeDate:
+		tn = dateType
+	case c.timeFormat == timeFormatRFC3339:
+		tn, pkg = "time.Time", "time"
+	default:
+		tn = timestampType
+	}
+	if f.isArray {
+		tn = "[]" + tn
+	}
+	return tn, pkg, true
+}
+
+// timeHelpersFor returns the names of the helper types needed by the fields of types.
+func timeHelpersFor(c *config, types []*Type) map[string]bool {
+	helpers := map[string]bool{}
+	if c.timeFormat == timeFormatNone {
+		return helpers
+	}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			if fld.Name == "" {
+				continue
+			}
+			switch {
+			case fld.Type.time == timeDate:
+				helpers[dateType] = true
+			case fld.Type.time == timeDateTime && c.timeFormat != timeFormatRFC3339:
+				helpers[timestampType] = true
+			}
+		}
+	}
+	return helpers
+}
+
+// markTimes keeps as strings the times that need a helper type whose name is taken.
+func markTimes(c *config, types []*Type) {
+	helpers := timeHelpersFor(c, types)
+	for _, t := range types {
+		name := capitalize(t.Name)
+		if !helpers[name] {
+			continue
+		}
+		c.warn(t.Name, "the type is named %s, the fields needing it are kept as strings", name)
+		kind := timeDate
+		if name == timestampType {
+			kind = timeDateTime
+		}
+		for _, t := range types {
+			for i := range t.Fields {
+				if t.Fields[i].Type.time == kind {
+					t.Fields[i].Type.time = ""
+				}
+			}
+		}
+	}
+}
+
+// writeTimeHelpers writes the helper types needed by the fields of types.
+func writeTimeHelpers(w io.Writer, c *config, types []*Type) {
+	helpers := timeHelpersFor(c, types)
+	if helpers[dateType] {
+		io.WriteString(w, timeHelper(dateType, dateLayout))
+	}
+	if helpers[timestampType] {
+		io.WriteString(w, timeHelper(timestampType, c.timeLayout))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tupleItems returns the schemas of the positions of a tuple, from prefixItems or the legacy
+// array form of items, or nil if the array is not a tuple.
+func tupleItems(prefixItems []SwaggerProperty, items SwaggerItems) []SwaggerProperty {
+	if len(prefixItems) > 0 {
+		return prefixItems
+	}
+	return items.Tuple
+}
+
+// tupleTypes returns a struct with a field per position of the tuple, named item0, item1 and so
+// on, along with any type made up for those fields.
+func tupleTypes(c *config, name, description string, items []SwaggerProperty) []*Type {
+	positions := SwaggerProperties{
+		Names:  make([]string, 0, len(items)),
+		ByName: make(map[string]SwaggerProperty, len(items)),
+	}
+	for i, item := range items {
+		n := fmt.Sprintf("item%d", i)
+		positions.Names = append(positions.Names, n)
+		positions.ByName[n] = item
+	}
+	c.debugf("processing tuple %s\n", name)
+	// the positions are always written, by the tuple marshalers rather than by their tags.
+	fields, extra := processProperty(c, name, positions, SwaggerRequired{Names: positions.Names})
+	tuple := &Type{
+		Name:        name,
+		Source:      c.swaggerFile,
+		Description: description,
+		Fields:      fields,
+		tuple:       true,
+	}
+	return append([]*Type{tuple}, extra...)
+}
+
+// writeTupleMethods writes the marshalers that read and write a tuple type as the array it is in
+// the source. Missing positions are left as their zero value and positions past the declared ones
+// are dropped.
+func writeTupleMethods(w io.Writer, t *Type, structName string) {
+	values := make([]string, 0, len(t.Fields))
+	for _, fld := range t.Fields {
+		values = append(values, "t."+fld.GoName())
+	}
+	fmt.Fprintf(w, "// MarshalJSON implements json.Marshaler, writing %s as an array.\n", structName)
+	fmt.Fprintf(w, "func (t %s) MarshalJSON() ([]byte, error) {\n", structName)
+	fmt.Fprintf(w, "\treturn json.Marshal([]interface{}{%s})\n}\n\n", strings.Join(values, ", "))
+
+	fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, reading %s from an array.\n", structName)
+	fmt.Fprintf(w, "func (t *%s) UnmarshalJSON(b []byte) error {\n", structName)
+	fmt.Fprint(w, "\tvar items []json.RawMessage\n")
+	fmt.Fprint(w, "\tif err := json.Unmarshal(b, &items); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprintf(w, "\t*t = %s{}\n", structName)
+	for i, v := range values {
+		fmt.Fprintf(w, "\tif len(items) > %d {\n", i)
+		fmt.Fprintf(w, "\t\tif err := json.Unmarshal(items[%d], &%s); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n", i, v)
+	}
+	fmt.Fprint(w, "\treturn nil\n}\n\n")
+}
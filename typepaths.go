@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// markTypePaths sets the type asked with --typesforitems on the fields it points to. Paths are
+// made of Go names separated by dots, the first one is the struct and every one after it a member,
+// with a [] suffix to go through the elements of arrays, ie Order.Items[].Price. Every part can use
+// the wildcards of path.Match, so *.CreatedAt reaches the CreatedAt member of every struct.
+func markTypePaths(c *config, types []*Type) {
+	if len(c.typesForItems) == 0 {
+		return
+	}
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[capitalize(t.Name)] = t
+	}
+	// the more precise paths are applied last so they win over the sweeping ones.
+	paths := make([]string, 0, len(c.typesForItems))
+	for p := range c.typesForItems {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		wi, wj := strings.Count(paths[i], "*"), strings.Count(paths[j], "*")
+		if wi != wj {
+			return wi > wj
+		}
+		return paths[i] < paths[j]
+	})
+	for _, p := range paths {
+		parts := strings.Split(p, ".")
+		if len(parts) < 2 {
+			c.warn(p, "--typesforitems paths need at least a struct and a member")
+			continue
+		}
+		matched := false
+		for _, t := range types {
+			if ok, _ := path.Match(parts[0], capitalize(t.Name)); ok && len(t.Enum) == 0 {
+				matched = markTypePath(t, parts[1:], c.typesForItems[p], byName) || matched
+			}
+		}
+		if !matched {
+			c.debugf("--typesforitems path %s matches no field\n", p)
+		}
+	}
+}
+
+// markTypePath follows parts from the struct t and sets tn on the fields at the end of them, it
+// returns false if there are none. The last part replaces the whole field unless it goes through
+// the elements.
+func markTypePath(t *Type, parts []string, tn string, byName map[string]*Type) bool {
+	part := parts[0]
+	elements := strings.HasSuffix(part, "[]")
+	part = strings.TrimSuffix(part, "[]")
+	matched := false
+	for i := range t.Fields {
+		f := &t.Fields[i].Type
+		if t.Fields[i].Name == "" || f.IsMultiple() || (elements && !f.isArray) {
+			continue
+		}
+		if ok, _ := path.Match(part, t.Fields[i].GoName()); !ok {
+			continue
+		}
+		if len(parts) == 1 {
+			f.pathType = tn
+			if elements {
+				f.pathType = "[]" + tn
+			}
+			matched = true
+			continue
+		}
+		next, ok := byName[capitalize(f.nameOftype)]
+		if !ok || elements != f.isArray || f.typeOf != nil || f.enum || len(next.Enum) > 0 {
+			continue
+		}
+		matched = markTypePath(next, parts[1:], tn, byName) || matched
+	}
+	return matched
+}
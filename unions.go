@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// union is a oneOf told apart by a discriminator, it is generated as a struct holding the variant
+// behind an interface and decoded by switching on the discriminator.
+type union struct {
+	// property is the name of the discriminator property.
+	property string
+	cases    []unionCase
+}
+
+// unionCase is a variant of a union and the values of the discriminator that select it.
+type unionCase struct {
+	typeName string
+	values   []string
+}
+
+// discriminatedUnion returns the union of a oneOf, nil when it has no discriminator. Variants
+// without values in the mapping are selected by their name, as the spec says. The variants are
+// in the order of the oneOf, the ones only found in the mapping follow sorted by name.
+func discriminatedUnion(oneOf []OnlyRef, d *SwaggerDiscriminator) *union {
+	if d == nil || d.PropertyName == "" {
+		return nil
+	}
+	u := &union{property: d.PropertyName}
+	byType := map[string]int{}
+	for _, ref := range oneOf {
+		n := typeFromRef(ref.Ref)
+		if _, ok := byType[n]; !ok {
+			byType[n] = len(u.cases)
+			u.cases = append(u.cases, unionCase{typeName: n})
+		}
+	}
+	var mappedOnly []string
+	for _, value := range sortedKeys(d.Mapping) {
+		n := typeFromRef(d.Mapping[value])
+		if _, ok := byType[n]; !ok {
+			byType[n] = -1
+			mappedOnly = append(mappedOnly, n)
+		}
+	}
+	sort.Strings(mappedOnly)
+	for _, n := range mappedOnly {
+		byType[n] = len(u.cases)
+		u.cases = append(u.cases, unionCase{typeName: n})
+	}
+	for _, value := range sortedKeys(d.Mapping) {
+		i := byType[typeFromRef(d.Mapping[value])]
+		u.cases[i].values = append(u.cases[i].values, value)
+	}
+	for i := range u.cases {
+		if len(u.cases[i].values) == 0 {
+			u.cases[i].values = []string{u.cases[i].typeName}
+		}
+	}
+	return u
+}
+
+// uniqueVariants returns the names of the variants without the ones repeated, each where it is
+// first listed, so the variants are in the order of the schema however many times a ref is
+// repeated or renamed into another.
+func uniqueVariants(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// unionInterface returns the name of the interface implemented by the variants of the union t.
+func unionInterface(t *Type) string {
+	return capitalize(t.Name) + "Variant"
+}
+
+// markUnions falls back to embedding the variants of the unions whose interface name is taken,
+// and drops the variants that are not generated.
+func markUnions(c *config, types []*Type) {
+	names := make(map[string]bool, len(types))
+	for _, t := range types {
+		names[capitalize(t.Name)] = true
+	}
+	for _, t := range types {
+		if t.union == nil {
+			continue
+		}
+		if names[unionInterface(t)] {
+			c.warn(t.Name, "the type %s is taken, the variants of the oneOf are embedded", unionInterface(t))
+			t.union = nil
+			continue
+		}
+		cases := t.union.cases[:0]
+		for _, uc := range t.union.cases {
+			if !names[capitalize(uc.typeName)] {
+				c.warn(t.Name, "the variant %s of the oneOf is not generated, it is left out", uc.typeName)
+				continue
+			}
+			cases = append(cases, uc)
+		}
+		t.union.cases = cases
+	}
+}
+
+// writeUnion writes the struct holding the variant of the union t and the interface the variants
+// implement.
+func writeUnion(w io.Writer, t *Type, structName string) {
+	iface := unionInterface(t)
+	variants := make([]string, 0, len(t.union.cases))
+	for _, uc := range t.union.cases {
+		variants = append(variants, capitalize(uc.typeName))
+	}
+	fmt.Fprintf(w, "type %s struct {\n", structName)
+	fmt.Fprintf(w, "\t// Value holds the variant, one of %s.\n", joinNames(variants))
+	fmt.Fprintf(w, "\tValue %s\n}\n\n", iface)
+	fmt.Fprintf(w, "// %s is implemented by the variants a %s can hold, told apart by %s.\n", iface, structName, t.union.property)
+	fmt.Fprintf(w, "type %s interface {\n\tis%s()\n}\n\n", iface, structName)
+}
+
+// writeUnionMethods writes the marker methods of the variants and the marshalers of the union,
+// which decodes the variant named by the discriminator and encodes the variant as it is.
+func writeUnionMethods(w io.Writer, t *Type, structName string) {
+	for _, uc := range t.union.cases {
+		fmt.Fprintf(w, "func (*%s) is%s() {}\n", capitalize(uc.typeName), structName)
+	}
+	fmt.Fprint(w, "\n")
+
+	fmt.Fprintf(w, "// MarshalJSON implements json.Marshaler, writing the variant held by %s.\n", structName)
+	fmt.Fprintf(w, "func (u %s) MarshalJSON() ([]byte, error) {\n", structName)
+	fmt.Fprint(w, "\treturn json.Marshal(u.Value)\n}\n\n")
+
+	fmt.Fprintf(w, "// UnmarshalJSON implements json.Unmarshaler, reading the variant named by %s.\n", t.union.property)
+	fmt.Fprintf(w, "func (u *%s) UnmarshalJSON(b []byte) error {\n", structName)
+	fmt.Fprintf(w, "\tvar d struct {\n\t\tDiscriminator string `json:%q`\n\t}\n", t.union.property)
+	fmt.Fprint(w, "\tif err := json.Unmarshal(b, &d); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprint(w, "\tswitch d.Discriminator {\n")
+	for _, uc := range t.union.cases {
+		values := make([]string, 0, len(uc.values))
+		for _, v := range uc.values {
+			values = append(values, fmt.Sprintf("%q", v))
+		}
+		fmt.Fprintf(w, "\tcase %s:\n", strings.Join(values, ", "))
+		fmt.Fprintf(w, "\t\tv := new(%s)\n", capitalize(uc.typeName))
+		fmt.Fprint(w, "\t\tif err := json.Unmarshal(b, v); err != nil {\n\t\t\treturn err\n\t\t}\n")
+		fmt.Fprint(w, "\t\tu.Value = v\n")
+	}
+	fmt.Fprint(w, "\tdefault:\n")
+	fmt.Fprintf(w, "\t\treturn fmt.Errorf(\"unknown %s %%q for %s\", d.Discriminator)\n", t.union.property, structName)
+	fmt.Fprint(w, "\t}\n\treturn nil\n}\n\n")
+}
+
+// unionExamplesFile returns the name of the file the examples of the unions of target are written
+// to, next to it.
+func unionExamplesFile(target string) string {
+	return strings.TrimSuffix(target, ".go") + "_example_test.go"
+}
+
+// writeUnionExamples writes, with --union-examples, an Example for each union next to the target
+// decoding a document with the discriminator of its first variant and switching on the variants,
+// as how to get to the variant held is not obvious from the struct alone.
+func writeUnionExamples(c *config, types []*Type) error {
+	var unions []*Type
+	for _, t := range types {
+		if t.union != nil && len(t.union.cases) > 0 {
+			unions = append(unions, t)
+		}
+	}
+	if len(unions) == 0 {
+		c.warn(c.swaggerFile, "no oneOf has a discriminator, no union examples are written")
+		return nil
+	}
+	return writeFile(c, unionExamplesFile(c.targetFile), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		writeHeading(w, c, []string{"encoding/json", "fmt"})
+		for i, t := range unions {
+			if i > 0 {
+				w.WriteString("\n")
+			}
+			writeUnionExample(w, c, t)
+		}
+		return w.Flush()
+	})
+}
+
+// writeUnionExample writes the Example of the union t.
+func writeUnionExample(w *bufio.Writer, c *config, t *Type) {
+	structName := capitalize(t.Name)
+	first := t.union.cases[0]
+	doc, _ := json.Marshal(map[string]string{t.union.property: first.values[0]})
+	literal, _ := exampleLiteral(doc)
+	fmt.Fprintf(w, "// Example%s decodes a %s, which holds the variant named by %s, and switches\n", structName, structName, t.union.property)
+	fmt.Fprint(w, "// on the variants to handle the one it holds.\n")
+	fmt.Fprintf(w, "func Example%s() {\n", structName)
+	fmt.Fprintf(w, "\tvar u %s\n", structName)
+	fmt.Fprintf(w, "\tif err := json.Unmarshal([]byte(%s), &u); err != nil {\n", literal)
+	fmt.Fprint(w, "\t\tfmt.Println(err)\n\t\treturn\n\t}\n")
+	fmt.Fprint(w, "\tswitch v := u.Value.(type) {\n")
+	for _, uc := range t.union.cases {
+		variant := capitalize(uc.typeName)
+		fmt.Fprintf(w, "\tcase *%s:\n", variant)
+		fmt.Fprintf(w, "\t\t// v is the %s held, selected by %s %s.\n", variant, t.union.property, joinNames(quoteAll(uc.values)))
+		w.WriteString("\t\tfmt.Printf(\"%T\\n\", v)\n")
+	}
+	fmt.Fprint(w, "\t}\n")
+	fmt.Fprintf(w, "\t// Output: *%s.%s\n}\n", c.targetPackage, capitalize(first.typeName))
+}
+
+// quoteAll returns values quoted as Go strings.
+func quoteAll(values []string) []string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return quoted
+}
+
+// joinNames returns names as a sentence, ie Cat, Dog or Bird.
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldCheck is a condition on a field enforced by the generated Validate.
+type fieldCheck struct {
+	// key is the name of the field in the source, used in the errors.
+	key string
+	// invalid is a Go expression that is true when the field holds an invalid value.
+	invalid string
+	// value is a Go expression with the value of the field, for the errors.
+	value string
+	// errorf is the format of the error after the key of the field, it takes value.
+	errorf string
+	// keys is set when invalid checks every key of a map, held in k, instead of the field.
+	keys bool
+	// items is set when invalid checks every item of an array, held in e, instead of the field.
+	items bool
+	// nested is set when the Validate method of the value, or of its items, is called, invalid is
+	// then the condition for calling it.
+	nested bool
+	// patterns are the regular expressions invalid uses, by the name of their variable.
+	patterns [][2]string
+	imports  []string
+}
+
+// Kinds of values a check can be made on.
+const (
+	kindString = "string"
+	kindInt    = "int"
+	kindFloat  = "float"
+	kindBool   = "bool"
+	kindArray  = "array"
+	kindMap    = "map"
+	kindAny    = "any"
+)
+
+// markChecks collects the checks of --validate for every type, fields whose constraints can not
+// be checked on their Go type are warned about and left out. Every validation keyword, the
+// exclusions of not and the propertyNames and sizes of maps are checked, and the types holding
+// others with checks check them too.
+func markChecks(c *config, types []*Type) {
+	for _, t := range types {
+		t.checks = nil
+		if len(t.Enum) > 0 {
+			continue
+		}
+		structName := capitalize(t.Name)
+		for _, fld := range t.Fields {
+			cs := fld.Type.constraints
+			if fld.Name == "" || cs == nil {
+				continue
+			}
+			fn := fld.GoName()
+			tn, _ := fieldType(c, structName, fn, &fld.Type)
+			if c.validate && cs.not != nil {
+				chk, ok := notCheck(structName, fn, fld.Name, tn, &fld.Type)
+				switch {
+				case !ok:
+					c.warn(structName+"."+fn, "the exclusion of not can not be checked on %s, it is ignored", tn)
+				case chk.invalid == "":
+					c.debugf("not excludes no %s value of %s.%s\n", tn, structName, fn)
+				default:
+					t.checks = append(t.checks, chk)
+				}
+			}
+			if c.validate && (cs.minProperties != nil || cs.maxProperties != nil) {
+				chk, ok := sizeCheck(fn, fld.Name, tn, &fld.Type)
+				if !ok {
+					c.warn(structName+"."+fn, "minProperties and maxProperties can not be checked on %s, they are ignored", tn)
+				} else {
+					t.checks = append(t.checks, chk)
+				}
+			}
+			if c.validate && cs.keys != nil {
+				chk, ok := keysCheck(structName, fn, fld.Name, tn, &fld.Type)
+				switch {
+				case !ok:
+					c.warn(structName+"."+fn, "propertyNames can not be checked on %s, it is ignored", tn)
+				case chk.invalid != "":
+					t.checks = append(t.checks, chk)
+				}
+			}
+			if c.validate {
+				checks, ok := constraintChecks(structName, fn, fld.Name, "t."+fn, tn, &fld.Type)
+				if !ok {
+					c.warn(structName+"."+fn, "the validation keywords can not be checked on %s, they are ignored", tn)
+				}
+				t.checks = append(t.checks, checks...)
+			}
+		}
+		if len(t.checks) > 0 && hasValidateField(t) {
+			c.warn(structName, "a field is named Validate so no Validate method can be generated")
+			t.checks = nil
+		}
+	}
+	if c.validate {
+		markNestedChecks(c, types)
+	}
+}
+
+// valueKind returns the kind of value held by a field of Go type tn, or an empty string if it is
+// not one checks can be made on.
+func valueKind(tn string, f *maybeType) string {
+	switch {
+	case f.enum && !f.isArray:
+		return kindString
+	case strings.HasPrefix(tn, "[]"):
+		return kindArray
+	case strings.HasPrefix(tn, "map[string]"):
+		return kindMap
+	}
+	switch tn {
+	case "string":
+		return kindString
+	case "int", "int32", "int64":
+		return kindInt
+	case "float32", "float64":
+		return kindFloat
+	case "bool":
+		return kindBool
+	case "interface{}":
+		return kindAny
+	}
+	return ""
+}
+
+// notCheck returns the check rejecting the values excluded by the not of a field, its invalid
+// expression is empty if no value of the field can be excluded. It returns false when the
+// exclusion can not be checked on tn.
+func notCheck(structName, fn, key, tn string, f *maybeType) (fieldCheck, bool) {
+	v := "t." + fn
+	guard := ""
+	// nullable fields can be pointers, nil is never excluded as not has no type null.
+	if strings.HasPrefix(tn, "*") {
+		guard = v + " != nil && "
+		v = "*" + v
+		tn = tn[1:]
+	}
+	kind := valueKind(tn, f)
+	if kind == "" || kind == kindMap {
+		return fieldCheck{}, false
+	}
+	if f.enum && kind == kindString {
+		v = "string(" + v + ")"
+	}
+	chk := fieldCheck{key: key, value: v, errorf: "%v is excluded by the schema", imports: []string{"fmt"}}
+	conds, ok := chk.conditions(f.constraints.not, kind, v, "lac"+structName+fn+"Not")
+	switch {
+	case !ok:
+		return fieldCheck{}, false
+	case len(conds) == 1 && conds[0] == "false":
+		return chk, true
+	case len(conds) == 0:
+		// not with nothing left to meet excludes every value, the field can only be left out,
+		// which the Go type can not tell apart from its zero value.
+		return fieldCheck{}, false
+	}
+	chk.invalid = guard + strings.Join(conds, " && ")
+	return chk, true
+}
+
+// keysCheck returns the check rejecting the keys of a map not allowed by its propertyNames, it
+// returns false when the field is not a map.
+func keysCheck(structName, fn, key, tn string, f *maybeType) (fieldCheck, bool) {
+	if valueKind(tn, f) != kindMap {
+		return fieldCheck{}, false
+	}
+	chk := fieldCheck{key: key, value: "t." + fn, errorf: "key %q is not allowed by the schema", keys: true, imports: []string{"fmt"}}
+	conds, ok := chk.conditions(f.constraints.keys, kindString, "k", "lac"+structName+fn+"Keys")
+	switch {
+	case !ok:
+		return fieldCheck{}, false
+	case len(conds) == 0:
+		// only keywords for other kinds of values, every key is allowed.
+		return chk, true
+	}
+	chk.invalid = negate(conds)
+	return chk, true
+}
+
+// sizeCheck returns the check rejecting maps with less properties than minProperties or more than
+// maxProperties, it returns false when the field is not a map.
+func sizeCheck(fn, key, tn string, f *maybeType) (fieldCheck, bool) {
+	if valueKind(tn, f) != kindMap {
+		return fieldCheck{}, false
+	}
+	v := "t." + fn
+	cs := f.constraints
+	chk := fieldCheck{key: key, value: "len(" + v + ")", imports: []string{"fmt"}}
+	switch {
+	case cs.minProperties != nil && cs.maxProperties != nil:
+		chk.errorf = fmt.Sprintf("has %%d properties, the schema allows from %d to %d", *cs.minProperties, *cs.maxProperties)
+	case cs.minProperties != nil:
+		chk.errorf = fmt.Sprintf("has %%d properties, the schema requires at least %d", *cs.minProperties)
+	default:
+		chk.errorf = fmt.Sprintf("has %%d properties, the schema allows at most %d", *cs.maxProperties)
+	}
+	conds, _ := chk.conditions(&constraints{minProperties: cs.minProperties, maxProperties: cs.maxProperties}, kindMap, v, "")
+	chk.invalid = negate(conds)
+	return chk, true
+}
+
+// conditions returns the Go conditions a value v of kind meets when it meets cs, keywords for
+// other kinds of values do not apply, so they are met and left out. An enum no value of kind
+// can be equal to is a single false condition. The patterns used are named after pattern and
+// added to chk, along with their imports. It returns false if cs can not be checked on kind.
+func (chk *fieldCheck) conditions(cs *constraints, kind, v, pattern string) ([]string, bool) {
+	num := v
+	if kind == kindInt {
+		num = "float64(" + v + ")"
+	}
+	var conds []string
+	if len(cs.enum) > 0 {
+		var equals []string
+		for _, e := range cs.enum {
+			lit, ok := enumLiteral(kind, e)
+			if kind == kindAny && !ok && e != nil {
+				return nil, false
+			}
+			if ok {
+				equals = append(equals, num+" == "+lit)
+			}
+		}
+		if len(equals) == 0 {
+			return []string{"false"}, true
+		}
+		if len(equals) > 1 {
+			equals = []string{"(" + strings.Join(equals, " || ") + ")"}
+		}
+		conds = append(conds, equals[0])
+	}
+	if kind == kindAny && (cs.minimum != nil || cs.maximum != nil || cs.minLength != nil ||
+		cs.maxLength != nil || cs.pattern != "" || cs.minItems != nil || cs.maxItems != nil ||
+		cs.minProperties != nil || cs.maxProperties != nil) {
+		return nil, false
+	}
+	if kind == kindInt || kind == kindFloat {
+		if cs.minimum != nil {
+			conds = append(conds, fmt.Sprintf("%s %s %s", num, comparison(">=", cs.exclusiveMinimum), formatFloat(*cs.minimum)))
+		}
+		if cs.maximum != nil {
+			conds = append(conds, fmt.Sprintf("%s %s %s", num, comparison("<=", cs.exclusiveMaximum), formatFloat(*cs.maximum)))
+		}
+	}
+	if kind == kindString {
+		if cs.minLength != nil {
+			conds = append(conds, fmt.Sprintf("utf8.RuneCountInString(%s) >= %d", v, *cs.minLength))
+			chk.imports = append(chk.imports, "unicode/utf8")
+		}
+		if cs.maxLength != nil {
+			conds = append(conds, fmt.Sprintf("utf8.RuneCountInString(%s) <= %d", v, *cs.maxLength))
+			chk.imports = append(chk.imports, "unicode/utf8")
+		}
+		if cs.pattern != "" {
+			// the pattern is compiled when the generated package loads, it must not panic there.
+			if _, err := regexp.Compile(cs.pattern); err != nil {
+				return nil, false
+			}
+			conds = append(conds, pattern+".MatchString("+v+")")
+			chk.patterns = append(chk.patterns, [2]string{pattern, cs.pattern})
+			chk.imports = append(chk.imports, "regexp")
+		}
+	}
+	if kind == kindMap {
+		if cs.minProperties != nil {
+			conds = append(conds, fmt.Sprintf("len(%s) >= %d", v, *cs.minProperties))
+		}
+		if cs.maxProperties != nil {
+			conds = append(conds, fmt.Sprintf("len(%s) <= %d", v, *cs.maxProperties))
+		}
+	}
+	if kind == kindArray {
+		if cs.minItems != nil {
+			conds = append(conds, fmt.Sprintf("len(%s) >= %d", v, *cs.minItems))
+		}
+		if cs.maxItems != nil {
+			conds = append(conds, fmt.Sprintf("len(%s) <= %d", v, *cs.maxItems))
+		}
+	}
+	return conds, true
+}
+
+// negate returns a Go expression that is true when any of conds is not met.
+func negate(conds []string) string {
+	if len(conds) == 1 && (!strings.Contains(conds[0], " ") || strings.HasPrefix(conds[0], "(")) {
+		return "!" + conds[0]
+	}
+	return "!(" + strings.Join(conds, " && ") + ")"
+}
+
+// comparison returns op without the equal when the limit is exclusive.
+func comparison(op string, exclusive bool) string {
+	if exclusive {
+		return op[:1]
+	}
+	return op
+}
+
+// formatFloat writes f as a Go constant.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// enumLiteral returns the Go literal for an enum value decoded from JSON when compared with a
+// value of kind, false is returned when no value of that kind can be equal to it.
+func enumLiteral(kind string, e interface{}) (string, bool) {
+	switch e := e.(type) {
+	case string:
+		if kind == kindString || kind == kindAny {
+			return strconv.Quote(e), true
+		}
+	case bool:
+		if kind == kindBool || kind == kindAny {
+			return strconv.FormatBool(e), true
+		}
+	case float64:
+		switch kind {
+		case kindInt, kindFloat:
+			return formatFloat(e), true
+		case kindAny:
+			// encoding/json decodes every number into a float64.
+			return "float64(" + formatFloat(e) + ")", true
+		}
+	case nil:
+		if kind == kindAny {
+			return "nil", true
+		}
+	}
+	return "", false
+}
+
+// keysDoc describes the keys allowed by cs, for the comment of the map holding them.
+func keysDoc(cs *constraints) string {
+	var parts []string
+	if cs.pattern != "" {
+		parts = append(parts, "match "+cs.pattern)
+	}
+	if cs.minLength != nil {
+		parts = append(parts, fmt.Sprintf("be at least %d characters long", *cs.minLength))
+	}
+	if cs.maxLength != nil {
+		parts = append(parts, fmt.Sprintf("be at most %d characters long", *cs.maxLength))
+	}
+	if values, ok := stringEnum(cs.enum); ok {
+		parts = append(parts, "be one of "+strings.Join(values, ", "))
+	}
+	return sanitizeComment(strings.Join(parts, " and "))
+}
+
+// writeValidate writes a Validate method enforcing the checks of t, types without checks get
+// none.
+func writeValidate(w io.Writer, t *Type, structName string) {
+	if len(t.checks) == 0 {
+		return
+	}
+	for _, chk := range t.checks {
+		for _, p := range chk.patterns {
+			fmt.Fprintf(w, "var %s = regexp.MustCompile(%s)\n\n", p[0], strconv.Quote(p[1]))
+		}
+	}
+	fmt.Fprintf(w, "// Validate returns an error if %s holds a value the schema does not allow and Go types can\n", structName)
+	fmt.Fprint(w, "// not express.\n")
+	fmt.Fprintf(w, "func (t %s) Validate() error {\n", structName)
+	for _, chk := range t.checks {
+		if chk.nested {
+			writeNestedCheck(w, chk)
+			continue
+		}
+		errorf := strconv.Quote("%s: " + chk.errorf)
+		if chk.value == "" {
+			fmt.Fprintf(w, "\tif %s {\n", chk.invalid)
+			fmt.Fprintf(w, "\t\treturn fmt.Errorf(%s, %s)\n\t}\n", errorf, strconv.Quote(chk.key))
+			continue
+		}
+		if chk.items {
+			fmt.Fprintf(w, "\tfor i, e := range %s {\n", chk.value)
+			fmt.Fprintf(w, "\t\tif %s {\n", chk.invalid)
+			fmt.Fprintf(w, "\t\t\treturn fmt.Errorf(%s, %s, i, e)\n\t\t}\n\t}\n", strconv.Quote("%s[%d]: "+chk.errorf), strconv.Quote(chk.key))
+			continue
+		}
+		if chk.keys {
+			fmt.Fprintf(w, "\tfor k := range %s {\n", chk.value)
+			fmt.Fprintf(w, "\t\tif %s {\n", chk.invalid)
+			fmt.Fprintf(w, "\t\t\treturn fmt.Errorf(%s, %s, k)\n\t\t}\n\t}\n", errorf, strconv.Quote(chk.key))
+			continue
+		}
+		fmt.Fprintf(w, "\tif %s {\n", chk.invalid)
+		fmt.Fprintf(w, "\t\treturn fmt.Errorf(%s, %s, %s)\n\t}\n", errorf, strconv.Quote(chk.key), chk.value)
+	}
+	fmt.Fprint(w, "\treturn nil\n}\n\n")
+}
+
+// writeNestedCheck writes the call to the Validate method of the value of chk, or of its items.
+func writeNestedCheck(w io.Writer, chk fieldCheck) {
+	indent := "\t"
+	if chk.items {
+		fmt.Fprintf(w, "\tfor i, e := range %s {\n", chk.value)
+		indent = "\t\t"
+	}
+	if chk.invalid != "" {
+		fmt.Fprintf(w, "%sif %s {\n", indent, chk.invalid)
+		indent += "\t"
+	}
+	if chk.items {
+		fmt.Fprintf(w, "%sif err := e.Validate(); err != nil {\n", indent)
+		fmt.Fprintf(w, "%s\treturn fmt.Errorf(\"%%s[%%d]: %%w\", %s, i, err)\n%s}\n", indent, strconv.Quote(chk.key), indent)
+	} else {
+		fmt.Fprintf(w, "%sif err := %s.Validate(); err != nil {\n", indent, chk.value)
+		fmt.Fprintf(w, "%s\treturn fmt.Errorf(\"%%s: %%w\", %s, err)\n%s}\n", indent, strconv.Quote(chk.key), indent)
+	}
+	if chk.invalid != "" {
+		fmt.Fprintf(w, "%s}\n", indent[:len(indent)-1])
+	}
+	if chk.items {
+		fmt.Fprint(w, "\t}\n")
+	}
+}
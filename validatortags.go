@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validatorImport is the validator the validate tags of --validator-tags are written for.
+const validatorImport = "github.com/go-playground/validator/v10"
+
+// patternImports are the packages needed by patternHelpers.
+var patternImports = []string{validatorImport, "regexp", "sync"}
+
+// patternHelpers is written once per file when a validate tag checks a pattern, the validator has
+// no validation for regular expressions.
+const patternHelpers = `// lacPatterns holds the compiled patterns of the validate tags by expression.
+var lacPatterns sync.Map
+
+// RegisterPatternValidation registers the pattern validation the validate tags use for the fields
+// with a pattern, it must be called on the validator before validating them.
+func RegisterPatternValidation(v *validator.Validate) error {
+	return v.RegisterValidation("pattern", func(fl validator.FieldLevel) bool {
+		expr := fl.Param()
+		re, ok := lacPatterns.Load(expr)
+		if !ok {
+			compiled, err := regexp.Compile(expr)
+			if err != nil {
+				return false
+			}
+			re, _ = lacPatterns.LoadOrStore(expr, compiled)
+		}
+		return re.(*regexp.Regexp).MatchString(fl.Field().String())
+	})
+}
+
+`
+
+// validateTag returns the go-playground/validator tag for a field of Go type tn, empty when its
+// constraints have no rule the validator knows. Fields that can be missing only have their
+// values validated.
+func validateTag(c *config, fn, tn string, f *maybeType) string {
+	cs := f.constraints
+	if cs == nil || tn == "" {
+		return ""
+	}
+	elem := strings.TrimPrefix(tn, "*")
+	kind := valueKind(elem, f)
+	var rules []string
+	if kind == kindArray {
+		rules = append(rules, sizeRules(cs.minItems, cs.maxItems)...)
+		if items := validateRules(c, fn, cs.items, valueKind(elem[2:], f)); len(items) > 0 {
+			rules = append(append(rules, "dive"), items...)
+		}
+	} else {
+		rules = validateRules(c, fn, cs, kind)
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	if f.optional || f.nullable || strings.HasPrefix(tn, "*") {
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return "validate:" + strconv.Quote(strings.Join(rules, ","))
+}
+
+// validateRules returns the rules of the validator for the constraints cs of a value of kind.
+func validateRules(c *config, fn string, cs *constraints, kind string) []string {
+	if cs == nil {
+		return nil
+	}
+	var rules []string
+	switch kind {
+	case kindInt, kindFloat:
+		if cs.minimum != nil {
+			rules = append(rules, fmt.Sprintf("%s=%s", comparisonRule("gte", cs.exclusiveMinimum), formatFloat(*cs.minimum)))
+		}
+		if cs.maximum != nil {
+			rules = append(rules, fmt.Sprintf("%s=%s", comparisonRule("lte", cs.exclusiveMaximum), formatFloat(*cs.maximum)))
+		}
+		// oneof compares integers and strings only.
+		if kind == kindInt {
+			rules = append(rules, oneOfRule(cs.enum, kind)...)
+		}
+	case kindString:
+		rules = append(rules, sizeRules(cs.minLength, cs.maxLength)...)
+		if cs.pattern != "" {
+			if _, err := regexp.Compile(cs.pattern); err != nil || strings.Contains(cs.pattern, "`") {
+				c.warn(fn, "the pattern %q can not be written in a validate tag, it is left out", cs.pattern)
+			} else {
+				rules = append(rules, "pattern="+escapeRuleParam(cs.pattern))
+			}
+		}
+		rules = append(rules, oneOfRule(cs.enum, kind)...)
+	}
+	return rules
+}
+
+// sizeRules returns the rules for the minimum and maximum length of strings and arrays.
+func sizeRules(min, max *int64) []string {
+	var rules []string
+	if min != nil {
+		rules = append(rules, fmt.Sprintf("min=%d", *min))
+	}
+	if max != nil {
+		rules = append(rules, fmt.Sprintf("max=%d", *max))
+	}
+	return rules
+}
+
+// comparisonRule returns rule without the equal when the limit is exclusive.
+func comparisonRule(rule string, exclusive bool) string {
+	if exclusive {
+		return rule[:2]
+	}
+	return rule
+}
+
+// oneOfRule returns the oneof rule for the values of enum of kind, none if any of them can not be
+// written in it.
+func oneOfRule(enum []interface{}, kind string) []string {
+	if len(enum) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		switch v := e.(type) {
+		case string:
+			if kind != kindString || strings.ContainsAny(v, "'`") {
+				return nil
+			}
+			// values are space separated, the ones with spaces are quoted.
+			if strings.ContainsAny(v, " \t") || v == "" {
+				v = "'" + v + "'"
+			}
+			values = append(values, escapeRuleParam(v))
+		case float64:
+			if kind != kindInt || v != float64(int64(v)) {
+				return nil
+			}
+			values = append(values, strconv.FormatInt(int64(v), 10))
+		default:
+			return nil
+		}
+	}
+	return []string{"oneof=" + strings.Join(values, " ")}
+}
+
+// escapeRuleParam escapes the commas and pipes of a parameter, they separate rules otherwise.
+func escapeRuleParam(p string) string {
+	return strings.NewReplacer(",", "0x2C", "|", "0x7C").Replace(p)
+}
+
+// needsPatternHelpers returns true if a validate tag checks a pattern.
+func needsPatternHelpers(c *config, types []*Type) bool {
+	if !c.validatorTags {
+		return false
+	}
+	for _, t := range types {
+		for _, fld := range t.Fields {
+			cs := fld.Type.constraints
+			if cs != nil && (cs.pattern != "" || cs.items != nil && cs.items.pattern != "") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/token"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// specVersion is one of several versions of a spec passed as --swaggerfile version=path, each
+// one is generated into its own package named after the version.
+type specVersion struct {
+	name string
+	file string
+}
+
+// parseSwaggerFiles sets the spec to read from the --swaggerfile values, a single plain value is
+// used as is while version=path values make a package per version.
+func parseSwaggerFiles(c *config, values []string) error {
+	if len(values) == 1 && !isVersioned(values[0]) {
+		c.swaggerFile = values[0]
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, v := range values {
+		if !isVersioned(v) {
+			return fmt.Errorf("%q must be version=path when more than one swaggerfile is given", v)
+		}
+		i := strings.Index(v, "=")
+		name := v[:i]
+		if seen[name] {
+			return fmt.Errorf("version %s given more than once", name)
+		}
+		seen[name] = true
+		c.versions = append(c.versions, specVersion{name: name, file: v[i+1:]})
+	}
+	if len(c.versions) > 0 && c.targetFile == "" {
+		return fmt.Errorf("versioned swaggerfiles need a --target directory")
+	}
+	if len(c.versions) > 1 && c.importPath == "" {
+		return fmt.Errorf("versioned swaggerfiles need --importpath to generate conversions")
+	}
+	return nil
+}
+
+// isVersioned returns true for version=path values, the version must be usable as a package
+// name which also keeps urls with query strings from being mistaken for one.
+func isVersioned(v string) bool {
+	i := strings.Index(v, "=")
+	if i < 0 {
+		return false
+	}
+	name := v[:i]
+	return token.IsIdentifier(name) && !token.IsKeyword(name) && strings.ToLower(name) == name
+}
+
+// generateVersions writes one package per spec version under the target directory plus, in the
+// target directory itself, conversion functions between each version and the next.
+func generateVersions(ctx context.Context, c *config) error {
+	pkg, spec := c.targetPackage, c.swaggerFile
+	defer func() { c.targetPackage, c.swaggerFile = pkg, spec }()
+
+	perVersion := make([][]*Type, len(c.versions))
+	for i, v := range c.versions {
+		c.swaggerFile = v.file
+		c.targetPackage = v.name
+		types, err := schemaIntoTypes(ctx, c)
+		if err != nil {
+			return fmt.Errorf("reading swagger file for %s into types: %w", v.name, err)
+		}
+		types = filterTypes(c, types)
+		c.report.finish(c, types)
+		perVersion[i] = types
+		dir := filepath.Join(c.targetFile, v.name)
+		if err := c.files().MkdirAll(dir); err != nil {
+			return fmt.Errorf("creating package for %s: %w", v.name, err)
+		}
+		target := filepath.Join(dir, v.name+".go")
+		aliases := previousAliases(c, target, types)
+		if err := writeFile(c, target, func(out io.Writer) error {
+			if err := makeMeCode(ctx, c, types, out); err != nil {
+				return err
+			}
+			return writeAliases(out, aliases)
+		}); err != nil {
+			return fmt.Errorf("generating code for %s: %w", v.name, err)
+		}
+	}
+	if len(c.versions) < 2 {
+		return nil
+	}
+	c.targetPackage = pkg
+	return writeFile(c, filepath.Join(c.targetFile, "conversion.go"), func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		imports := make([]string, 0, len(c.versions))
+		for _, v := range c.versions {
+			imports = append(imports, path.Join(c.importPath, v.name))
+		}
+		writeHeading(w, c, imports)
+		for i := 1; i < len(c.versions); i++ {
+			older, newer := c.versions[i-1], c.versions[i]
+			writeConversions(w, c, older, newer, perVersion[i-1], perVersion[i])
+			writeConversions(w, c, newer, older, perVersion[i], perVersion[i-1])
+		}
+		return w.Flush()
+	})
+}
+
+// versionPair holds the types of two versions that are converted into each other, types are
+// paired by name and, failing that, by shape so renamed types are also converted.
+type versionPair struct {
+	from, to specVersion
+	// names maps the Go name of a type in from to its counterpart in to.
+	names map[string]string
+	// enums has the Go names of from that are enums.
+	enums map[string]bool
+	// own has the Go names of all the types of from.
+	own map[string]bool
+}
+
+// pairTypes returns the types of to paired with each type of from, nil when there is none.
+func pairTypes(fromTypes, toTypes []*Type) []*Type {
+	byName := make(map[string]*Type, len(toTypes))
+	byShape := make(map[uint64][]*Type, len(toTypes))
+	for _, t := range toTypes {
+		byName[t.Name] = t
+		byShape[t.Shape()] = append(byShape[t.Shape()], t)
+	}
+	taken := map[*Type]bool{}
+	result := make([]*Type, len(fromTypes))
+	for i, t := range fromTypes {
+		if other, ok := byName[t.Name]; ok && (len(t.Enum) > 0) == (len(other.Enum) > 0) {
+			result[i] = other
+			taken[other] = true
+		}
+	}
+	for i, t := range fromTypes {
+		if result[i] != nil || len(t.Enum) > 0 {
+			continue
+		}
+		for _, other := range byShape[t.Shape()] {
+			if !taken[other] && len(other.Enum) == 0 && len(other.Fields) == len(t.Fields) {
+				result[i] = other
+				taken[other] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// writeConversions writes a conversion function from every type of from that has a counterpart
+// in to, fields that can not be converted automatically are left as TODOs for the user.
+func writeConversions(w io.Writer, c *config, from, to specVersion, fromTypes, toTypes []*Type) {
+	pairs := pairTypes(fromTypes, toTypes)
+	vp := &versionPair{from: from, to: to, names: map[string]string{}, enums: map[string]bool{}, own: map[string]bool{}}
+	for i, t := range fromTypes {
+		vp.own[capitalize(t.Name)] = true
+		if pairs[i] == nil {
+			c.warn(t.Name, "no counterpart in %s, no conversion generated from %s", to.name, from.name)
+			continue
+		}
+		vp.names[capitalize(t.Name)] = capitalize(pairs[i].Name)
+		if len(t.Enum) > 0 {
+			vp.enums[capitalize(t.Name)] = true
+		}
+	}
+	for i, t := range fromTypes {
+		if pairs[i] == nil || len(t.Enum) > 0 {
+			continue
+		}
+		writeConversion(w, c, vp, t, pairs[i])
+	}
+}
+
+// conversionName returns the name of the function converting fromName in from to toName in to,
+// it follows the naming of k8s conversion-gen.
+func conversionName(from, to specVersion, fromName, toName string) string {
+	return fmt.Sprintf("Convert_%s_%s_To_%s_%s", from.name, fromName, to.name, toName)
+}
+
+// writeConversion writes the conversion function between a pair of types.
+func writeConversion(w io.Writer, c *config, vp *versionPair, in, out *Type) {
+	inName, outName := capitalize(in.Name), capitalize(out.Name)
+	fn := conversionName(vp.from, vp.to, inName, outName)
+	fmt.Fprintf(w, "// %s converts %s.%s into %s.%s.\n", fn, vp.from.name, inName, vp.to.name, outName)
+	fmt.Fprintf(w, "func %s(in *%s.%s, out *%s.%s) error {\n", fn, vp.from.name, inName, vp.to.name, outName)
+	for _, of := range out.Fields {
+		if of.Name == "" {
+			fmt.Fprint(w, "\t// TODO: convert the embedded types.\n")
+			continue
+		}
+		goName := of.GoName()
+		f := in.Field(of.Name)
+		if f == nil {
+			fmt.Fprintf(w, "\t// TODO: %s has no counterpart in %s.\n", goName, vp.from.name)
+			continue
+		}
+		inType := conversionFieldType(c, inName, f)
+		outType := conversionFieldType(c, outName, &of)
+		writeFieldConversion(w, vp, goName, inType, outType)
+	}
+	for _, f := range in.Fields {
+		if f.Name != "" && out.Field(f.Name) == nil {
+			fmt.Fprintf(w, "\t// TODO: %s has no counterpart in %s.\n", f.GoName(), vp.to.name)
+		}
+	}
+	fmt.Fprint(w, "\treturn nil\n}\n\n")
+}
+
+// conversionFieldType returns the Go type of f as writeType declares it, multi types have no
+// name so they are returned empty.
+func conversionFieldType(c *config, structName string, f *Field) string {
+	if f.Type.IsMultiple() {
+		return ""
+	}
+	tn, _ := fieldType(c, structName, f.GoName(), &f.Type)
+	if tn == structName {
+		tn = "*" + tn
+	}
+	return tn
+}
+
+// writeFieldConversion writes the conversion of field fn from inType to outType, only values,
+// pointers and slices of the same kind are handled.
+func writeFieldConversion(w io.Writer, vp *versionPair, fn, inType, outType string) {
+	inMod, inBase := splitModifier(inType)
+	outMod, outBase := splitModifier(outType)
+	mapped, paired := vp.names[inBase]
+	switch {
+	case inType != "" && inType == outType && !vp.own[baseType(inType)]:
+		fmt.Fprintf(w, "\tout.%s = in.%s\n", fn, fn)
+	case inType == "" || outType == "" || inMod != outMod || !paired || mapped != outBase:
+		fmt.Fprintf(w, "\t// TODO: convert %s, it is %s in %s and %s in %s.\n", fn, typeOrMulti(inType), vp.from.name, typeOrMulti(outType), vp.to.name)
+	case vp.enums[inBase]:
+		target := vp.to.name + "." + outBase
+		switch inMod {
+		case "":
+			fmt.Fprintf(w, "\tout.%s = %s(in.%s)\n", fn, target, fn)
+		case "*":
+			fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tv := %s(*in.%s)\n\t\tout.%s = &v\n\t}\n", fn, target, fn, fn)
+		case "[]":
+			fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tout.%s = make([]%s, len(in.%s))\n", fn, fn, target, fn)
+			fmt.Fprintf(w, "\t\tfor i := range in.%s {\n\t\t\tout.%s[i] = %s(in.%s[i])\n\t\t}\n\t}\n", fn, fn, target, fn)
+		}
+	default:
+		target := vp.to.name + "." + outBase
+		convert := conversionName(vp.from, vp.to, inBase, outBase)
+		switch inMod {
+		case "":
+			fmt.Fprintf(w, "\tif err := %s(&in.%s, &out.%s); err != nil {\n\t\treturn err\n\t}\n", convert, fn, fn)
+		case "*":
+			fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tout.%s = new(%s)\n", fn, fn, target)
+			fmt.Fprintf(w, "\t\tif err := %s(in.%s, out.%s); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n", convert, fn, fn)
+		case "[]":
+			fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tout.%s = make([]%s, len(in.%s))\n", fn, fn, target, fn)
+			fmt.Fprintf(w, "\t\tfor i := range in.%s {\n", fn)
+			fmt.Fprintf(w, "\t\t\tif err := %s(&in.%s[i], &out.%s[i]); err != nil {\n\t\t\t\treturn err\n\t\t\t}\n\t\t}\n\t}\n", convert, fn, fn)
+		case "[]*":
+			fmt.Fprintf(w, "\tif in.%s != nil {\n\t\tout.%s = make([]*%s, len(in.%s))\n", fn, fn, target, fn)
+			fmt.Fprintf(w, "\t\tfor i := range in.%s {\n\t\t\tif in.%s[i] == nil {\n\t\t\t\tcontinue\n\t\t\t}\n", fn, fn)
+			fmt.Fprintf(w, "\t\t\tout.%s[i] = new(%s)\n", fn, target)
+			fmt.Fprintf(w, "\t\t\tif err := %s(in.%s[i], out.%s[i]); err != nil {\n\t\t\t\treturn err\n\t\t\t}\n\t\t}\n\t}\n", convert, fn, fn)
+		}
+	}
+}
+
+// splitModifier splits a Go type into its pointer or slice modifier and the type it modifies,
+// anything else, such as maps, is returned whole.
+func splitModifier(tn string) (string, string) {
+	for _, m := range []string{"[]*", "*", "[]"} {
+		if strings.HasPrefix(tn, m) && !strings.ContainsAny(tn[len(m):], "*[]") {
+			return m, tn[len(m):]
+		}
+	}
+	return "", tn
+}
+
+// baseType returns the type name under any pointer, slice or map of tn.
+func baseType(tn string) string {
+	for {
+		switch {
+		case strings.HasPrefix(tn, "*"):
+			tn = tn[1:]
+		case strings.HasPrefix(tn, "[]"):
+			tn = tn[2:]
+		case strings.HasPrefix(tn, "map["):
+			tn = tn[strings.Index(tn, "]")+1:]
+		default:
+			return tn
+		}
+	}
+}
+
+// typeOrMulti names a type for the TODO comments, multi types have no name.
+func typeOrMulti(tn string) string {
+	if tn == "" {
+		return "a oneOf/anyOf/allOf"
+	}
+	return tn
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// warning describes a guess LAC had to make or a construct it had to skip while generating
+// types, it does not stop the run but the output might not be exactly what the user expects.
+type warning struct {
+	// Source is the file, schema or type that originated the warning.
+	Source string
+	// Message is a human readable explanation of what happened.
+	Message string
+}
+
+func (w warning) String() string {
+	if w.Source == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("%s: %s", w.Source, w.Message)
+}
+
+// printWarning is where warnings go unless the config has a warningHandler, like the one --rpc
+// collects them with. It prints to stderr so warnings never end up in the code written to stdout.
+func printWarning(w warning) {
+	fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+}
+
+// debugf prints progress information to stderr when running verbose.
+func (c *config) debugf(format string, args ...interface{}) {
+	if c.verbose {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// warn builds a warning and hands it to the warningHandler of the config.
+func (c *config) warn(source, format string, args ...interface{}) {
+	c.warnMu.Lock()
+	defer c.warnMu.Unlock()
+	h := c.warningHandler
+	if h == nil {
+		h = printWarning
+	}
+	h(warning{Source: source, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats for --swaggerformat.
+const (
+	swaggerFormatAuto = "auto"
+	swaggerFormatJSON = "json"
+	swaggerFormatYAML = "yaml"
+)
+
+// readSpec returns the contents of the swagger file as JSON, YAML specs are converted keeping the
+// order of their keys. The schemas referenced in other documents are folded into it and nullable
+// types are written the OpenAPI way.
+func readSpec(ctx context.Context, c *config) ([]byte, error) {
+	b, err := readDocument(ctx, c, c.swaggerFile, c.swaggerFormat)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteSpec(ctx, c, b)
+}
+
+// rewriteSpec folds the schemas referenced in other documents into spec and writes its nullable
+// types the OpenAPI way.
+func rewriteSpec(ctx context.Context, c *config, spec []byte) ([]byte, error) {
+	spec, err := resolveExternalRefs(ctx, c, spec)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeTypeArrays(ctx, c, spec)
+}
+
+// decodeSpec reads the spec into v. Decoding errors are located in the file when the spec is
+// decoded as it is in there, YAML specs, converted into a single line, and the ones rewritten by
+// rewriteSpec are not so their errors only name the offending field.
+func decodeSpec(ctx context.Context, c *config, v interface{}) error {
+	raw, err := readDocument(ctx, c, c.swaggerFile, c.swaggerFormat)
+	if err != nil {
+		return err
+	}
+	spec, err := rewriteSpec(ctx, c, raw)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(bytes.NewReader(spec)).Decode(v); err != nil {
+		if bytes.Equal(raw, spec) && bytes.IndexByte(spec, '\n') >= 0 {
+			return locateError(c.swaggerFile, spec, err, -1)
+		}
+		return err
+	}
+	return nil
+}
+
+// readDocument returns the contents of the document at location as JSON, converting it from
+// YAML if format says so.
+func readDocument(ctx context.Context, c *config, location, format string) ([]byte, error) {
+	p, err := providerFor(ctx, c, location)
+	if err != nil {
+		return nil, fmt.Errorf("finding %s: %w", location, err)
+	}
+	fp, err := p.Open(sourceRef(location))
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", location, err)
+	}
+	defer fp.Close()
+	b, err := readLimited(c, location, fp)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", location, err)
+	}
+	// documents exported by Windows tools can be UTF-16 or start with a byte order mark.
+	b, err = toUTF8(b)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", location, err)
+	}
+	if !isYAMLSpec(format, location, b) {
+		if c.lenientJSON {
+			b = stripJSONC(b)
+		}
+		if err := checkDepth(c, location, b); err != nil {
+			return nil, err
+		}
+		// syntax errors are told here, where the document is still as it is in the file.
+		if !json.Valid(b) {
+			var v interface{}
+			return nil, locateError(location, b, json.Unmarshal(b, &v), -1)
+		}
+		return b, nil
+	}
+	c.debugf("reading %s as yaml\n", location)
+	b, err = yamlToJSON(b, c.maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("converting yaml %s: %w", location, err)
+	}
+	if err := checkDepth(c, location, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// isYAMLSpec tells if the spec at location is YAML, with the auto format it is when the extension
+// says so or, lacking one, when the contents do not start like a JSON object.
+func isYAMLSpec(format, location string, b []byte) bool {
+	switch format {
+	case swaggerFormatJSON:
+		return false
+	case swaggerFormatYAML:
+		return true
+	}
+	// URLs can carry a query after the path.
+	if i := strings.IndexAny(location, "?#"); i >= 0 && strings.Contains(location, "://") {
+		location = location[:i]
+	}
+	switch strings.ToLower(path.Ext(location)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	return !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
+
+// yamlToJSON converts a YAML document into JSON, keys are kept in order since the order of
+// properties is the order of the fields. Aliases are expanded so the JSON can grow much larger
+// than the YAML, a JSON larger than max bytes, unless it is 0, is an error.
+func yamlToJSON(b []byte, max int64) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := writeYAMLNode(buf, &doc, max); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYAMLNode writes n as JSON into buf, failing once buf is larger than max bytes.
+func writeYAMLNode(buf *bytes.Buffer, n *yaml.Node, max int64) error {
+	if max > 0 && int64(buf.Len()) > max {
+		return fmt.Errorf("the document expands past %d bytes, see --max-bytes", max)
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLNode(buf, n.Content[0], max)
+	case yaml.AliasNode:
+		return writeYAMLNode(buf, n.Alias, max)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			// keys such as the status codes of responses are not strings in YAML.
+			k, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err := writeYAMLNode(buf, n.Content[i+1], max); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, e := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLNode(buf, e, max); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	var v interface{}
+	switch n.ShortTag() {
+	case "!!timestamp", "!!binary":
+		// dates are strings with a format in specs.
+		v = n.Value
+	default:
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	buf.Write(b)
+	return nil
+}